@@ -0,0 +1,46 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	iconfig "github.com/mhamza15/forest/internal/config"
+)
+
+func getCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "get <key>",
+		Short: "Print a config setting",
+		Long: `Print the value of a single key in the global config, with defaults
+applied. Use --project to read a project's config instead, where
+settings the project does not override print as empty.
+
+Lists and mappings are printed as YAML.`,
+		Args: cobra.ExactArgs(1),
+		RunE: runGet,
+	}
+}
+
+func runGet(cmd *cobra.Command, args []string) error {
+	project, _ := cmd.Flags().GetString("project")
+
+	var (
+		value string
+		err   error
+	)
+
+	if project == "" {
+		value, err = iconfig.GetGlobalValue(args[0])
+	} else {
+		value, err = iconfig.GetProjectValue(project, args[0])
+	}
+
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(value)
+
+	return nil
+}
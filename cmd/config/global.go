@@ -0,0 +1,15 @@
+package config
+
+import "github.com/spf13/cobra"
+
+func globalCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "global",
+		Short: "Open the global config in your editor",
+		Long:  "Open the global config in $VISUAL or $EDITOR, writing the default config first if none exists.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return editGlobal()
+		},
+	}
+}
@@ -0,0 +1,20 @@
+package config
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/mhamza15/forest/internal/completion"
+)
+
+func projectCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:               "project <name>",
+		Short:             "Open a project's config in your editor",
+		Long:              "Open the config of the named project in $VISUAL or $EDITOR.",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completion.Projects,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return editProject(args[0])
+		},
+	}
+}
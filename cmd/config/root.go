@@ -5,58 +5,167 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
-	"os/exec"
+	"strings"
+	"text/tabwriter"
 
 	"github.com/spf13/cobra"
 
 	iconfig "github.com/mhamza15/forest/internal/config"
+	"github.com/mhamza15/forest/internal/editor"
+)
+
+var (
+	pathFlag  bool
+	checkFlag bool
 )
 
 // Command returns the config cobra command, ready to be added as a
 // subcommand of root.
 func Command() *cobra.Command {
-	return &cobra.Command{
+	cmd := &cobra.Command{
 		Use:   "config",
 		Short: "Open configuration in your editor",
-		Long:  "Opens the global config in $EDITOR. Use --project to open a specific project's config instead.",
-		Args:  cobra.NoArgs,
-		RunE:  run,
+		Long: `Opens the global config in $VISUAL or $EDITOR. Use --project to open a specific project's config instead.
+
+With --path, prints the resolved configuration locations instead of
+opening an editor. Combined with --project, prints only that project's
+config path.
+
+With --check, makes a best-effort check that every project's layout
+runs: the first word of each window's command and shell must resolve
+on PATH or exist in the project's repository. Combined with --project,
+checks only that project.
+
+The global and project subcommands open a config explicitly, while get
+and set read and change a single setting without opening an editor.`,
+		Args: cobra.NoArgs,
+		RunE: run,
 	}
+
+	cmd.Flags().BoolVar(&pathFlag, "path", false, "print config locations instead of opening an editor")
+	cmd.Flags().BoolVar(&checkFlag, "check", false, "check that layout commands can be found")
+
+	cmd.AddCommand(globalCmd())
+	cmd.AddCommand(projectCmd())
+	cmd.AddCommand(getCmd())
+	cmd.AddCommand(setCmd())
+
+	return cmd
 }
 
 func run(cmd *cobra.Command, _ []string) error {
 	project, _ := cmd.Flags().GetString("project")
 
-	var path string
+	if pathFlag {
+		return printPaths(project)
+	}
+
+	if checkFlag {
+		return checkLayouts(project)
+	}
 
 	if project == "" {
-		if err := iconfig.WriteDefaultGlobal(); err != nil {
-			return err
-		}
-		path = iconfig.GlobalConfigPath()
-	} else {
-		path = iconfig.ProjectConfigPath(project)
+		return editGlobal()
+	}
+
+	return editProject(project)
+}
+
+// editGlobal opens the global config in the editor, writing the
+// default config first if none exists.
+func editGlobal() error {
+	if err := iconfig.WriteDefaultGlobal(); err != nil {
+		return err
 	}
 
+	return editFile(iconfig.GlobalConfigPath())
+}
+
+// editProject opens a project's config in the editor.
+func editProject(project string) error {
+	return editFile(iconfig.ProjectConfigPath(project))
+}
+
+func editFile(path string) error {
 	if _, err := os.Stat(path); err != nil {
 		return fmt.Errorf("config file not found: %s", path)
 	}
 
 	slog.Debug("opening config", slog.String("path", path))
-	return openEditor(path)
+	return editor.Open(path)
 }
 
-// openEditor launches the user's preferred editor on the given file path.
-func openEditor(path string) error {
-	editor := os.Getenv("EDITOR")
-	if editor == "" {
-		editor = "vi"
+// printPaths prints the resolved config locations. When project is
+// set, only that project's config path is printed.
+func printPaths(project string) error {
+	if project != "" {
+		fmt.Println(iconfig.ProjectConfigPath(project))
+		return nil
+	}
+
+	global, err := iconfig.LoadGlobal()
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+	_, _ = fmt.Fprintf(w, "config\t%s\n", iconfig.GlobalConfigPath())
+	_, _ = fmt.Fprintf(w, "projects\t%s\n", iconfig.ProjectsDir())
+	_, _ = fmt.Fprintf(w, "schemas\t%s\n", iconfig.SchemaDir())
+	_, _ = fmt.Fprintf(w, "data\t%s\n", iconfig.DataDir())
+	_, _ = fmt.Fprintf(w, "state\t%s\n", iconfig.StateDir())
+	_, _ = fmt.Fprintf(w, "worktrees\t%s\n", global.WorktreeDir)
+
+	return w.Flush()
+}
+
+// checkLayouts reports layout windows whose programs cannot be found,
+// for one project or all of them. A project whose config cannot be
+// resolved is reported and skipped, so the others are still checked.
+// It fails when any problems are found or any project is skipped.
+func checkLayouts(project string) error {
+	names := []string{project}
+
+	if project == "" {
+		var err error
+
+		names, err = iconfig.ListProjects()
+		if err != nil {
+			return err
+		}
+	}
+
+	var (
+		problems int
+		failed   []string
+	)
+
+	for _, name := range names {
+		rc, err := iconfig.Resolve(name)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Skipping project %s: %s\n", name, err)
+			failed = append(failed, name)
+
+			continue
+		}
+
+		for _, issue := range iconfig.CheckLayout(rc.Layout, rc.Repo) {
+			fmt.Printf("%s: %s\n", name, issue)
+			problems++
+		}
+	}
+
+	switch {
+	case problems > 0 && len(failed) > 0:
+		return fmt.Errorf("found %d layout problem(s); could not check %d project(s): %s", problems, len(failed), strings.Join(failed, ", "))
+	case problems > 0:
+		return fmt.Errorf("found %d layout problem(s)", problems)
+	case len(failed) > 0:
+		return fmt.Errorf("could not check %d project(s): %s", len(failed), strings.Join(failed, ", "))
 	}
 
-	c := exec.Command(editor, path)
-	c.Stdin = os.Stdin
-	c.Stdout = os.Stdout
-	c.Stderr = os.Stderr
+	fmt.Println("No layout problems found.")
 
-	return c.Run()
+	return nil
 }
@@ -0,0 +1,50 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	iconfig "github.com/mhamza15/forest/internal/config"
+)
+
+func setCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "set <key> <value>",
+		Short: "Change a config setting",
+		Long: `Set a single key in the global config without opening an editor. Use
+--project to change a project's config instead.
+
+Nested keys are separated by dots. The value is parsed as YAML, so
+booleans, numbers and lists keep their types:
+
+  forest config set branch develop
+  forest config set auto_session false
+  forest config set prune_exclude "[staging, release/*]"
+
+Comments and other settings in the file are preserved. Unknown keys and
+values of the wrong type are rejected without changing the file.`,
+		Args: cobra.ExactArgs(2),
+		RunE: runSet,
+	}
+}
+
+func runSet(cmd *cobra.Command, args []string) error {
+	key, value := args[0], args[1]
+	project, _ := cmd.Flags().GetString("project")
+
+	var err error
+	if project == "" {
+		err = iconfig.SetGlobalValue(key, value)
+	} else {
+		err = iconfig.SetProjectValue(project, key, value)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Set %s to %s\n", key, value)
+
+	return nil
+}
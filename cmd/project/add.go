@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
-	"os/exec"
 	"path/filepath"
 
 	"charm.land/bubbles/v2/key"
@@ -15,10 +14,19 @@ import (
 	"github.com/mhamza15/forest/internal/forest"
 	"github.com/mhamza15/forest/internal/git"
 	"github.com/mhamza15/forest/internal/github"
+	"github.com/mhamza15/forest/internal/prompt"
 	"github.com/mhamza15/forest/internal/tmux"
 )
 
-var nameFlag string
+var (
+	nameFlag         string
+	depthFlag        int
+	singleBranchFlag bool
+	partialFlag      bool
+	remoteNameFlag   string
+	upstreamFlag     string
+	hereFlag         bool
+)
 
 func addCmd() *cobra.Command {
 	cmd := &cobra.Command{
@@ -26,7 +34,10 @@ func addCmd() *cobra.Command {
 		Short: "Register a new project",
 		Long: `Register a git repository as a forest project.
 
-If no path is given, an interactive prompt is shown.
+If no path is given, an interactive prompt is shown. Without a
+terminal (for example in scripts), a path or URL is required. Use
+--here to register the repository in the current directory without
+the prompt.
 
 A GitHub repository URL may be passed instead of a local path:
 
@@ -34,17 +45,50 @@ A GitHub repository URL may be passed instead of a local path:
 
 The repository is cloned into the current directory (or projects_dir
 if configured), registered as a project, and the default branch is
-opened in a tmux session.`,
+opened in a tmux session. Use --depth and --single-branch to speed up
+cloning large repositories, or --partial to clone the full history
+without file contents, which git fetches as worktrees need them.
+
+When cloning a fork, use --remote-name to name the cloned remote
+something other than origin, and --upstream to add the repository it
+was forked from as an "upstream" remote:
+
+  forest project add https://github.com/me/repo --upstream https://github.com/owner/repo`,
 		Args: cobra.MaximumNArgs(1),
 		RunE: runAdd,
 	}
 
 	cmd.Flags().StringVar(&nameFlag, "name", "", "project name (defaults to repo directory name)")
+	cmd.Flags().BoolVar(&hereFlag, "here", false, "register the repository in the current directory")
+	cmd.Flags().IntVar(&depthFlag, "depth", 0, "create a shallow clone with the given number of commits")
+	cmd.Flags().BoolVar(&singleBranchFlag, "single-branch", false, "clone only the default branch")
+	cmd.Flags().BoolVar(&partialFlag, "partial", false, "create a blobless partial clone, fetching file contents on demand")
+	cmd.Flags().StringVar(&remoteNameFlag, "remote-name", "", "name for the cloned remote instead of origin")
+	cmd.Flags().StringVar(&upstreamFlag, "upstream", "", "URL of the upstream repository to add as the \"upstream\" remote")
+
+	return cmd
+}
+
+// newCmd returns the command's former name, "new", as a hidden copy of
+// add, so that old scripts keep working without it showing in help
+// next to add.
+func newCmd() *cobra.Command {
+	cmd := addCmd()
+	cmd.Use = "new [path | github-url]"
+	cmd.Hidden = true
 
 	return cmd
 }
 
 func runAdd(cmd *cobra.Command, args []string) error {
+	if hereFlag {
+		if len(args) > 0 {
+			return fmt.Errorf("--here cannot be combined with a path or URL")
+		}
+
+		args = []string{"."}
+	}
+
 	if len(args) == 0 {
 		return runAddInteractive()
 	}
@@ -52,30 +96,19 @@ func runAdd(cmd *cobra.Command, args []string) error {
 	if github.IsGitHubURL(args[0]) {
 		return runAddFromGitHub(args[0], nameFlag)
 	}
+
+	if remoteNameFlag != "" || upstreamFlag != "" {
+		return fmt.Errorf("--remote-name and --upstream only apply when cloning from a GitHub URL")
+	}
+
 	return registerProject(args[0], nameFlag)
 }
 
 // registerProject validates the repo path, derives a name, and writes
 // the project config file.
 func registerProject(repoPath string, name string) error {
-	absPath, err := filepath.Abs(repoPath)
+	name, absPath, err := forest.RegisterProject(repoPath, name)
 	if err != nil {
-		return fmt.Errorf("resolving path: %w", err)
-	}
-
-	if err := validateGitRepo(absPath); err != nil {
-		return err
-	}
-
-	if name == "" {
-		name = filepath.Base(absPath)
-	}
-
-	cfg := config.ProjectConfig{
-		Repo: absPath,
-	}
-
-	if err := config.SaveProject(name, cfg); err != nil {
 		return err
 	}
 
@@ -111,7 +144,36 @@ func runAddFromGitHub(rawURL, name string) error {
 
 	fmt.Printf("Cloning %s/%s into %s\n", info.Owner, info.Repo, dest)
 
-	if err := git.Clone(info.CloneURL, dest); err != nil {
+	if depthFlag > 0 || singleBranchFlag {
+		fmt.Println("Warning: shallow or single-branch clones may limit operations on other base branches")
+	}
+
+	opts := git.CloneOptions{
+		Depth:        depthFlag,
+		SingleBranch: singleBranchFlag,
+		Partial:      partialFlag,
+		Progress:     os.Stderr,
+	}
+
+	// gh carries the user's credentials, so prefer it for private
+	// repositories. Plain git works for public ones without it.
+	if github.Authenticated() {
+		err = github.CloneRepo(info.Owner+"/"+info.Repo, dest, opts)
+	} else {
+		err = git.CloneWithOptions(info.CloneURL, dest, opts)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	// Remove the clone when its remotes cannot be set up as asked, so
+	// that running the command again does not find it in the way.
+	if err := configureCloneRemotes(dest); err != nil {
+		if rmErr := os.RemoveAll(dest); rmErr != nil {
+			slog.Debug("could not remove clone", slog.String("path", dest), slog.Any("err", rmErr))
+		}
+
 		return err
 	}
 
@@ -120,8 +182,17 @@ func runAddFromGitHub(rawURL, name string) error {
 		return fmt.Errorf("resolving path: %w", err)
 	}
 
+	// Detect the default branch of the freshly cloned repo and store
+	// it so the project does not depend on the global default.
+	branch, err := git.DefaultBranch(absPath)
+	if err != nil {
+		return err
+	}
+
 	cfg := config.ProjectConfig{
-		Repo: absPath,
+		Repo:   absPath,
+		Remote: info.Owner + "/" + info.Repo,
+		Branch: branch,
 	}
 
 	if err := config.SaveProject(name, cfg); err != nil {
@@ -130,12 +201,6 @@ func runAddFromGitHub(rawURL, name string) error {
 
 	fmt.Printf("Registered project %q (%s)\n", name, absPath)
 
-	// Detect the default branch of the freshly cloned repo.
-	branch, err := git.DefaultBranch(absPath)
-	if err != nil {
-		return err
-	}
-
 	rc, err := config.Resolve(name)
 	if err != nil {
 		return err
@@ -150,7 +215,7 @@ func runAddFromGitHub(rawURL, name string) error {
 		fmt.Println(w)
 	}
 
-	if err := forest.OpenSession(rc, branch, result.WorktreePath); err != nil {
+	if _, err := forest.OpenSession(rc, branch, result.WorktreePath); err != nil {
 		return err
 	}
 
@@ -159,9 +224,35 @@ func runAddFromGitHub(rawURL, name string) error {
 	return tmux.SwitchTo(result.SessionName)
 }
 
+// configureCloneRemotes renames the cloned origin remote and adds an
+// upstream remote when requested by --remote-name and --upstream.
+func configureCloneRemotes(repoPath string) error {
+	if remoteNameFlag != "" && remoteNameFlag != "origin" {
+		if err := git.RenameRemote(repoPath, "origin", remoteNameFlag); err != nil {
+			return err
+		}
+	}
+
+	// gh repo clone already adds an upstream remote when cloning a
+	// fork, so point it at the requested URL rather than adding it.
+	if upstreamFlag != "" {
+		if err := git.SetRemote(repoPath, "upstream", upstreamFlag); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // runAddInteractive prompts the user for repo path and project name
 // using a huh form with a file picker for directory selection.
 func runAddInteractive() error {
+	// The huh form cannot run without a terminal. Fail with guidance
+	// instead so provisioning scripts get a clear error.
+	if !prompt.IsInteractive() {
+		return fmt.Errorf("no repository given and stdin is not a terminal; pass a path or GitHub URL to project add")
+	}
+
 	var name string
 
 	startDir, _ := filepath.Abs(".")
@@ -209,13 +300,3 @@ func runAddInteractive() error {
 
 	return registerProject(repoPath, name)
 }
-
-func validateGitRepo(path string) error {
-	c := exec.Command("git", "-C", path, "rev-parse", "--git-dir")
-
-	if err := c.Run(); err != nil {
-		return fmt.Errorf("%s is not a git repository", path)
-	}
-
-	return nil
-}
@@ -0,0 +1,42 @@
+package project
+
+import (
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mhamza15/forest/internal/git"
+)
+
+func TestConfigureCloneRemotes_ForkAlreadyHasUpstream(t *testing.T) {
+	// gh repo clone of a fork leaves both origin and upstream behind.
+	repo := filepath.Join(t.TempDir(), "repo")
+	runGit(t, "init", "--quiet", repo)
+	runGit(t, "-C", repo, "remote", "add", "origin", "https://github.com/me/app.git")
+	runGit(t, "-C", repo, "remote", "add", "upstream", "https://github.com/owner/app.git")
+
+	remoteNameFlag, upstreamFlag = "fork", "https://github.com/other/app.git"
+
+	t.Cleanup(func() { remoteNameFlag, upstreamFlag = "", "" })
+
+	require.NoError(t, configureCloneRemotes(repo))
+
+	remotes, err := git.Remotes(repo)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"fork", "upstream"}, remotes)
+
+	url, err := git.RemoteURL(repo, "upstream")
+	require.NoError(t, err)
+	assert.Equal(t, upstreamFlag, url)
+}
+
+func runGit(t *testing.T, args ...string) {
+	t.Helper()
+
+	output, err := exec.Command("git", args...).CombinedOutput()
+	require.NoError(t, err, "git %s: %s", strings.Join(args, " "), output)
+}
@@ -49,7 +49,7 @@ func runList(cmd *cobra.Command, args []string) error {
 
 		_, _ = fmt.Fprintf(w, "%s\t%s\n",
 			projectNameStyle.Render(name),
-			pathStyle.Render(proj.Repo),
+			pathStyle.Render(proj.RepoPath()),
 		)
 	}
 
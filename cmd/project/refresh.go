@@ -0,0 +1,64 @@
+package project
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mhamza15/forest/internal/completion"
+	"github.com/mhamza15/forest/internal/config"
+	"github.com/mhamza15/forest/internal/forest"
+)
+
+func refreshCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "refresh [project...]",
+		Short: "Update the cached remotes of projects",
+		Long: `Record the current origin remote of each project in its config.
+
+Forest caches each project's origin remote to infer projects from
+GitHub links and the working directory without running git for every
+project. Run this after changing a repository's origin. Without
+arguments, every project is refreshed. To ignore the cache for a single
+command instead, pass --refresh.`,
+		RunE:              runRefresh,
+		ValidArgsFunction: completion.Projects,
+	}
+}
+
+func runRefresh(cmd *cobra.Command, args []string) error {
+	names := args
+	if len(names) == 0 {
+		var err error
+
+		names, err = config.ListProjects()
+		if err != nil {
+			return err
+		}
+	}
+
+	var failed int
+
+	for _, name := range names {
+		remote, err := forest.RefreshProjectRemote(name)
+		if err != nil {
+			fmt.Printf("Failed to refresh %s: %s\n", name, err)
+			failed++
+
+			continue
+		}
+
+		if remote == "" {
+			fmt.Printf("Project %q has no origin remote\n", name)
+			continue
+		}
+
+		fmt.Printf("Project %q: %s\n", name, remote)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("failed to refresh %d project(s)", failed)
+	}
+
+	return nil
+}
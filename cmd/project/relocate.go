@@ -0,0 +1,91 @@
+package project
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mhamza15/forest/internal/completion"
+	"github.com/mhamza15/forest/internal/config"
+	"github.com/mhamza15/forest/internal/forest"
+)
+
+var searchFlag string
+
+func relocateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "relocate <project> [new-path]",
+		Short: "Point a project at its moved repository",
+		Long: `Update a project's repo path after the repository was moved on disk.
+
+The new path must be a git repository. Existing worktrees are repaired
+with git worktree repair so that they find the repository at its new
+location.
+
+Instead of a path, use --search to look for the repository under a
+directory whose origin matches the one recorded when the project was
+registered, or failing that, any repository with a remote that
+matches it. This helps after moving many repositories at once:
+
+  forest project relocate myapp --search ~/code`,
+		Args: cobra.RangeArgs(1, 2),
+		RunE: runRelocate,
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+			if len(args) == 0 {
+				return completion.Projects(cmd, args, toComplete)
+			}
+
+			return nil, cobra.ShellCompDirectiveFilterDirs
+		},
+	}
+
+	cmd.Flags().StringVar(&searchFlag, "search", "", "search this directory for the repository instead of passing its path")
+
+	if err := cmd.MarkFlagDirname("search"); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: registering --search completion: %s\n", err)
+	}
+
+	return cmd
+}
+
+func runRelocate(cmd *cobra.Command, args []string) error {
+	name := args[0]
+
+	var newPath string
+
+	switch {
+	case len(args) == 2 && searchFlag != "":
+		return fmt.Errorf("pass either a new path or --search, not both")
+
+	case len(args) == 2:
+		newPath = args[1]
+
+	case searchFlag != "":
+		proj, err := config.LoadProject(name)
+		if err != nil {
+			return err
+		}
+
+		if proj.Remote == "" {
+			return fmt.Errorf("project %s has no recorded remote to search for; pass the new path instead", name)
+		}
+
+		newPath, err = forest.FindRepo(searchFlag, proj.Remote)
+		if err != nil {
+			return err
+		}
+
+	default:
+		return fmt.Errorf("a new path or --search is required")
+	}
+
+	path, err := forest.RelocateProject(name, newPath)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Project %q now points to %s\n", name, path)
+
+	return nil
+}
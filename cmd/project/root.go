@@ -12,7 +12,10 @@ func Command() *cobra.Command {
 	}
 
 	cmd.AddCommand(addCmd())
+	cmd.AddCommand(newCmd())
 	cmd.AddCommand(listCmd())
+	cmd.AddCommand(refreshCmd())
+	cmd.AddCommand(relocateCmd())
 	cmd.AddCommand(removeCmd())
 
 	return cmd
@@ -0,0 +1,37 @@
+package project
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommand_NewRunsAddHidden(t *testing.T) {
+	cmd := Command()
+
+	add, _, err := cmd.Find([]string{"add"})
+	require.NoError(t, err)
+
+	alias, args, err := cmd.Find([]string{"new", "myapp", "/src/myapp"})
+	require.NoError(t, err)
+
+	assert.Equal(t, "new", alias.Name())
+	assert.True(t, alias.Hidden)
+	assert.Equal(t, []string{"myapp", "/src/myapp"}, args)
+
+	// new behaves exactly like add.
+	assert.Equal(t, add.Short, alias.Short)
+	assert.Equal(t, add.Long, alias.Long)
+	assert.NotNil(t, alias.RunE)
+	assert.NotNil(t, alias.Flags().Lookup("upstream"))
+
+	var help bytes.Buffer
+	cmd.SetOut(&help)
+	cmd.SetArgs([]string{"--help"})
+	require.NoError(t, cmd.Execute())
+	assert.Contains(t, help.String(), "\n  add ")
+	assert.NotContains(t, help.String(), "\n  new ")
+	assert.NotContains(t, help.String(), "Aliases")
+}
@@ -6,12 +6,16 @@ import (
 	"log/slog"
 	"os"
 	"runtime/debug"
+	"time"
 
 	configcmd "github.com/mhamza15/forest/cmd/config"
 	projectcmd "github.com/mhamza15/forest/cmd/project"
 	sessioncmd "github.com/mhamza15/forest/cmd/session"
 	treecmd "github.com/mhamza15/forest/cmd/tree"
 	"github.com/mhamza15/forest/internal/completion"
+	"github.com/mhamza15/forest/internal/config"
+	"github.com/mhamza15/forest/internal/network"
+	"github.com/mhamza15/forest/internal/prompt"
 	"github.com/spf13/cobra"
 )
 
@@ -19,7 +23,13 @@ import (
 // is read from the Go module build info (populated by go install).
 var version = ""
 
-var verbose bool
+var (
+	verbose bool
+	timeout time.Duration
+	retries int
+	yes     bool
+	refresh bool
+)
 
 func newRootCmd() *cobra.Command {
 	rootCmd := &cobra.Command{
@@ -39,12 +49,20 @@ tmux session.`,
 			// validation errors, but not for runtime errors.
 			cmd.SilenceUsage = true
 			initLogging()
+			network.SetTimeout(timeout)
+			network.SetRetries(retries)
+			prompt.SetAssumeYes(yes)
+			config.SetRefreshRemotes(refresh)
 		},
 	}
 
 	rootCmd.Version = resolveVersion()
 
 	rootCmd.PersistentFlags().BoolVar(&verbose, "verbose", false, "enable debug logging")
+	rootCmd.PersistentFlags().DurationVar(&timeout, "timeout", network.DefaultTimeout, "timeout for network git and gh operations, except clones and fetches that show progress (0 disables)")
+	rootCmd.PersistentFlags().IntVar(&retries, "retries", network.DefaultRetries, "times to retry network git and gh operations after a transient failure")
+	rootCmd.PersistentFlags().BoolVarP(&yes, "yes", "y", false, "answer yes to all confirmation prompts")
+	rootCmd.PersistentFlags().BoolVar(&refresh, "refresh", false, "ignore cached project remotes and query git when inferring the project")
 	rootCmd.PersistentFlags().StringP("project", "p", "", "project name (inferred from working directory when omitted)")
 
 	if err := rootCmd.RegisterFlagCompletionFunc("project", completion.Projects); err != nil {
@@ -54,7 +72,9 @@ tmux session.`,
 	rootCmd.AddCommand(configcmd.Command())
 	rootCmd.AddCommand(projectcmd.Command())
 	rootCmd.AddCommand(sessioncmd.Command())
+	rootCmd.AddCommand(shellInitCmd())
 	rootCmd.AddCommand(treecmd.Command())
+	rootCmd.AddCommand(versionCmd())
 
 	return rootCmd
 }
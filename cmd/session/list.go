@@ -1,9 +1,14 @@
 package session
 
 import (
+	"errors"
 	"fmt"
+	"log/slog"
 	"os"
+	"path/filepath"
+	"slices"
 	"text/tabwriter"
+	"time"
 
 	"charm.land/lipgloss/v2"
 	"github.com/spf13/cobra"
@@ -19,25 +24,69 @@ var (
 	sessionBranch  = lipgloss.NewStyle().Foreground(lipgloss.Color("#A6E3A1"))
 )
 
+var (
+	orphansFlag bool
+	sortFlag    string
+)
+
 func listCmd() *cobra.Command {
-	return &cobra.Command{
+	cmd := &cobra.Command{
 		Use:   "list",
 		Short: "List active tmux sessions",
-		Args:  cobra.NoArgs,
-		RunE:  runList,
+		Long: `List active tmux sessions for the worktrees of all registered projects.
+
+Use --orphans to instead list sessions created by forest whose worktree
+no longer exists, for example after deleting a worktree directory by
+hand.
+
+Each session shows when it was last active. Use --sort activity to list
+the least recently used sessions first, to spot abandoned ones.`,
+		Args: cobra.NoArgs,
+		RunE: runList,
 	}
+
+	cmd.Flags().BoolVar(&orphansFlag, "orphans", false, "list forest sessions whose worktree no longer exists")
+	cmd.Flags().StringVar(&sortFlag, "sort", "project", "order sessions by project or activity (least recent first)")
+
+	return cmd
+}
+
+// sessionRow is a running session matched to a project worktree.
+type sessionRow struct {
+	info    tmux.SessionInfo
+	project string
+	branch  string
 }
 
 // runList iterates over all registered projects and their worktrees,
 // printing each tmux session that is currently running.
 func runList(_ *cobra.Command, _ []string) error {
+	if sortFlag != "project" && sortFlag != "activity" {
+		return fmt.Errorf("unknown --sort %q (want project or activity)", sortFlag)
+	}
+
+	if orphansFlag {
+		return runListOrphans()
+	}
+
 	projects, err := config.ListProjects()
 	if err != nil {
 		return fmt.Errorf("listing projects: %w", err)
 	}
 
-	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-	var found int
+	// List all tmux sessions once rather than checking each worktree's
+	// session individually.
+	sessions, err := tmux.ListSessions()
+	if err != nil {
+		return err
+	}
+
+	running := make(map[string]tmux.SessionInfo, len(sessions))
+	for _, s := range sessions {
+		running[s.Name] = s
+	}
+
+	var rows []sessionRow
 
 	for _, name := range projects {
 		proj, err := config.LoadProject(name)
@@ -45,7 +94,11 @@ func runList(_ *cobra.Command, _ []string) error {
 			return fmt.Errorf("loading project %q: %w", name, err)
 		}
 
-		worktrees, err := git.List(proj.Repo)
+		if err := config.ValidateRepo(name, proj.RepoPath()); err != nil {
+			return err
+		}
+
+		worktrees, err := git.List(proj.RepoPath())
 		if err != nil {
 			return fmt.Errorf("listing worktrees for %q: %w", name, err)
 		}
@@ -55,25 +108,207 @@ func runList(_ *cobra.Command, _ []string) error {
 				continue
 			}
 
-			session := tmux.SessionName(name, wt.Branch)
-
-			if !tmux.SessionExists(session) {
+			info, ok := running[tmux.SessionName(name, wt.Branch)]
+			if !ok {
 				continue
 			}
 
-			_, _ = fmt.Fprintf(w, "%s\t%s\t%s\n",
-				sessionStyle.Render(session),
-				sessionProject.Render(name),
-				sessionBranch.Render(wt.Branch),
-			)
-			found++
+			rows = append(rows, sessionRow{info: info, project: name, branch: wt.Branch})
 		}
 	}
 
-	if found == 0 {
+	if len(rows) == 0 {
 		fmt.Println("No active sessions.")
 		return nil
 	}
 
+	if sortFlag == "activity" {
+		slices.SortStableFunc(rows, func(a, b sessionRow) int {
+			return a.info.Activity.Compare(b.info.Activity)
+		})
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	now := time.Now()
+
+	for _, r := range rows {
+		state := "detached"
+		if r.info.Attached {
+			state = "attached"
+		}
+
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			sessionStyle.Render(r.info.Name),
+			sessionProject.Render(r.project),
+			sessionBranch.Render(r.branch),
+			state,
+			windowCount(r.info.Windows),
+			ago(now, r.info.Activity),
+		)
+	}
+
 	return w.Flush()
 }
+
+// ago formats the time elapsed since t relative to now, e.g. "2h ago".
+func ago(now, t time.Time) string {
+	d := now.Sub(t)
+
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%dd ago", int(d.Hours()/24))
+	}
+}
+
+// windowCount formats a window count, e.g. "1 window" or "3 windows".
+func windowCount(n int) string {
+	if n == 1 {
+		return "1 window"
+	}
+
+	return fmt.Sprintf("%d windows", n)
+}
+
+// runListOrphans prints forest sessions whose worktree no longer
+// exists.
+func runListOrphans() error {
+	orphans, err := orphanedSessions()
+	if err != nil {
+		return err
+	}
+
+	if len(orphans) == 0 {
+		fmt.Println("No orphaned sessions.")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+	for _, o := range orphans {
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\n",
+			sessionStyle.Render(o.Name),
+			sessionProject.Render(o.Project),
+			sessionBranch.Render(o.Branch),
+		)
+	}
+
+	return w.Flush()
+}
+
+// orphanedSessions returns the running sessions tagged as created by
+// forest whose project is no longer registered or whose worktree no
+// longer exists. Sessions of projects whose worktrees cannot be listed
+// are kept, since their worktrees may still be there.
+func orphanedSessions() ([]tmux.SessionInfo, error) {
+	sessions, err := tmux.ListSessions()
+	if err != nil {
+		return nil, err
+	}
+
+	// Cache worktree lists since many sessions share a project.
+	projects := make(map[string]projectTrees)
+
+	var orphans []tmux.SessionInfo
+
+	for _, s := range sessions {
+		if !s.Forest {
+			continue
+		}
+
+		pt, ok := projects[s.Project]
+		if !ok {
+			pt = listProjectTrees(s.Project)
+			projects[s.Project] = pt
+		}
+
+		if pt.orphaned(s) {
+			orphans = append(orphans, s)
+		}
+	}
+
+	return orphans, nil
+}
+
+// projectTrees holds a project's worktrees for orphan detection.
+type projectTrees struct {
+	trees []git.Worktree
+
+	// prefix is the project's worktree_prefix, which starts the
+	// directory names of its worktrees.
+	prefix string
+
+	// known is false when the worktrees could not be listed, in which
+	// case no session of the project counts as orphaned.
+	known bool
+}
+
+// listProjectTrees lists the worktrees of a project. An unregistered
+// project has none; any other failure leaves them unknown.
+func listProjectTrees(project string) projectTrees {
+	proj, err := config.LoadProject(project)
+	if errors.Is(err, config.ErrProjectNotFound) {
+		return projectTrees{known: true}
+	}
+
+	if err == nil {
+		err = config.ValidateRepo(project, proj.RepoPath())
+	}
+
+	var trees []git.Worktree
+	if err == nil {
+		trees, err = git.List(proj.RepoPath())
+	}
+
+	if err != nil {
+		slog.Debug("could not list worktrees for session project",
+			slog.String("project", project),
+			slog.Any("err", err),
+		)
+
+		return projectTrees{}
+	}
+
+	return projectTrees{trees: trees, prefix: proj.WorktreePrefix, known: true}
+}
+
+// orphaned reports whether no worktree of the project belongs to the
+// session. Sessions are matched by their recorded worktree path, which
+// also covers detached worktrees. Sessions tagged before the path was
+// recorded are matched by branch, or for detached worktrees by the
+// directory named after the tree, with or without the project's
+// worktree_prefix.
+func (p projectTrees) orphaned(s tmux.SessionInfo) bool {
+	if !p.known {
+		return false
+	}
+
+	for _, t := range p.trees {
+		if t.Bare {
+			continue
+		}
+
+		switch {
+		case s.Path != "":
+			if filepath.Clean(t.Path) == filepath.Clean(s.Path) {
+				return false
+			}
+		case t.Branch != "":
+			if t.Branch == s.Branch {
+				return false
+			}
+		default:
+			dir := git.SafeBranchDir(s.Branch)
+			if base := filepath.Base(t.Path); base == dir || base == p.prefix+dir {
+				return false
+			}
+		}
+	}
+
+	return true
+}
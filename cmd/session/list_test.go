@@ -0,0 +1,85 @@
+package session
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mhamza15/forest/internal/git"
+	"github.com/mhamza15/forest/internal/tmux"
+)
+
+func TestProjectTrees_Orphaned(t *testing.T) {
+	trees := projectTrees{
+		trees: []git.Worktree{
+			{Path: "/repo", Bare: true},
+			{Path: "/trees/app/feature-login", Branch: "feature/login"},
+			{Path: "/trees/app/tag-v1.0"},
+			{Path: "/trees/app/wt-pr-12-abc1234"},
+		},
+		prefix: "wt-",
+		known:  true,
+	}
+
+	tests := []struct {
+		name    string
+		session tmux.SessionInfo
+		want    bool
+	}{
+		{
+			name:    "recorded path",
+			session: tmux.SessionInfo{Branch: "tag-v1.0", Path: "/trees/app/tag-v1.0/"},
+			want:    false,
+		},
+		{
+			name:    "recorded path removed",
+			session: tmux.SessionInfo{Branch: "feature/login", Path: "/trees/app/old"},
+			want:    true,
+		},
+		{
+			name:    "legacy branch",
+			session: tmux.SessionInfo{Branch: "feature/login"},
+			want:    false,
+		},
+		{
+			name:    "legacy branch removed",
+			session: tmux.SessionInfo{Branch: "feature/signup"},
+			want:    true,
+		},
+		{
+			name:    "legacy detached",
+			session: tmux.SessionInfo{Branch: "tag-v1.0"},
+			want:    false,
+		},
+		{
+			name:    "legacy detached with prefix",
+			session: tmux.SessionInfo{Branch: "pr-12-abc1234"},
+			want:    false,
+		},
+		{
+			name:    "legacy detached suffix only",
+			session: tmux.SessionInfo{Branch: "v1.0"},
+			want:    true,
+		},
+		{
+			name:    "legacy detached bare repo",
+			session: tmux.SessionInfo{Branch: "repo"},
+			want:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, trees.orphaned(tt.session))
+		})
+	}
+}
+
+func TestProjectTrees_OrphanedUnknown(t *testing.T) {
+	// Sessions of a project whose worktrees could not be listed are
+	// never reported.
+	assert.False(t, projectTrees{}.orphaned(tmux.SessionInfo{Branch: "feature"}))
+
+	// A removed project has no worktrees left.
+	assert.True(t, projectTrees{known: true}.orphaned(tmux.SessionInfo{Branch: "feature"}))
+}
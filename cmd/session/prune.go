@@ -0,0 +1,78 @@
+package session
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mhamza15/forest/internal/prompt"
+	"github.com/mhamza15/forest/internal/tmux"
+)
+
+var dryRunFlag bool
+
+func pruneCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Kill forest sessions whose worktree no longer exists",
+		Long: `Kill tmux sessions created by forest whose worktree no longer exists,
+for example after deleting a worktree directory by hand or removing
+it with git directly. Sessions not created by forest are never
+touched, and neither are sessions of projects whose worktrees cannot
+be listed, for example because the repository is not mounted.
+
+The sessions are listed and confirmed before any are killed. Pass
+--yes to skip the prompt.`,
+		Args: cobra.NoArgs,
+		RunE: runPrune,
+	}
+
+	cmd.Flags().BoolVar(&dryRunFlag, "dry-run", false, "show what would be killed without killing")
+
+	return cmd
+}
+
+func runPrune(_ *cobra.Command, _ []string) error {
+	orphans, err := orphanedSessions()
+	if err != nil {
+		return err
+	}
+
+	if len(orphans) == 0 {
+		fmt.Println("Nothing to prune.")
+		return nil
+	}
+
+	if dryRunFlag {
+		for _, o := range orphans {
+			fmt.Printf("would kill %s\n", o.Name)
+		}
+
+		return nil
+	}
+
+	for _, o := range orphans {
+		fmt.Println(o.Name)
+	}
+
+	ok, err := prompt.Confirm(fmt.Sprintf("Kill %d session(s)? [y/N] ", len(orphans)))
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		fmt.Println("Nothing pruned.")
+		return nil
+	}
+
+	for _, o := range orphans {
+		if err := tmux.KillSession(o.Name); err != nil {
+			fmt.Printf("failed to kill %s: %s\n", o.Name, err)
+			continue
+		}
+
+		fmt.Printf("killed %s\n", o.Name)
+	}
+
+	return nil
+}
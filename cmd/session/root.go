@@ -13,6 +13,7 @@ func Command() *cobra.Command {
 
 	cmd.AddCommand(killCmd())
 	cmd.AddCommand(listCmd())
+	cmd.AddCommand(pruneCmd())
 
 	return cmd
 }
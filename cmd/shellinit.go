@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+// posixShellInit wraps forest for bash and zsh. tree switch writes the
+// worktree path to a temp file, so that the command's own output still
+// reaches the terminal.
+const posixShellInit = `forest() {
+  if [ "$1" = tree ] && [ "$2" = switch ]; then
+    shift 2
+    local forest_path_file forest_status forest_dir
+    forest_path_file=$(mktemp) || return
+    command forest tree switch --print-path "$forest_path_file" "$@"
+    forest_status=$?
+    forest_dir=$(cat "$forest_path_file")
+    rm -f "$forest_path_file"
+    if [ "$forest_status" -eq 0 ] && [ -n "$forest_dir" ] && [ -d "$forest_dir" ]; then
+      cd "$forest_dir" || return
+    fi
+    return "$forest_status"
+  fi
+  command forest "$@"
+}
+`
+
+// fishShellInit is posixShellInit for fish.
+const fishShellInit = `function forest --description 'forest, changing into the worktree after tree switch'
+    if test (count $argv) -ge 2; and test "$argv[1]" = tree; and test "$argv[2]" = switch
+        set -l forest_path_file (mktemp); or return
+        command forest tree switch --print-path $forest_path_file $argv[3..-1]
+        set -l forest_status $status
+        set -l forest_dir (cat $forest_path_file)
+        rm -f $forest_path_file
+        if test $forest_status -eq 0; and test -n "$forest_dir"; and test -d "$forest_dir"
+            cd $forest_dir
+        end
+        return $forest_status
+    end
+    command forest $argv
+end
+`
+
+func shellInitCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "shell-init {bash | zsh | fish}",
+		Short: "Print a shell function that changes into switched worktrees",
+		Long: `Print a shell function wrapping forest, so that tree switch changes
+the current shell into the worktree when it does not switch tmux
+clients, for example with --no-session or when auto_session is false.
+A program cannot change its parent shell's directory, so the function
+reads the path that tree switch --print-path writes and runs cd itself.
+
+Add it to your shell's startup file:
+
+  eval "$(forest shell-init bash)"   # ~/.bashrc
+  eval "$(forest shell-init zsh)"    # ~/.zshrc
+  forest shell-init fish | source    # ~/.config/fish/config.fish`,
+		Args:      cobra.ExactArgs(1),
+		ValidArgs: []string{"bash", "zsh", "fish"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			switch args[0] {
+			case "bash", "zsh":
+				fmt.Print(posixShellInit)
+			case "fish":
+				fmt.Print(fishShellInit)
+			default:
+				return fmt.Errorf("unsupported shell %q: want bash, zsh or fish", args[0])
+			}
+
+			return nil
+		},
+	}
+}
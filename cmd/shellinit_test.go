@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeForest is a stand-in for the forest binary that writes the
+// directory in $FOREST_TEST_DIR to the --print-path file and echoes
+// its arguments.
+const fakeForest = `#!/bin/sh
+echo "args: $*"
+while [ $# -gt 0 ]; do
+  if [ "$1" = --print-path ]; then
+    printf '%s\n' "$FOREST_TEST_DIR" > "$2"
+  fi
+  shift
+done
+`
+
+func TestShellInit(t *testing.T) {
+	tests := []struct {
+		shell  string
+		script string
+		flag   string
+		source string
+	}{
+		{"bash", posixShellInit, "--norc", "."},
+		{"zsh", posixShellInit, "-f", "."},
+		{"fish", fishShellInit, "--no-config", "source"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.shell, func(t *testing.T) {
+			if _, err := exec.LookPath(tt.shell); err != nil {
+				t.Skipf("%s not installed", tt.shell)
+			}
+
+			bin := t.TempDir()
+			require.NoError(t, os.WriteFile(filepath.Join(bin, "forest"), []byte(fakeForest), 0o755))
+
+			initFile := filepath.Join(t.TempDir(), "init")
+			require.NoError(t, os.WriteFile(initFile, []byte(tt.script), 0o644))
+
+			target, err := filepath.EvalSymlinks(t.TempDir())
+			require.NoError(t, err)
+
+			line := tt.source + " " + initFile + "; forest tree switch feature -p demo; pwd"
+			cmd := exec.Command(tt.shell, tt.flag, "-c", line)
+			cmd.Env = append(os.Environ(), "PATH="+bin+string(os.PathListSeparator)+os.Getenv("PATH"), "FOREST_TEST_DIR="+target)
+
+			out, err := cmd.CombinedOutput()
+			require.NoError(t, err, string(out))
+
+			lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+			require.Len(t, lines, 2, string(out))
+			assert.True(t, strings.HasPrefix(lines[0], "args: tree switch --print-path "), lines[0])
+			assert.True(t, strings.HasSuffix(lines[0], " feature -p demo"), lines[0])
+			assert.Equal(t, target, lines[1])
+		})
+	}
+}
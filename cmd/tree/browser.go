@@ -1,6 +1,8 @@
 package tree
 
 import (
+	"fmt"
+
 	tea "charm.land/bubbletea/v2"
 
 	"github.com/mhamza15/forest/internal/tui"
@@ -24,6 +26,10 @@ func runTreeBrowser(project string) error {
 	// Execute any deferred action (e.g. switching to a tmux session)
 	// after the TUI has finished and restored the terminal.
 	if final, ok := result.(tui.Model); ok {
+		for _, path := range final.Yanked() {
+			fmt.Println(path)
+		}
+
 		if action := final.Action(); action != nil {
 			return action()
 		}
@@ -0,0 +1,226 @@
+package tree
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mhamza15/forest/internal/completion"
+	"github.com/mhamza15/forest/internal/config"
+	"github.com/mhamza15/forest/internal/forest"
+	"github.com/mhamza15/forest/internal/git"
+	"github.com/mhamza15/forest/internal/tmux"
+)
+
+var infoJSONFlag bool
+
+// treeInfo holds the details reported by tree info. Pointer fields are
+// nil when the value could not be determined.
+type treeInfo struct {
+	Project         string      `json:"project"`
+	Branch          string      `json:"branch"`
+	Path            string      `json:"path"`
+	Base            string      `json:"base"`
+	AheadOfBase     *int        `json:"ahead_of_base,omitempty"`
+	BehindBase      *int        `json:"behind_base,omitempty"`
+	Upstream        string      `json:"upstream,omitempty"`
+	AheadOfUpstream *int        `json:"ahead_of_upstream,omitempty"`
+	BehindUpstream  *int        `json:"behind_upstream,omitempty"`
+	Dirty           *bool       `json:"dirty,omitempty"`
+	Merged          bool        `json:"merged"`
+	OnRemote        *bool       `json:"on_remote,omitempty"`
+	Session         string      `json:"session"`
+	SessionActive   bool        `json:"session_active"`
+	LastCommit      *commitJSON `json:"last_commit,omitempty"`
+}
+
+type commitJSON struct {
+	Hash    string    `json:"hash"`
+	Subject string    `json:"subject"`
+	Author  string    `json:"author"`
+	Date    time.Time `json:"date"`
+}
+
+func infoCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "info <branch>",
+		Short: "Show details about a worktree",
+		Long: `Show details about a single worktree: its path, base branch, how far
+it is ahead of or behind its base and upstream, whether it has local
+changes, whether it is merged, whether the branch still exists on the
+remote, its tmux session, and its last commit.
+
+Use --json for machine-readable output.`,
+		Args:              cobra.ExactArgs(1),
+		RunE:              runInfo,
+		ValidArgsFunction: completion.Branches,
+	}
+
+	cmd.Flags().BoolVar(&infoJSONFlag, "json", false, "print details as JSON")
+
+	return cmd
+}
+
+func runInfo(cmd *cobra.Command, args []string) error {
+	projectFlag, _ := cmd.Flags().GetString("project")
+	branch := args[0]
+
+	project, err := resolveProject(projectFlag)
+	if err != nil {
+		return err
+	}
+
+	rc, err := config.Resolve(project)
+	if err != nil {
+		return err
+	}
+
+	if err := config.ValidateRepo(project, rc.Repo); err != nil {
+		return err
+	}
+
+	info, err := collectTreeInfo(rc, branch)
+	if err != nil {
+		return err
+	}
+
+	if infoJSONFlag {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+
+		return enc.Encode(info)
+	}
+
+	printTreeInfo(info)
+
+	return nil
+}
+
+// collectTreeInfo gathers the details for one worktree. Failures to
+// determine individual fields are logged and leave the field unset
+// rather than failing the whole command.
+func collectTreeInfo(rc config.ResolvedConfig, branch string) (treeInfo, error) {
+	wt := git.FindByBranch(rc.Repo, branch)
+	if wt == nil {
+		return treeInfo{}, fmt.Errorf("no worktree found for branch %q in project %q", branch, rc.Name)
+	}
+
+	base := forest.PruneTarget(rc, branch)
+	session := tmux.SessionName(rc.Name, branch)
+
+	info := treeInfo{
+		Project:       rc.Name,
+		Branch:        branch,
+		Path:          wt.Path,
+		Base:          base,
+		Merged:        git.IsMerged(rc.Repo, branch, base),
+		Session:       session,
+		SessionActive: tmux.SessionExists(session),
+	}
+
+	if ahead, behind, err := git.AheadBehind(rc.Repo, branch, base); err == nil {
+		info.AheadOfBase, info.BehindBase = &ahead, &behind
+	} else {
+		slog.Debug("could not compare with base", slog.String("base", base), slog.Any("err", err))
+	}
+
+	if upstream := git.Upstream(rc.Repo, branch); upstream != "" {
+		info.Upstream = upstream
+
+		if ahead, behind, err := git.AheadBehind(rc.Repo, branch, upstream); err == nil {
+			info.AheadOfUpstream, info.BehindUpstream = &ahead, &behind
+		} else {
+			slog.Debug("could not compare with upstream", slog.String("upstream", upstream), slog.Any("err", err))
+		}
+	}
+
+	if dirty, err := git.IsDirty(wt.Path); err == nil {
+		info.Dirty = &dirty
+	} else {
+		slog.Debug("could not check worktree status", slog.String("path", wt.Path), slog.Any("err", err))
+	}
+
+	if remoteBranches, err := git.RemoteBranches(rc.Repo, rc.UpstreamRemote); err == nil {
+		onRemote := remoteBranches[branch]
+		info.OnRemote = &onRemote
+	} else {
+		slog.Debug("could not fetch remote branches", slog.String("project", rc.Name), slog.Any("err", err))
+	}
+
+	if commit, err := git.LastCommit(wt.Path); err == nil {
+		info.LastCommit = &commitJSON{
+			Hash:    commit.Hash,
+			Subject: commit.Subject,
+			Author:  commit.Author,
+			Date:    commit.Date,
+		}
+	} else {
+		slog.Debug("could not read last commit", slog.String("path", wt.Path), slog.Any("err", err))
+	}
+
+	return info, nil
+}
+
+func printTreeInfo(info treeInfo) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+	row := func(key, value string) {
+		_, _ = fmt.Fprintf(w, "%s\t%s\n", key, value)
+	}
+
+	row("Project", projectStyle.Render(info.Project))
+	row("Branch", branchStyle.Render(info.Branch))
+	row("Path", info.Path)
+	row("Base", info.Base+aheadBehind(info.AheadOfBase, info.BehindBase))
+
+	if info.Upstream != "" {
+		row("Upstream", info.Upstream+aheadBehind(info.AheadOfUpstream, info.BehindUpstream))
+	} else {
+		row("Upstream", "none")
+	}
+
+	row("Dirty", yesNo(info.Dirty))
+	row("Merged", yesNo(&info.Merged))
+	row("On remote", yesNo(info.OnRemote))
+
+	sessionState := "not running"
+	if info.SessionActive {
+		sessionState = "running"
+	}
+
+	row("Session", fmt.Sprintf("%s (%s)", info.Session, sessionState))
+
+	if c := info.LastCommit; c != nil {
+		row("Last commit", fmt.Sprintf("%s %s", c.Hash[:min(len(c.Hash), 7)], c.Subject))
+		row("Author", fmt.Sprintf("%s, %s", c.Author, c.Date.Format(time.DateTime)))
+	}
+
+	_ = w.Flush()
+}
+
+// aheadBehind formats ahead/behind counts as a parenthesized suffix,
+// or returns an empty string when they are unknown.
+func aheadBehind(ahead, behind *int) string {
+	if ahead == nil || behind == nil {
+		return ""
+	}
+
+	return fmt.Sprintf(" (ahead %d, behind %d)", *ahead, *behind)
+}
+
+// yesNo formats an optional boolean, using "unknown" when unset.
+func yesNo(v *bool) string {
+	switch {
+	case v == nil:
+		return "unknown"
+	case *v:
+		return "yes"
+	default:
+		return "no"
+	}
+}
@@ -0,0 +1,64 @@
+package tree
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/mhamza15/forest/internal/clipboard"
+)
+
+// readStdinArg reads a single argument from the first non-empty line
+// of r.
+func readStdinArg(r io.Reader) (string, error) {
+	scanner := bufio.NewScanner(r)
+
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			return line, nil
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("reading stdin: %w", err)
+	}
+
+	return "", errors.New("no branch or URL on stdin")
+}
+
+// readClipboard returns the first non-empty line of the system
+// clipboard.
+func readClipboard() (string, error) {
+	text, err := clipboard.Read()
+	if err != nil {
+		return "", err
+	}
+
+	value, err := readStdinArg(strings.NewReader(text))
+	if err != nil {
+		return "", errors.New("clipboard is empty")
+	}
+
+	return value, nil
+}
+
+// switchArg returns the branch or URL to switch to: the positional
+// argument, stdin when the argument is "-", or the clipboard when
+// fromClipboard is set.
+func switchArg(args []string, fromClipboard bool) (string, error) {
+	switch {
+	case fromClipboard && len(args) > 0:
+		return "", errors.New("--clipboard cannot be combined with a branch argument")
+	case fromClipboard:
+		return readClipboard()
+	case len(args) == 0:
+		return "", errors.New("requires a branch or GitHub link, or --clipboard")
+	case args[0] == "-":
+		return readStdinArg(os.Stdin)
+	default:
+		return args[0], nil
+	}
+}
@@ -0,0 +1,113 @@
+package tree
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadStdinArg(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr string
+	}{
+		{name: "single line", input: "feature/login\n", want: "feature/login"},
+		{name: "no trailing newline", input: "feature/login", want: "feature/login"},
+		{name: "surrounding whitespace", input: "  \tfeature/login \r\n", want: "feature/login"},
+		{name: "leading blank lines", input: "\n  \n\nfeature/login\n", want: "feature/login"},
+		{name: "extra lines ignored", input: "feature/login\nfeature/signup\nmain\n", want: "feature/login"},
+		{name: "url", input: "https://github.com/acme/app/pull/12\n", want: "https://github.com/acme/app/pull/12"},
+		{name: "empty", input: "", wantErr: "no branch or URL on stdin"},
+		{name: "only whitespace", input: " \n\t\n\r\n", wantErr: "no branch or URL on stdin"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readStdinArg(strings.NewReader(tt.input))
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestSwitchArg(t *testing.T) {
+	got, err := switchArg([]string{"feature"}, false)
+	require.NoError(t, err)
+	assert.Equal(t, "feature", got)
+
+	_, err = switchArg(nil, false)
+	require.ErrorContains(t, err, "requires a branch or GitHub link")
+
+	_, err = switchArg([]string{"feature"}, true)
+	require.ErrorContains(t, err, "cannot be combined")
+}
+
+func TestSwitchArg_Stdin(t *testing.T) {
+	withStdin(t, "\n  feature/login  \nmain\n")
+
+	got, err := switchArg([]string{"-"}, false)
+	require.NoError(t, err)
+	assert.Equal(t, "feature/login", got)
+
+	withStdin(t, "  \n")
+
+	_, err = switchArg([]string{"-"}, false)
+	require.EqualError(t, err, "no branch or URL on stdin")
+}
+
+func TestSwitchArg_Clipboard(t *testing.T) {
+	fakeClipboard(t, "\n  https://github.com/acme/app/pull/12 \nfeature\n")
+
+	got, err := switchArg(nil, true)
+	require.NoError(t, err)
+	assert.Equal(t, "https://github.com/acme/app/pull/12", got)
+
+	fakeClipboard(t, " \n\n")
+
+	_, err = switchArg(nil, true)
+	require.EqualError(t, err, "clipboard is empty")
+}
+
+// withStdin replaces os.Stdin with a file holding input for the rest
+// of the test.
+func withStdin(t *testing.T, input string) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "stdin")
+	require.NoError(t, os.WriteFile(path, []byte(input), 0o644))
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+
+	stdin := os.Stdin
+	os.Stdin = f
+
+	t.Cleanup(func() {
+		os.Stdin = stdin
+		_ = f.Close()
+	})
+}
+
+// fakeClipboard puts a pbpaste that prints text first on PATH, so that
+// the clipboard reads text.
+func fakeClipboard(t *testing.T, text string) {
+	t.Helper()
+
+	bin := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(bin, "text"), []byte(text), 0o644))
+
+	script := "#!/bin/sh\nwhile IFS= read -r line || [ -n \"$line\" ]; do printf '%s\\n' \"$line\"; done < " + filepath.Join(bin, "text") + "\n"
+	require.NoError(t, os.WriteFile(filepath.Join(bin, "pbpaste"), []byte(script), 0o755))
+	t.Setenv("PATH", bin)
+}
@@ -2,6 +2,7 @@ package tree
 
 import (
 	"fmt"
+	"log/slog"
 	"os"
 	"text/tabwriter"
 
@@ -9,22 +10,60 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/mhamza15/forest/internal/config"
+	"github.com/mhamza15/forest/internal/forest"
 	"github.com/mhamza15/forest/internal/git"
+	"github.com/mhamza15/forest/internal/github"
 )
 
 var (
-	projectStyle = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#89B4FA"))
-	branchStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("#A6E3A1"))
-	pathDimStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#6C7086"))
+	projectStyle   = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#89B4FA"))
+	branchStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("#A6E3A1"))
+	pathDimStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("#6C7086"))
+	repoLabelStyle = lipgloss.NewStyle().Italic(true).Foreground(lipgloss.Color("#6C7086"))
+)
+
+var (
+	sizeFlag   bool
+	mergedFlag bool
+	staleFlag  bool
+	allFlag    bool
+	prsFlag    bool
+	repoFlag   bool
 )
 
 func listCmd() *cobra.Command {
-	return &cobra.Command{
+	cmd := &cobra.Command{
 		Use:   "list",
 		Short: "List worktrees for one or all projects",
 		Args:  cobra.NoArgs,
-		RunE:  runList,
+		Long: `List worktrees for one or all projects.
+
+Use --merged to show only worktrees whose branch is merged into the
+project's base branch, and --stale to show only worktrees whose branch
+is gone from the remote. Together they preview what tree prune would
+consider.
+
+Bare and detached entries are skipped unless --all is passed, which
+lists every worktree git knows about and labels the main working tree
+(main), bare repositories (bare), and detached worktrees (detached).
+
+Use --prs to mark branches that have an open pull request with its
+number, e.g. #123. PRs are looked up on the project's upstream_remote,
+and this makes one gh call per project.
+
+Use --show-repo to print the repository each project's worktrees
+belong to under its heading.`,
+		RunE: runList,
 	}
+
+	cmd.Flags().BoolVar(&sizeFlag, "size", false, "show the disk usage of each worktree")
+	cmd.Flags().BoolVar(&mergedFlag, "merged", false, "only show worktrees merged into the base branch")
+	cmd.Flags().BoolVar(&staleFlag, "stale", false, "only show worktrees whose branch is gone from the remote")
+	cmd.Flags().BoolVar(&allFlag, "all", false, "include the main, bare and detached worktrees, labeled")
+	cmd.Flags().BoolVar(&prsFlag, "prs", false, "show the open pull request of each branch (queries gh)")
+	cmd.Flags().BoolVar(&repoFlag, "show-repo", false, "show the repository path under each project")
+
+	return cmd
 }
 
 func runList(cmd *cobra.Command, args []string) error {
@@ -46,31 +85,66 @@ func runList(cmd *cobra.Command, args []string) error {
 	found := false
 
 	for _, name := range names {
-		proj, err := config.LoadProject(name)
+		rc, err := config.Resolve(name)
 		if err != nil {
 			return err
 		}
 
-		trees, err := git.List(proj.Repo)
+		if err := config.ValidateRepo(name, rc.Repo); err != nil {
+			return err
+		}
+
+		trees, err := git.List(rc.Repo)
 		if err != nil {
 			return err
 		}
 
+		var remoteBranches map[string]bool
+		if staleFlag {
+			remoteBranches, err = git.RemoteBranches(rc.Repo, rc.UpstreamRemote)
+			if err != nil {
+				slog.Debug("could not fetch remote branches", slog.String("project", name), slog.Any("err", err))
+			}
+		}
+
+		openPRs := listOpenPRs(rc)
+
 		// Buffer worktree lines so we only print the project header
 		// when there is at least one non-skipped worktree.
 		type row struct {
 			branch string
 			path   string
+			size   string
 		}
 
 		var rows []row
 
-		for _, t := range trees {
-			if t.Bare || t.Branch == "" {
+		for i, t := range trees {
+			if !allFlag && (t.Bare || t.Branch == "") {
 				continue
 			}
 
-			rows = append(rows, row{branch: t.Branch, path: t.Path})
+			if (mergedFlag || staleFlag) && !isPruneCandidate(t, rc, remoteBranches) {
+				continue
+			}
+
+			r := row{branch: branchStyle.Render(t.Branch), path: t.Path}
+
+			if allFlag {
+				// git worktree list always reports the main working
+				// tree (or bare repository) first.
+				r.branch = branchStyle.Render(worktreeLabel(t, i == 0))
+			}
+
+			if pr, ok := openPRs[t.Branch]; ok && t.Branch != "" {
+				r.branch += " " + prReasonStyle.Render(fmt.Sprintf("#%d", pr.Number))
+			}
+
+			if sizeFlag {
+				r.size = worktreeSize(t.Path)
+			}
+
+			rows = append(rows, r)
 		}
 
 		if len(rows) == 0 {
@@ -80,9 +154,30 @@ func runList(cmd *cobra.Command, args []string) error {
 		found = true
 		_, _ = fmt.Fprintln(w, projectStyle.Render(name))
 
+		if repoFlag {
+			// Keep the path in the path column, past the size column
+			// when there is one.
+			sizeCol := ""
+			if sizeFlag {
+				sizeCol = "\t"
+			}
+
+			_, _ = fmt.Fprintf(w, "  %s\t%s%s\n", repoLabelStyle.Render("(repo)"), sizeCol, pathDimStyle.Render(rc.Repo))
+		}
+
 		for _, r := range rows {
+			if sizeFlag {
+				_, _ = fmt.Fprintf(w, "  %s\t%s\t%s\n",
+					r.branch,
+					r.size,
+					pathDimStyle.Render(r.path),
+				)
+
+				continue
+			}
+
 			_, _ = fmt.Fprintf(w, "  %s\t%s\n",
-				branchStyle.Render(r.branch),
+				r.branch,
 				pathDimStyle.Render(r.path),
 			)
 		}
@@ -95,3 +190,87 @@ func runList(cmd *cobra.Command, args []string) error {
 
 	return w.Flush()
 }
+
+// listOpenPRs returns the project's open PRs keyed by head branch when
+// --prs is set. Failures are logged and leave branches unmarked.
+func listOpenPRs(rc config.ResolvedConfig) map[string]github.PRSummary {
+	if !prsFlag {
+		return nil
+	}
+
+	nwo := forest.RemoteNWO(rc.Repo, rc.UpstreamRemote)
+	if nwo == "" {
+		return nil
+	}
+
+	prs, err := github.ListPRs(nwo, "open")
+	if err != nil {
+		slog.Debug("could not list open PRs", slog.String("project", rc.Name), slog.Any("err", err))
+		return nil
+	}
+
+	return github.PRsByBranch(prs)
+}
+
+// worktreeLabel returns the branch column for --all, labeling bare,
+// detached and main worktrees.
+func worktreeLabel(t git.Worktree, main bool) string {
+	switch {
+	case t.Bare:
+		return "(bare)"
+	case t.Branch == "" && main:
+		return "(detached) (main)"
+	case t.Branch == "":
+		return "(detached)"
+	case main:
+		return t.Branch + " (main)"
+	default:
+		return t.Branch
+	}
+}
+
+// isPruneCandidate reports whether a worktree matches the --merged or
+// --stale filters, using the same checks as tree prune.
+func isPruneCandidate(t git.Worktree, rc config.ResolvedConfig, remoteBranches map[string]bool) bool {
+	if !forest.PruneEligible(t, rc, nil) {
+		return false
+	}
+
+	switch git.PruneCheck(rc.Repo, t.Branch, forest.PruneTarget(rc, t.Branch), remoteBranches) {
+	case git.PruneMerged:
+		return mergedFlag
+	case git.PruneRemoteGone:
+		return staleFlag
+	default:
+		return false
+	}
+}
+
+// worktreeSize returns the human-readable disk usage of a worktree, or
+// "?" if it cannot be measured.
+func worktreeSize(path string) string {
+	size, err := git.WorktreeSize(path)
+	if err != nil {
+		slog.Debug("could not measure worktree size", slog.String("path", path), slog.Any("err", err))
+		return "?"
+	}
+
+	return formatSize(size)
+}
+
+// formatSize formats a byte count using binary units (e.g. "1.5 MiB").
+func formatSize(bytes int64) string {
+	const unit = 1024
+
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
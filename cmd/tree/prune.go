@@ -1,19 +1,29 @@
 package tree
 
 import (
+	"encoding/json"
 	"fmt"
-	"log/slog"
-	"path/filepath"
+	"os"
+	"strings"
+	"text/tabwriter"
 
+	"charm.land/lipgloss/v2"
 	"github.com/spf13/cobra"
 
 	"github.com/mhamza15/forest/internal/config"
 	"github.com/mhamza15/forest/internal/forest"
-	"github.com/mhamza15/forest/internal/git"
-	"github.com/mhamza15/forest/internal/github"
+	"github.com/mhamza15/forest/internal/prompt"
 )
 
-var dryRunFlag bool
+var (
+	dryRunFlag     bool
+	checkPRsFlag   bool
+	mergedOnlyFlag bool
+	goneOnlyFlag   bool
+	excludeFlag    []string
+	remoteFlag     string
+	pruneJSONFlag  bool
+)
 
 func pruneCmd() *cobra.Command {
 	cmd := &cobra.Command{
@@ -23,19 +33,79 @@ func pruneCmd() *cobra.Command {
 into the project's base branch. When a branch no longer exists on
 the remote, forest checks via gh whether the PR was merged (common
 after squash-merge workflows). If gh is unavailable or the PR was
-not merged, an interactive confirmation is shown instead.`,
+not merged, an interactive confirmation is shown instead.
+
+With --check-prs, branches that still exist on the remote but are not
+merged locally are also checked via gh. A merged PR marks the branch
+as prunable, catching squash-merged PRs whose branch was not deleted.
+
+Use --merged-only to consider only merged branches, which never
+prompts and is safe for automation. Use --gone-only to consider only
+branches deleted from the remote.
+
+Branches matching a prune_exclude glob in the global or project config,
+or an --exclude pattern, are never pruned. Patterns use path.Match
+syntax, so "release/*" matches "release/1.0".
+
+The remote is origin unless the project sets upstream_remote, for
+repositories where another remote such as upstream is the source of
+truth. Pull requests are looked up in that remote's repository too.
+Use --remote to check a different remote for one run.
+
+Use --json with --dry-run to print the plan as a JSON array of
+{project, branch, reason, path} objects. Nothing is removed and no
+prompts are shown; branches that would need confirmation are reported
+with the reason "needs_confirmation".`,
 		Args: cobra.NoArgs,
 		RunE: runPrune,
 	}
 
 	cmd.Flags().BoolVar(&dryRunFlag, "dry-run", false, "show what would be pruned without removing")
+	cmd.Flags().BoolVar(&checkPRsFlag, "check-prs", false, "check gh for merged PRs on branches still present on the remote")
+	cmd.Flags().BoolVar(&mergedOnlyFlag, "merged-only", false, "only prune branches merged into the base branch")
+	cmd.Flags().BoolVar(&goneOnlyFlag, "gone-only", false, "only prune branches deleted from the remote")
+	cmd.Flags().StringArrayVar(&excludeFlag, "exclude", nil, "glob of branches to never prune (repeatable)")
+	cmd.Flags().StringVar(&remoteFlag, "remote", "", "remote to check for deleted branches and merged PRs (overrides upstream_remote)")
+	cmd.Flags().BoolVar(&pruneJSONFlag, "json", false, "print the prune plan as JSON (requires --dry-run)")
+	cmd.MarkFlagsMutuallyExclusive("merged-only", "gone-only")
 
 	return cmd
 }
 
+var (
+	mergedReasonStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#94E2D5"))
+	prReasonStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("#CBA6F7"))
+	goneReasonStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("#F9E2AF"))
+	failedStyle       = lipgloss.NewStyle().Foreground(lipgloss.Color("#F38BA8"))
+)
+
+// renderLabel returns a prune label colored by reason.
+func renderLabel(l forest.PruneLabel) string {
+	switch l {
+	case forest.PruneLabelMerged:
+		return mergedReasonStyle.Render(string(l))
+	case forest.PruneLabelPRMerged:
+		return prReasonStyle.Render(string(l))
+	default:
+		return goneReasonStyle.Render(string(l))
+	}
+}
+
+// prunePlanEntry is one element of the --json output.
+type prunePlanEntry struct {
+	Project string `json:"project"`
+	Branch  string `json:"branch"`
+	Reason  string `json:"reason"`
+	Path    string `json:"path"`
+}
+
 func runPrune(cmd *cobra.Command, args []string) error {
 	projectFlag, _ := cmd.Flags().GetString("project")
 
+	if pruneJSONFlag && !dryRunFlag {
+		return fmt.Errorf("--json requires --dry-run")
+	}
+
 	var names []string
 	if projectFlag != "" {
 		names = []string{projectFlag}
@@ -47,114 +117,153 @@ func runPrune(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	pruned := 0
+	var (
+		pruned     int
+		counts     = make(map[forest.PruneLabel]int)
+		headerDone bool
+		failed     []string
+		plan       = []prunePlanEntry{}
+	)
 
 	for _, name := range names {
-		rc, err := config.Resolve(name)
+		// A broken project is reported and skipped so that it does not
+		// block cleaning up the others.
+		rc, trees, err := forest.LoadPruneProject(name)
 		if err != nil {
-			return err
-		}
+			fmt.Fprintf(os.Stderr, "Skipping project %s: %s\n", name, err)
+			failed = append(failed, name)
 
-		trees, err := git.List(rc.Repo)
-		if err != nil {
-			return err
+			continue
 		}
 
-		// Fetch remote branches once per project so we can detect
-		// branches deleted after a squash-merge PR.
-		remoteBranches, err := git.RemoteBranches(rc.Repo, "origin")
-		if err != nil {
-			slog.Debug("could not fetch remote branches", slog.String("project", name), slog.Any("err", err))
-		}
+		candidates := forest.PruneCandidates(rc, trees, forest.PruneOptions{
+			Remote:     remoteFlag,
+			Exclude:    excludeFlag,
+			CheckPRs:   checkPRsFlag,
+			MergedOnly: mergedOnlyFlag,
+			GoneOnly:   goneOnlyFlag,
+		})
 
-		// Resolve NWO once per project for gh PR lookups. A failure
-		// here is non-fatal; we fall back to interactive confirmation.
-		nwo := resolveNWO(rc.Repo)
+		if pruneJSONFlag {
+			for _, c := range candidates {
+				plan = append(plan, prunePlanEntry{
+					Project: name,
+					Branch:  c.Branch,
+					Reason:  pruneReason(c),
+					Path:    c.Path,
+				})
+			}
 
-		repoPath := filepath.Clean(rc.Repo)
+			continue
+		}
 
-		for _, t := range trees {
-			if t.Bare || t.Branch == "" || t.Branch == rc.Branch {
-				continue
-			}
+		// Results are buffered and printed under a project heading
+		// once the project is done, so that prompts do not break up
+		// the aligned output.
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		projectDone := false
 
-			// Skip the main working tree. It cannot be removed by
-			// git worktree remove and should never be pruned.
-			if filepath.Clean(t.Path) == repoPath {
-				continue
+		printResult := func(branch, status string) {
+			if dryRunFlag && !headerDone {
+				fmt.Println("Would prune:")
 			}
 
-			reason := git.PruneCheck(rc.Repo, t.Branch, rc.Branch, remoteBranches)
-			if reason == git.PruneNone {
-				continue
-			}
+			headerDone = true
 
-			// When the branch is gone from the remote but not merged
-			// locally, verify via gh that the PR was actually merged.
-			// Fall back to an interactive prompt when gh is
-			// unavailable or the PR was not merged.
-			if reason == git.PruneRemoteGone {
-				if !shouldPruneRemoteGone(nwo, name, t.Branch) {
-					continue
-				}
+			if !projectDone {
+				_, _ = fmt.Fprintln(w, projectStyle.Render(name))
+				projectDone = true
 			}
 
-			if dryRunFlag {
-				fmt.Printf("would prune %s/%s\n", name, t.Branch)
-				pruned++
+			_, _ = fmt.Fprintf(w, "  %s\t%s\n", branchStyle.Render(branch), status)
+		}
+
+		for _, c := range candidates {
+			if c.Confirm && !confirmRemoteGone(name, c.Branch) {
 				continue
 			}
 
-			if err := forest.RemoveTree(rc, t.Branch, true); err != nil {
-				fmt.Printf("failed to prune %s/%s: %s\n", name, t.Branch, err)
-				continue
+			if !dryRunFlag {
+				if err := forest.RemoveTree(rc, c.Branch, true); err != nil {
+					printResult(c.Branch, failedStyle.Render("failed: "+err.Error()))
+					continue
+				}
 			}
 
-			fmt.Printf("pruned %s/%s\n", name, t.Branch)
+			printResult(c.Branch, renderLabel(c.Label))
+			counts[c.Label]++
 			pruned++
 		}
+
+		if err := w.Flush(); err != nil {
+			return err
+		}
 	}
 
-	if pruned == 0 {
+	if pruneJSONFlag {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+
+		if err := enc.Encode(plan); err != nil {
+			return err
+		}
+	} else if pruned == 0 {
 		fmt.Println("Nothing to prune.")
+	} else {
+		verb := "Pruned"
+		if dryRunFlag {
+			verb = "Would prune"
+		}
+
+		fmt.Printf("\n%s %d (%s)\n", verb, pruned, pruneSummary(counts))
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("could not check %d project(s): %s", len(failed), strings.Join(failed, ", "))
 	}
 
 	return nil
 }
 
-// shouldPruneRemoteGone determines whether a branch whose remote
-// tracking branch has been deleted should be pruned. It first tries
-// the gh CLI to check for a merged PR. If gh confirms the PR was
-// merged, pruning proceeds. Otherwise, the user is prompted.
-func shouldPruneRemoteGone(nwo, project, branch string) bool {
-	if nwo != "" {
-		merged, err := github.IsPRMerged(nwo, branch)
-		if err != nil {
-			slog.Debug("gh PR check failed, falling back to prompt",
-				slog.String("branch", branch),
-				slog.Any("err", err),
-			)
-		}
+// pruneReason returns the machine-readable reason used in --json
+// output.
+func pruneReason(c forest.PruneCandidate) string {
+	switch {
+	case c.Confirm:
+		return "needs_confirmation"
+	case c.Label == forest.PruneLabelPRMerged:
+		return "pr_merged"
+	default:
+		return string(c.Label)
+	}
+}
+
+// pruneSummary formats per-reason counts, e.g. "3 merged, 2 gone",
+// omitting reasons with no branches.
+func pruneSummary(counts map[forest.PruneLabel]int) string {
+	var parts []string
 
-		if merged {
-			return true
+	for _, label := range []forest.PruneLabel{forest.PruneLabelMerged, forest.PruneLabelPRMerged, forest.PruneLabelGone} {
+		if n := counts[label]; n > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", n, label))
 		}
 	}
 
-	return confirm(fmt.Sprintf(
+	return strings.Join(parts, ", ")
+}
+
+// confirmRemoteGone asks whether to prune a branch that is gone from
+// the remote but could not be confirmed as merged. Without a TTY to
+// prompt on, the branch is skipped.
+func confirmRemoteGone(project, branch string) bool {
+	ok, err := prompt.Confirm(fmt.Sprintf(
 		"Branch %s/%s is gone from the remote but may not be merged. Remove? [y/N] ",
 		project, branch,
 	))
-}
-
-// resolveNWO returns the "owner/repo" string for the origin remote,
-// or an empty string if it cannot be determined. An empty result
-// causes the caller to skip gh lookups and fall back to prompting.
-func resolveNWO(repoPath string) string {
-	raw, err := git.RemoteURL(repoPath, "origin")
 	if err != nil {
-		return ""
+		fmt.Printf("skipped %s/%s: %s\n", project, branch, err)
+		return false
 	}
 
-	return git.NormalizeRemoteURL(raw)
+	return ok
 }
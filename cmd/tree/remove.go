@@ -1,7 +1,6 @@
 package tree
 
 import (
-	"bufio"
 	"errors"
 	"fmt"
 	"os"
@@ -14,18 +13,23 @@ import (
 	"github.com/mhamza15/forest/internal/config"
 	"github.com/mhamza15/forest/internal/forest"
 	"github.com/mhamza15/forest/internal/git"
+	"github.com/mhamza15/forest/internal/prompt"
 )
 
 var forceFlag bool
 
 func removeCmd() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   "remove [branch]",
-		Short: "Remove a worktree and its tmux session",
-		Long: `Remove a worktree and its tmux session. With no arguments, detects
-the current worktree from the working directory and prompts for
-confirmation.`,
-		Args:              cobra.MaximumNArgs(1),
+		Use:   "remove [branch...]",
+		Short: "Remove worktrees and their tmux sessions",
+		Long: `Remove one or more worktrees and their tmux sessions. With no
+arguments, detects the current worktree from the working directory and
+prompts for confirmation.
+
+When several branches are given, forest prompts once with the full list
+and then removes each in turn. A failure to remove one worktree does not
+stop the others; failures are reported at the end.`,
+		Args:              cobra.ArbitraryArgs,
 		RunE:              runRemove,
 		ValidArgsFunction: completion.Branches,
 	}
@@ -36,14 +40,26 @@ confirmation.`,
 }
 
 func runRemove(cmd *cobra.Command, args []string) error {
-	var project, branch string
+	var (
+		project  string
+		branches []string
+	)
 
 	projectFlag, _ := cmd.Flags().GetString("project")
 
-	switch len(args) {
-	case 1:
-		branch = args[0]
+	if len(args) == 0 {
+		var (
+			branch string
+			err    error
+		)
 
+		project, branch, err = detectCurrentWorktree()
+		if err != nil {
+			return err
+		}
+
+		branches = []string{branch}
+	} else {
 		var err error
 
 		project, err = resolveProject(projectFlag)
@@ -51,15 +67,18 @@ func runRemove(cmd *cobra.Command, args []string) error {
 			return err
 		}
 
-	case 0:
-		var err error
+		branches = args
+	}
 
-		project, branch, err = detectCurrentWorktree()
+	// Removing the current worktree or several at once warrants a
+	// confirmation; a single explicit branch does not.
+	if len(args) != 1 {
+		ok, err := prompt.Confirm(removePrompt(project, branches))
 		if err != nil {
 			return err
 		}
 
-		if !confirm(fmt.Sprintf("Remove worktree %s/%s? [y/N] ", project, branch)) {
+		if !ok {
 			return nil
 		}
 	}
@@ -69,15 +88,65 @@ func runRemove(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	err = forest.RemoveTree(rc, branch, forceFlag)
+	if len(branches) == 1 {
+		return removeTree(rc, branches[0])
+	}
+
+	var failed []string
+
+	for _, branch := range branches {
+		if err := removeTree(rc, branch); err != nil {
+			fmt.Printf("Failed to remove %s/%s: %s\n", project, branch, err)
+			failed = append(failed, branch)
+		}
+	}
+
+	if len(failed) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("failed to remove %d of %d worktrees: %s", len(failed), len(branches), strings.Join(failed, ", "))
+}
+
+// removePrompt builds the confirmation prompt listing every worktree
+// about to be removed.
+func removePrompt(project string, branches []string) string {
+	if len(branches) == 1 {
+		return fmt.Sprintf("Remove worktree %s/%s? [y/N] ", project, branches[0])
+	}
+
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "Remove %d worktrees?\n", len(branches))
+
+	for _, branch := range branches {
+		fmt.Fprintf(&b, "  %s/%s\n", project, branch)
+	}
+
+	b.WriteString("[y/N] ")
+
+	return b.String()
+}
+
+// removeTree removes a single worktree, asking before force removing
+// it when it has local changes and --force was not given. Declining
+// the force removal is not an error.
+func removeTree(rc config.ResolvedConfig, branch string) error {
+	err := forest.RemoveTree(rc, branch, forceFlag)
 	if err != nil {
 		if !errors.Is(err, git.ErrWorktreeDirty) {
 			return err
 		}
 
-		fmt.Printf("Worktree %s/%s has modified or untracked files.\n", project, branch)
+		fmt.Printf("Worktree %s/%s has modified or untracked files.\n", rc.Name, branch)
 
-		if !confirm("Force remove? [y/N] ") {
+		ok, err := prompt.Confirm("Force remove? [y/N] ")
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			fmt.Printf("Skipped %s/%s\n", rc.Name, branch)
 			return nil
 		}
 
@@ -86,22 +155,11 @@ func runRemove(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	fmt.Printf("Removed worktree %s/%s\n", project, branch)
+	fmt.Printf("Removed worktree %s/%s\n", rc.Name, branch)
 
 	return nil
 }
 
-// confirm prints a prompt and returns true if the user types y or yes.
-func confirm(prompt string) bool {
-	fmt.Print(prompt)
-
-	reader := bufio.NewReader(os.Stdin)
-	answer, _ := reader.ReadString('\n')
-	answer = strings.TrimSpace(strings.ToLower(answer))
-
-	return answer == "y" || answer == "yes"
-}
-
 // detectCurrentWorktree figures out which project and branch the
 // current working directory belongs to by matching the worktree root
 // against registered projects.
@@ -136,7 +194,7 @@ func detectCurrentWorktree() (project string, branch string, err error) {
 			continue
 		}
 
-		trees, err := git.List(proj.Repo)
+		trees, err := git.List(proj.RepoPath())
 		if err != nil {
 			continue
 		}
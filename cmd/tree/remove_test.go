@@ -0,0 +1,137 @@
+package tree
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mhamza15/forest/internal/config"
+	"github.com/mhamza15/forest/internal/forest"
+	"github.com/mhamza15/forest/internal/git"
+	"github.com/mhamza15/forest/internal/prompt"
+)
+
+func TestRemove_SeveralBranches(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		wantErr  string
+		wantOut  []string
+		wantGone []string
+	}{
+		{
+			name:     "all removed",
+			args:     []string{"one", "two"},
+			wantOut:  []string{"Removed worktree demo/one", "Removed worktree demo/two"},
+			wantGone: []string{"one", "two"},
+		},
+		{
+			name:    "failure does not stop the others",
+			args:    []string{"one", "missing", "two"},
+			wantErr: "failed to remove 1 of 3 worktrees: missing",
+			wantOut: []string{
+				"Removed worktree demo/one",
+				"Failed to remove demo/missing:",
+				"Removed worktree demo/two",
+			},
+			wantGone: []string{"one", "two"},
+		},
+		{
+			name:    "every failure is listed",
+			args:    []string{"missing", "one", "gone"},
+			wantErr: "failed to remove 2 of 3 worktrees: missing, gone",
+			wantOut: []string{
+				"Failed to remove demo/missing:",
+				"Removed worktree demo/one",
+				"Failed to remove demo/gone:",
+			},
+			wantGone: []string{"one"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := setupRemoveProject(t, "one", "two")
+
+			var err error
+
+			out := captureStdout(t, func() {
+				err = runRemoveCmd(tt.args...)
+			})
+
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, tt.wantErr)
+			}
+
+			for _, line := range tt.wantOut {
+				assert.Contains(t, out, line)
+			}
+
+			for _, branch := range tt.wantGone {
+				assert.Nil(t, git.FindByBranch(repo, branch), "worktree %s not removed", branch)
+			}
+
+			// Branches are kept; only their worktrees are removed.
+			assert.True(t, git.BranchExists(repo, "one"))
+		})
+	}
+}
+
+func TestRemove_SingleBranchFailure(t *testing.T) {
+	repo := setupRemoveProject(t, "one")
+
+	var err error
+
+	out := captureStdout(t, func() {
+		err = runRemoveCmd("missing")
+	})
+
+	// A single branch reports its own error rather than a summary.
+	require.Error(t, err)
+	assert.NotContains(t, err.Error(), "failed to remove")
+	assert.NotContains(t, out, "Failed to remove")
+	assert.NotNil(t, git.FindByBranch(repo, "one"))
+}
+
+// setupRemoveProject registers a "demo" project with a worktree for
+// each of branches, and answers prompts with yes.
+func setupRemoveProject(t *testing.T, branches ...string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("XDG_DATA_HOME", dir)
+	t.Setenv("TMUX", "")
+	t.Setenv("TMUX_TMPDIR", dir)
+
+	prompt.SetAssumeYes(true)
+	t.Cleanup(func() { prompt.SetAssumeYes(false) })
+
+	repo := initTestRepo(t)
+	require.NoError(t, config.SaveProject("demo", config.ProjectConfig{Repo: repo}))
+
+	rc, err := config.Resolve("demo")
+	require.NoError(t, err)
+
+	for _, branch := range branches {
+		result, err := forest.AddTree(rc, branch)
+		require.NoError(t, err)
+		require.DirExists(t, result.WorktreePath)
+	}
+
+	return repo
+}
+
+// runRemoveCmd runs tree remove for the "demo" project with args.
+func runRemoveCmd(args ...string) error {
+	cmd := removeCmd()
+	cmd.Flags().String("project", "demo", "")
+	cmd.SetArgs(args)
+	cmd.SilenceUsage = true
+	cmd.SilenceErrors = true
+
+	return cmd.Execute()
+}
@@ -14,6 +14,7 @@ func Command() *cobra.Command {
 		RunE:  runBrowser,
 	}
 
+	cmd.AddCommand(infoCmd())
 	cmd.AddCommand(listCmd())
 	cmd.AddCommand(pruneCmd())
 	cmd.AddCommand(removeCmd())
@@ -1,21 +1,42 @@
 package tree
 
 import (
+	"errors"
 	"fmt"
 	"log/slog"
+	"os"
+	"slices"
 
 	"github.com/spf13/cobra"
 
 	"github.com/mhamza15/forest/internal/completion"
+	"github.com/mhamza15/forest/internal/config"
+	"github.com/mhamza15/forest/internal/editor"
 	"github.com/mhamza15/forest/internal/forest"
+	"github.com/mhamza15/forest/internal/git"
 	"github.com/mhamza15/forest/internal/tmux"
 )
 
-var baseBranchFlag string
+var (
+	baseBranchFlag string
+	noCheckoutFlag bool
+	sessionFlag    bool
+	noSessionFlag  bool
+	copyFromFlag   string
+	backgroundFlag bool
+	saveBaseFlag   bool
+	clipboardFlag  bool
+	pinFlag        bool
+	detachFlag     bool
+	tagFlag        string
+	updateBaseFlag bool
+	openFlag       bool
+	printPathFlag  string
+)
 
 func switchCmd() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   "switch {<branch> | <github-link>}",
+		Use:   "switch {<branch> | <github-link> | -}",
 		Short: "Switch to a worktree, creating it if needed",
 		Long: "Switch to the tmux session for a worktree.\n" +
 			"\n" +
@@ -31,33 +52,174 @@ func switchCmd() *cobra.Command {
 			"fetched and the local branch is created with upstream tracking\n" +
 			"configured. Otherwise, a new branch is created based on the project's\n" +
 			"configured base branch, falling back to the global default. Use\n" +
-			"--branch to override the base branch for new worktrees.\n" +
+			"--branch to override the base branch for new worktrees. When --branch\n" +
+			"is set, the new branch is always created off that base, even if a\n" +
+			"remote branch of the same name exists. Add --save-base to also store\n" +
+			"that base in the project config for future worktrees.\n" +
 			"\n" +
-			"A GitHub issue or pull request URL may be passed instead of a branch:\n" +
+			"A GitHub issue, pull request or branch URL may be passed instead of\n" +
+			"a branch:\n" +
 			"\n" +
 			"  forest tree switch https://github.com/owner/repo/issues/42\n" +
 			"  forest tree switch https://github.com/owner/repo/pull/99\n" +
+			"  forest tree switch https://github.com/owner/repo/tree/feature/login\n" +
+			"  forest tree switch owner/repo#42\n" +
+			"\n" +
+			"The owner/repo#number shorthand is looked up with gh to tell issues\n" +
+			"from pull requests, and is treated as an issue if that fails. A\n" +
+			"branch of the same name, such as team/bug#12, takes precedence; pass\n" +
+			"the full URL to reach the issue or pull request instead. The\n" +
+			"project is the one whose remotes match the link's repository; if\n" +
+			"several match, you are asked to pick one. Pass --project to choose\n" +
+			"it directly.\n" +
+			"\n" +
+			"A branch URL switches to that branch, fetching it from the remote\n" +
+			"when it does not exist locally.\n" +
 			"\n" +
 			"For issues, a branch named \"issue-<number>\" is used (e.g. \"issue-42\").\n" +
-			"For pull requests, the PR's head branch is used. If the PR comes from\n" +
-			"a fork, the branch is fetched from the fork's remote.",
-		Args:              cobra.ExactArgs(1),
+			"For pull requests, the PR's head branch is used. Both names can be\n" +
+			"changed with issue_branch_template and pr_branch_template. If the PR\n" +
+			"comes from a fork, the branch is fetched from the fork's remote; set\n" +
+			"pr_fetch_strategy to gh-checkout to let gh pr checkout fetch it. When\n" +
+			"the PR is already closed or merged, you are asked to confirm before\n" +
+			"its branch is created; --yes skips the question. The PR's base branch\n" +
+			"is recorded as the worktree's base, so tree prune checks whether it\n" +
+			"was merged into that branch. Use --pin to check out the PR's current\n" +
+			"head commit in a separate detached worktree named pr-<number>-<sha>,\n" +
+			"so it stays on the reviewed commit even if the branch moves. The\n" +
+			"branch itself and any worktree of it are left alone.\n" +
+			"\n" +
+			"Pass - to read the branch or link from stdin, or use --clipboard to\n" +
+			"read it from the system clipboard (via pbpaste, wl-paste, xclip or\n" +
+			"xsel).\n" +
+			"\n" +
+			"A new branch starts from the project's base branch. If the local base\n" +
+			"is behind its upstream, a warning is shown; use --update-base to fetch\n" +
+			"the upstream and fast-forward the base before branching off it.\n" +
+			"\n" +
+			"Use --copy-from to read the project's copy and symlink files from\n" +
+			"another branch's worktree instead of the repo root, for example to\n" +
+			"carry over local env files or build caches from an in-progress tree.\n" +
+			"\n" +
+			"When auto_session is false in the global or project config, only the\n" +
+			"worktree is created, as if --no-session was passed. Use --session to\n" +
+			"open the tmux session anyway.\n" +
+			"\n" +
+			"Use --background to create the session with its layout but stay in\n" +
+			"the current session, switching to it later when ready.\n" +
+			"\n" +
+			"Use --open to also start $VISUAL or $EDITOR in the worktree, in a new\n" +
+			"\"editor\" window of the session, or in its existing \"editor\" window.\n" +
+			"With --background the window is added without switching to the\n" +
+			"session. Without a session, as with --no-session, the editor is run\n" +
+			"directly in the current terminal.\n" +
+			"\n" +
+			"Use --detach to create a worktree with a detached HEAD at any commit,\n" +
+			"tag or other ref instead of a branch, for read-only inspection:\n" +
+			"\n" +
+			"  forest tree switch --detach v1.2.0\n" +
+			"\n" +
+			"Detached worktrees are named after the ref and are never pruned.\n" +
+			"\n" +
+			"Use --tag to review a release alongside ongoing work. It creates a\n" +
+			"detached worktree at the tag named after it, e.g. \"tag-v1.2.0\":\n" +
+			"\n" +
+			"  forest tree switch --tag v1.2.0\n" +
+			"\n" +
+			"Use --print-path FILE to write the worktree path to FILE unless the\n" +
+			"command switched tmux clients, so that a shell function can cd into\n" +
+			"it. forest shell-init prints such a function.",
+		Args:              cobra.MaximumNArgs(1),
 		RunE:              runSwitch,
 		ValidArgsFunction: completion.Branches,
 	}
 
 	cmd.Flags().StringVarP(&baseBranchFlag, "branch", "b", "", "base branch for a new worktree (overrides project config)")
+	cmd.Flags().BoolVar(&saveBaseFlag, "save-base", false, "save the --branch base to the project config for future worktrees")
+	cmd.Flags().BoolVar(&noCheckoutFlag, "no-checkout", false, "create a new worktree without checking out its files")
+	cmd.Flags().BoolVar(&sessionFlag, "session", false, "open the tmux session even when auto_session is disabled")
+	cmd.Flags().BoolVar(&noSessionFlag, "no-session", false, "only create the worktree, without opening a tmux session")
+	cmd.Flags().StringVar(&copyFromFlag, "copy-from", "", "copy and symlink files from this branch's worktree (overrides copy_from)")
+	cmd.Flags().BoolVar(&clipboardFlag, "clipboard", false, "read the branch or GitHub link from the clipboard")
+	cmd.Flags().BoolVar(&backgroundFlag, "background", false, "set up the tmux session without switching to it")
+	cmd.Flags().BoolVar(&pinFlag, "pin", false, "check out a pull request's head commit detached")
+	cmd.Flags().BoolVar(&detachFlag, "detach", false, "create a worktree with a detached HEAD at a ref instead of a branch")
+	cmd.Flags().StringVar(&tagFlag, "tag", "", "create a detached worktree at a tag, named tag-<tag>")
+	cmd.Flags().BoolVar(&updateBaseFlag, "update-base", false, "fetch and fast-forward the base branch before creating a new branch")
+	cmd.Flags().BoolVar(&openFlag, "open", false, "open $VISUAL or $EDITOR in the worktree")
+	cmd.Flags().StringVar(&printPathFlag, "print-path", "", "write the worktree path to `file` for shell integration")
+	cmd.MarkFlagsMutuallyExclusive("session", "no-session")
+	cmd.MarkFlagsMutuallyExclusive("detach", "pin")
+	cmd.MarkFlagsMutuallyExclusive("detach", "branch")
+	cmd.MarkFlagsMutuallyExclusive("detach", "no-checkout")
+	cmd.MarkFlagsMutuallyExclusive("tag", "detach", "pin", "branch", "no-checkout", "clipboard")
+	cmd.MarkFlagsMutuallyExclusive("update-base", "detach", "tag")
+	cmd.MarkFlagsMutuallyExclusive("background", "no-session")
+
+	if err := cmd.RegisterFlagCompletionFunc("copy-from", completion.Branches); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: registering --copy-from completion: %s\n", err)
+	}
 
 	return cmd
 }
 
 func runSwitch(cmd *cobra.Command, args []string) error {
-	project, branch, rc, err := resolveTreeTarget(cmd, args[0])
+	if saveBaseFlag && baseBranchFlag == "" {
+		return fmt.Errorf("--save-base requires --branch")
+	}
+
+	arg, err := switchTarget(args)
 	if err != nil {
 		return err
 	}
 
-	result, err := forest.AddTree(rc, branch)
+	projectFlag, _ := cmd.Flags().GetString("project")
+
+	if detachFlag && isLink(arg, projectFlag) {
+		return fmt.Errorf("--detach takes a ref, not a GitHub link; use --pin for pull requests")
+	}
+
+	target, err := resolveTreeTarget(cmd, arg)
+	if errors.Is(err, errDeclined) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if pinFlag && target.headSHA == "" {
+		return fmt.Errorf("--pin requires a pull request link")
+	}
+
+	project, branch, rc := target.project, target.branch, target.rc
+
+	if pinFlag {
+		branch = forest.PinTreeName(target.prNumber, target.headSHA)
+	}
+
+	opts := forest.AddTreeOptions{
+		NoCheckout: noCheckoutFlag,
+		ForceBase:  baseBranchFlag != "",
+		CopyFrom:   copyFromFlag,
+		UpdateBase: updateBaseFlag,
+		Remote:     target.remote,
+		Checkout:   target.checkout,
+	}
+
+	var result forest.AddTreeResult
+
+	switch {
+	case tagFlag != "":
+		result, err = forest.AddTagTree(rc, tagFlag, opts)
+	case detachFlag:
+		result, err = forest.AddDetachedTree(rc, branch, opts)
+	case pinFlag:
+		result, err = pinTree(rc, target, opts)
+	default:
+		result, err = forest.AddTreeWithOptions(rc, branch, opts)
+	}
+
 	if err != nil {
 		return err
 	}
@@ -66,31 +228,212 @@ func runSwitch(cmd *cobra.Command, args []string) error {
 		fmt.Println(w)
 	}
 
+	if result.BaseWarning != "" {
+		fmt.Println("Warning: " + result.BaseWarning)
+	}
+
 	if result.Created {
 		if result.Fetched {
 			fmt.Printf("Fetched branch %q from %s\n", branch, result.Remote)
 		}
 
-		fmt.Printf("Created worktree %s/%s\n", project, branch)
+		switch {
+		case tagFlag != "":
+			fmt.Printf("Created worktree %s/%s at tag %s\n", project, branch, tagFlag)
+		case detachFlag:
+			fmt.Printf("Created detached worktree %s/%s\n", project, branch)
+		case pinFlag:
+			fmt.Printf("Created worktree %s/%s pinned to %s\n", project, branch, target.headSHA)
+		default:
+			fmt.Printf("Created worktree %s/%s\n", project, branch)
+		}
 	}
 
-	for _, w := range result.CopyWarnings {
+	if saveBaseFlag {
+		if err := saveProjectBase(project, baseBranchFlag); err != nil {
+			return err
+		}
+
+		fmt.Printf("Saved base branch %q for project %s\n", baseBranchFlag, project)
+	}
+
+	printCopyWarnings(rc, result)
+
+	for _, w := range result.RemoveWarnings {
 		fmt.Println(w)
 	}
 
-	for _, w := range result.SymlinkWarnings {
+	for _, w := range result.ConfigWarnings {
 		fmt.Println(w)
 	}
 
-	for _, w := range result.RemoveWarnings {
+	if !wantSession(rc) {
+		fmt.Printf("Worktree %s/%s is at %s\n", project, branch, result.WorktreePath)
+
+		if openFlag {
+			if err := editor.Open(result.WorktreePath); err != nil {
+				return err
+			}
+		}
+
+		return printPath(result.WorktreePath)
+	}
+
+	created, err := forest.OpenSession(rc, branch, result.WorktreePath)
+	if err != nil {
+		return err
+	}
+
+	if openFlag {
+		if err := openEditorWindow(rc, result, created); err != nil {
+			return err
+		}
+	}
+
+	if backgroundFlag {
+		if created {
+			fmt.Printf("Session %s is ready\n", result.SessionName)
+		} else {
+			fmt.Printf("Session %s already exists\n", result.SessionName)
+		}
+
+		return printPath(result.WorktreePath)
+	}
+
+	// A reused worktree whose session was killed gets a fresh session
+	// with the configured layout. When both already existed there is
+	// nothing to report; just jump to the session.
+	if !result.Created && created {
+		fmt.Printf("Created session %s for existing worktree %s/%s\n", result.SessionName, project, branch)
+	}
+
+	slog.Debug("switching to tmux session",
+		slog.String("session", result.SessionName),
+		slog.Bool("new_worktree", result.Created),
+		slog.Bool("new_session", created),
+	)
+
+	// Outside tmux, attaching returns once the user detaches, back in
+	// the shell that ran forest. Inside tmux, that shell is left
+	// behind in the previous session, so it stays where it is.
+	inTmux := tmux.IsRunning()
+
+	if err := tmux.SwitchTo(result.SessionName); err != nil {
+		return err
+	}
+
+	if inTmux {
+		return nil
+	}
+
+	return printPath(result.WorktreePath)
+}
+
+// openEditorWindow starts the editor in an "editor" window of the
+// worktree's session. A session that already existed may have one from
+// an earlier --open, in which case that window is selected instead of
+// adding another.
+func openEditorWindow(rc config.ResolvedConfig, result forest.AddTreeResult, created bool) error {
+	if !created {
+		err := tmux.SelectWindow(result.SessionName, "editor")
+		if err == nil {
+			return nil
+		}
+
+		slog.Debug("no editor window to reuse", slog.String("session", result.SessionName), slog.Any("err", err))
+	}
+
+	editorWindow := tmux.LayoutWindow{Name: "editor", Command: editor.ShellCommand(result.WorktreePath)}
+
+	return tmux.NewWindow(result.SessionName, result.WorktreePath, editorWindow, rc.SessionReadyTimeout)
+}
+
+// printPath writes path to the --print-path file when one is given.
+// A file rather than stdout keeps the command's output on the terminal
+// for shell functions that read the path back.
+func printPath(path string) error {
+	if printPathFlag == "" {
+		return nil
+	}
+
+	if err := os.WriteFile(printPathFlag, []byte(path+"\n"), 0o600); err != nil {
+		return fmt.Errorf("--print-path: %w", err)
+	}
+
+	return nil
+}
+
+// pinTree creates a detached worktree at a pull request's head commit,
+// fetching the commit from the PR's head repository first when it is
+// not already present. The branch's own worktree is never touched.
+func pinTree(rc config.ResolvedConfig, target treeTarget, opts forest.AddTreeOptions) (forest.AddTreeResult, error) {
+	if !git.CommitExists(rc.Repo, target.headSHA) {
+		fmt.Printf("Fetching commit %s from %s\n", target.headSHA, target.headURL)
+
+		if err := git.FetchCommit(rc.Repo, target.headURL, target.headSHA, os.Stderr); err != nil {
+			return forest.AddTreeResult{}, fmt.Errorf("fetching pinned commit: %w", err)
+		}
+	}
+
+	return forest.AddPinTree(rc, target.prNumber, target.headSHA, opts)
+}
+
+// switchTarget returns the branch or link to switch to. With --tag,
+// it is the tag worktree's name and no argument may be given.
+func switchTarget(args []string) (string, error) {
+	if tagFlag == "" {
+		return switchArg(args, clipboardFlag)
+	}
+
+	if len(args) > 0 {
+		return "", fmt.Errorf("--tag cannot be combined with a branch argument")
+	}
+
+	return forest.TagTreeName(tagFlag), nil
+}
+
+// printCopyWarnings prints the copy and symlink warnings of a new
+// worktree. Warnings about missing files are left out when
+// warn_missing_copies is disabled for the project, and only logged for
+// --verbose.
+func printCopyWarnings(rc config.ResolvedConfig, result forest.AddTreeResult) {
+	for _, w := range result.MissingWarnings {
+		if rc.WarnMissingCopies {
+			fmt.Println(w)
+			continue
+		}
+
+		slog.Debug("suppressed missing copy warning", slog.String("warning", w))
+	}
+
+	for _, w := range append(slices.Clone(result.CopyWarnings), result.SymlinkWarnings...) {
 		fmt.Println(w)
 	}
+}
+
+// wantSession reports whether a tmux session should be opened, taking
+// the --session, --background and --no-session flags over the
+// configured default.
+func wantSession(rc config.ResolvedConfig) bool {
+	switch {
+	case sessionFlag, backgroundFlag:
+		return true
+	case noSessionFlag:
+		return false
+	default:
+		return rc.AutoSession
+	}
+}
 
-	if err := forest.OpenSession(rc, branch, result.WorktreePath); err != nil {
+// saveProjectBase stores base as the project's base branch, preserving
+// all other project settings.
+func saveProjectBase(project, base string) error {
+	cfg, err := config.LoadProject(project)
+	if err != nil {
 		return err
 	}
 
-	slog.Debug("switching to tmux session", slog.String("session", result.SessionName))
+	cfg.Branch = base
 
-	return tmux.SwitchTo(result.SessionName)
+	return config.SaveProject(project, cfg)
 }
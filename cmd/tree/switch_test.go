@@ -0,0 +1,282 @@
+package tree
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mhamza15/forest/internal/config"
+	"github.com/mhamza15/forest/internal/forest"
+	"github.com/mhamza15/forest/internal/git"
+	"github.com/mhamza15/forest/internal/github"
+)
+
+func TestSaveProjectBase_KeepsUnexpandedRepo(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("XDG_DATA_HOME", filepath.Join(dir, "data"))
+	t.Setenv("FOREST_TEST_DEV", "/srv/dev")
+
+	require.NoError(t, config.SaveProject("tilde", config.ProjectConfig{Repo: "~/dev/myapp"}))
+	require.NoError(t, config.SaveProject("env", config.ProjectConfig{Repo: "$FOREST_TEST_DEV/myapp"}))
+
+	for _, name := range []string{"tilde", "env"} {
+		require.NoError(t, saveProjectBase(name, "develop"))
+	}
+
+	data, err := os.ReadFile(config.ProjectConfigPath("tilde"))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "repo: ~/dev/myapp")
+
+	proj, err := config.LoadProject("env")
+	require.NoError(t, err)
+	assert.Equal(t, "$FOREST_TEST_DEV/myapp", proj.Repo)
+	assert.Equal(t, "develop", proj.Branch)
+}
+
+func TestPinTree_KeepsBranchWorktree(t *testing.T) {
+	repo := initTestRepo(t)
+
+	rc := config.ResolvedConfig{
+		Name:        "demo",
+		Repo:        repo,
+		WorktreeDir: t.TempDir(),
+		Branch:      "main",
+	}
+
+	branchTree, err := forest.AddTree(rc, "feature")
+	require.NoError(t, err)
+
+	// The PR head is a commit the local repository does not have yet.
+	head := filepath.Join(t.TempDir(), "head")
+	runGit(t, repo, "clone", "--quiet", repo, head)
+	runGit(t, head, "config", "user.email", "test@test.com")
+	runGit(t, head, "config", "user.name", "test")
+	runGit(t, head, "checkout", "--quiet", "feature")
+	runGit(t, head, "commit", "--allow-empty", "-m", "review me")
+	sha := strings.TrimSpace(runGit(t, head, "rev-parse", "HEAD"))
+
+	target := treeTarget{headSHA: sha, headURL: head, prNumber: 7}
+
+	result, err := pinTree(rc, target, forest.AddTreeOptions{})
+	require.NoError(t, err)
+	assert.True(t, result.Created)
+	assert.Equal(t, filepath.Join(rc.WorktreeDir, "demo", "pr-7-"+sha[:7]), result.WorktreePath)
+	assert.Equal(t, sha, strings.TrimSpace(runGit(t, result.WorktreePath, "rev-parse", "HEAD")))
+
+	// The branch's worktree is still on its branch and can be
+	// switched to again.
+	assert.Equal(t, "feature", git.CurrentBranch(branchTree.WorktreePath))
+
+	reused, err := forest.AddTree(rc, "feature")
+	require.NoError(t, err)
+	assert.False(t, reused.Created)
+	assert.Equal(t, branchTree.WorktreePath, reused.WorktreePath)
+
+	// Pinning the same commit again reuses the pinned worktree.
+	again, err := pinTree(rc, target, forest.AddTreeOptions{})
+	require.NoError(t, err)
+	assert.False(t, again.Created)
+	assert.Equal(t, result.WorktreePath, again.WorktreePath)
+}
+
+func TestFetchPRBranch_RenamedBranchTracksHead(t *testing.T) {
+	origin := initTestRepo(t)
+	repo := filepath.Join(t.TempDir(), "local")
+	runGit(t, origin, "clone", "--quiet", origin, repo)
+
+	// The PR branch is pushed after the clone, so it must be fetched.
+	runGit(t, origin, "checkout", "--quiet", "-b", "fix-login")
+	runGit(t, origin, "commit", "--allow-empty", "-m", "fix")
+	runGit(t, origin, "checkout", "--quiet", "main")
+
+	// pr_branch_template renamed the local branch away from the PR's
+	// head branch.
+	head := github.PRHead{Branch: "fix-login", CloneURL: origin}
+	require.NoError(t, fetchPRBranch(repo, "pr-12", head))
+
+	assert.Equal(t,
+		strings.TrimSpace(runGit(t, origin, "rev-parse", "fix-login")),
+		strings.TrimSpace(runGit(t, repo, "rev-parse", "pr-12")),
+	)
+	assert.Equal(t, "origin", strings.TrimSpace(runGit(t, repo, "config", "branch.pr-12.remote")))
+	assert.Equal(t, "refs/heads/fix-login", strings.TrimSpace(runGit(t, repo, "config", "branch.pr-12.merge")))
+}
+
+func TestPrintCopyWarnings_SuppressesOnlyMissingFiles(t *testing.T) {
+	result := forest.AddTreeResult{
+		MissingWarnings: []string{"copy: .env not found, skipping"},
+		CopyWarnings:    []string{"copy: big.db: permission denied"},
+		SymlinkWarnings: []string{"symlink: cache: file exists"},
+	}
+
+	out := captureStdout(t, func() {
+		printCopyWarnings(config.ResolvedConfig{WarnMissingCopies: false}, result)
+	})
+	assert.Equal(t, "copy: big.db: permission denied\nsymlink: cache: file exists\n", out)
+
+	out = captureStdout(t, func() {
+		printCopyWarnings(config.ResolvedConfig{WarnMissingCopies: true}, result)
+	})
+	assert.Equal(t, "copy: .env not found, skipping\ncopy: big.db: permission denied\nsymlink: cache: file exists\n", out)
+}
+
+func TestIsLink_PrefersExistingBranch(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("XDG_DATA_HOME", filepath.Join(dir, "data"))
+
+	repo := initTestRepo(t)
+	require.NoError(t, config.SaveProject("demo", config.ProjectConfig{Repo: repo}))
+
+	assert.True(t, isLink("https://github.com/team/bug/pull/12", "demo"))
+	assert.False(t, isLink("feature", "demo"))
+	assert.True(t, isLink("team/bug#12", "demo"))
+
+	runGit(t, repo, "branch", "team/bug#12")
+	assert.False(t, isLink("team/bug#12", "demo"))
+
+	// A branch only fetched from a remote counts too.
+	runGit(t, repo, "remote", "add", "origin", "https://github.com/team/app.git")
+	runGit(t, repo, "update-ref", "refs/remotes/origin/team/bug#13", "HEAD")
+	assert.False(t, isLink("team/bug#13", "demo"))
+	assert.True(t, isLink("team/bug#14", "demo"))
+}
+
+func TestBranchLink_UsesLinkRemote(t *testing.T) {
+	// The project is a fork clone: origin is me/app and upstream is
+	// acme/app, the repository the links point to.
+	root := t.TempDir()
+	fork := initTestRepo(t)
+	upstream := initTestRepo(t)
+	serveGitHub(t, root, map[string]string{"me/app": fork, "acme/app": upstream})
+
+	runGit(t, fork, "branch", "fork-only")
+	runGit(t, fork, "branch", "both")
+	runGit(t, upstream, "branch", "release/1.x")
+	runGit(t, upstream, "branch", "both")
+	runGit(t, upstream, "commit", "--allow-empty", "-m", "upstream")
+	runGit(t, upstream, "branch", "--force", "both")
+
+	tests := []struct {
+		name       string
+		path       string
+		wantBranch string
+		wantRemote string
+	}{
+		{name: "on link remote", path: "release/1.x/docs", wantBranch: "release/1.x", wantRemote: "upstream"},
+		{name: "on both remotes", path: "both", wantBranch: "both", wantRemote: "upstream"},
+		{name: "only on other remote", path: "fork-only", wantBranch: "fork-only", wantRemote: ""},
+		{name: "nowhere", path: "new/idea", wantBranch: "new/idea", wantRemote: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := filepath.Join(t.TempDir(), "repo")
+			runGit(t, root, "clone", "--quiet", "git@github.com:me/app.git", repo)
+			runGit(t, repo, "remote", "add", "upstream", "git@github.com:acme/app.git")
+			runGit(t, repo, "fetch", "--quiet", "origin")
+
+			link := github.Link{Kind: github.KindBranch, Owner: "acme", Repo: "app", Branch: tt.path}
+			branch := linkBranch(link, repo)
+			assert.Equal(t, tt.wantBranch, branch)
+
+			rc := config.ResolvedConfig{Name: "app", Repo: repo, WorktreeDir: t.TempDir(), Branch: "main"}
+
+			result, err := forest.AddTreeWithOptions(rc, branch, forest.AddTreeOptions{Remote: linkRemote(link, repo)})
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantRemote, result.Remote)
+			assert.Equal(t, tt.wantRemote != "", result.Fetched)
+
+			if tt.wantRemote == "" {
+				assert.Equal(t,
+					strings.TrimSpace(runGit(t, repo, "rev-parse", "main")),
+					strings.TrimSpace(runGit(t, repo, "rev-parse", branch)),
+					"created off the base",
+				)
+
+				return
+			}
+
+			assert.Equal(t, tt.wantRemote, strings.TrimSpace(runGit(t, repo, "config", "branch."+branch+".remote")))
+			assert.Equal(t,
+				strings.TrimSpace(runGit(t, upstream, "rev-parse", branch)),
+				strings.TrimSpace(runGit(t, repo, "rev-parse", branch)),
+			)
+		})
+	}
+}
+
+func initTestRepo(t *testing.T) string {
+	t.Helper()
+
+	repo := filepath.Join(t.TempDir(), "repo")
+	require.NoError(t, os.MkdirAll(repo, 0o755))
+
+	runGit(t, repo, "init", "--initial-branch=main")
+	runGit(t, repo, "config", "user.email", "test@test.com")
+	runGit(t, repo, "config", "user.name", "test")
+	runGit(t, repo, "commit", "--allow-empty", "-m", "init")
+
+	return repo
+}
+
+func runGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+
+	output, err := cmd.CombinedOutput()
+	require.NoError(t, err, "git %s: %s", strings.Join(args, " "), output)
+
+	return string(output)
+}
+
+// captureStdout returns what fn prints to stdout.
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+
+	stdout := os.Stdout
+	os.Stdout = w
+
+	defer func() { os.Stdout = stdout }()
+
+	fn()
+
+	require.NoError(t, w.Close())
+
+	out, err := io.ReadAll(r)
+	require.NoError(t, err)
+
+	return string(out)
+}
+
+// serveGitHub points git's ssh at a fake that serves the given local
+// repositories for their "owner/repo" on any host, so that GitHub ssh
+// remotes work offline.
+func serveGitHub(t *testing.T, root string, repos map[string]string) {
+	t.Helper()
+
+	for nwo, repo := range repos {
+		dest := filepath.Join(root, nwo+".git")
+		require.NoError(t, os.MkdirAll(filepath.Dir(dest), 0o755))
+		require.NoError(t, os.Symlink(repo, dest))
+	}
+
+	// The last argument is the remote command, e.g.
+	// git-upload-pack 'acme/app.git'.
+	script := "#!/bin/sh\nfor last; do :; done\nrepo=${last#*\\'}\nexec git upload-pack \"" + root + "/${repo%\\'}\"\n"
+
+	ssh := filepath.Join(t.TempDir(), "ssh")
+	require.NoError(t, os.WriteFile(ssh, []byte(script), 0o755))
+	t.Setenv("GIT_SSH_COMMAND", ssh)
+}
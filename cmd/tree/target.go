@@ -1,147 +1,442 @@
 package tree
 
 import (
+	"errors"
 	"fmt"
 	"log/slog"
+	"os"
 
 	"github.com/spf13/cobra"
 
 	"github.com/mhamza15/forest/internal/config"
+	"github.com/mhamza15/forest/internal/forest"
 	"github.com/mhamza15/forest/internal/git"
 	"github.com/mhamza15/forest/internal/github"
+	"github.com/mhamza15/forest/internal/prompt"
 )
 
-func resolveTreeTarget(cmd *cobra.Command, arg string) (string, string, config.ResolvedConfig, error) {
+// errDeclined is returned when the user answers no to a confirmation
+// while resolving a switch target. It ends the command without an error.
+var errDeclined = errors.New("declined")
+
+// treeTarget is a resolved switch target: the project, the branch to
+// use, and the project's resolved config.
+type treeTarget struct {
+	project string
+	branch  string
+	rc      config.ResolvedConfig
+
+	// headSHA is the PR head commit when the target was a pull
+	// request link, and empty otherwise. headURL is the clone URL of
+	// the PR's head repository and prNumber its number, for fetching
+	// and naming a pinned worktree.
+	headSHA  string
+	headURL  string
+	prNumber int
+
+	// checkout creates the branch in a new worktree when the PR branch
+	// is left to gh pr checkout, and is nil otherwise.
+	checkout func(wtPath string) error
+
+	// remote is the remote for a branch link's repository, the only
+	// one a missing branch is fetched from. It is empty for other
+	// targets.
+	remote string
+}
+
+func resolveTreeTarget(cmd *cobra.Command, arg string) (treeTarget, error) {
 	projectFlag, _ := cmd.Flags().GetString("project")
 
-	var (
-		project string
-		branch  string
-		rc      config.ResolvedConfig
-	)
+	var target treeTarget
 
-	if github.IsGitHubURL(arg) {
-		link, err := github.ParseLink(arg)
+	if isLink(arg, projectFlag) {
+		link, err := parseLink(arg)
 		if err != nil {
-			return "", "", rc, err
+			return target, err
 		}
 
-		name, resolved, err := config.FindProjectByRemote(link.NWO())
+		name, resolved, err := linkProject(link, projectFlag)
 		if err != nil {
-			return "", "", rc, err
+			return target, err
 		}
 
-		project = name
-		rc = resolved
+		target.project = name
+		target.rc = resolved
 
-		branch, err = resolveLinkBranch(link, rc.Repo)
+		branch, head, err := resolveLinkBranch(link, resolved)
 		if err != nil {
-			return "", "", rc, err
+			return target, err
+		}
+
+		target.branch = branch
+		target.headSHA = head.HeadSHA
+
+		if link.Kind == github.KindBranch {
+			target.remote = linkRemote(link, resolved.Repo)
+		}
+
+		target.headURL = head.CloneURL
+		target.prNumber = link.Number
+
+		if link.Kind == github.KindPR && !pinFlag && resolved.PRFetchStrategy == config.PRFetchGHCheckout && !git.BranchExists(resolved.Repo, branch) {
+			target.checkout = func(wtPath string) error {
+				fmt.Printf("Checking out pull request #%d with gh\n", link.Number)
+				return github.CheckoutPR(link.NWO(), link.Number, wtPath, branch)
+			}
+		}
+
+		// Record the PR's base branch as the worktree's base, so that
+		// prune checks whether it was merged into the right branch.
+		// The PR branch was fetched rather than created off the base,
+		// so AddTree does not record it.
+		if base := prBaseRef(resolved.Repo, link, head.BaseBranch); base != "" {
+			target.rc.Branch = base
+
+			if link.Kind == github.KindPR && git.BranchExists(resolved.Repo, branch) {
+				forest.RecordBranchBase(resolved.Repo, branch, base)
+			}
 		}
 	} else {
-		branch = arg
+		target.branch = arg
 
 		name, err := resolveProject(projectFlag)
 		if err != nil {
-			return "", "", rc, err
+			return target, err
 		}
 
-		project = name
+		target.project = name
 
-		resolved, err := config.Resolve(project)
+		resolved, err := config.Resolve(name)
 		if err != nil {
-			return "", "", rc, err
+			if errors.Is(err, config.ErrProjectNotFound) {
+				return target, fmt.Errorf("%w (see forest project list)", err)
+			}
+
+			return target, err
 		}
 
-		rc = resolved
+		target.rc = resolved
 	}
 
 	if baseBranchFlag != "" {
-		rc.Branch = baseBranchFlag
+		target.rc.Branch = baseBranchFlag
+	}
+
+	return target, nil
+}
+
+// isLink reports whether arg is a GitHub URL or an owner/repo#number
+// reference rather than a branch. A reference is also a valid branch
+// name, so a local or fetched remote branch of that name in the
+// project wins; the full URL still reaches the issue or pull request.
+func isLink(arg, projectFlag string) bool {
+	if github.IsGitHubURL(arg) {
+		return true
+	}
+
+	if !github.IsReference(arg) {
+		return false
+	}
+
+	name := projectFlag
+	if name == "" {
+		inferred, err := config.InferProject()
+		if err != nil {
+			return true
+		}
+
+		name = inferred
 	}
 
-	return project, branch, rc, nil
+	rc, err := config.Resolve(name)
+	if err != nil {
+		return true
+	}
+
+	return !branchExists(rc.Repo, arg)
 }
 
-// resolveLinkBranch determines the branch name for a GitHub link. For
-// issues it returns the issue number as the branch name. For PRs it
+// branchExists reports whether branch exists locally or as a remote
+// tracking ref of any remote, without contacting the remotes.
+func branchExists(repoPath, branch string) bool {
+	if git.BranchExists(repoPath, branch) {
+		return true
+	}
+
+	remotes, err := git.Remotes(repoPath)
+	if err != nil {
+		return false
+	}
+
+	for _, remote := range remotes {
+		if git.RemoteRefExists(repoPath, remote, branch) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseLink parses a GitHub URL or owner/repo#number reference. A
+// reference does not say whether it is an issue or a pull request, so
+// gh is asked; if that fails it is treated as an issue.
+func parseLink(arg string) (github.Link, error) {
+	if github.IsGitHubURL(arg) {
+		return github.ParseLink(arg)
+	}
+
+	link, err := github.ParseReference(arg)
+	if err != nil {
+		return link, err
+	}
+
+	kind, err := github.LookupKind(link.NWO(), link.Number)
+	if err != nil {
+		slog.Debug("could not look up reference kind, assuming issue",
+			slog.String("reference", arg),
+			slog.String("error", err.Error()),
+		)
+
+		return link, nil
+	}
+
+	link.Kind = kind
+
+	return link, nil
+}
+
+// resolveLinkBranch determines the branch name for a GitHub link by
+// rendering the configured issue or PR branch template, or taking the
+// branch from a branch link as is. For PRs it
 // fetches the head branch from the appropriate remote when the branch
-// does not exist locally.
-func resolveLinkBranch(link github.Link, repoPath string) (string, error) {
+// does not exist locally, and also returns the PR's head metadata.
+func resolveLinkBranch(link github.Link, rc config.ResolvedConfig) (string, github.PRHead, error) {
+	repoPath := rc.Repo
+
 	switch link.Kind {
 
 	case github.KindIssue:
-		return fmt.Sprintf("issue-%d", link.Number), nil
+		branch, err := github.IssueBranch(link, rc.IssueBranchTemplate)
+		if err != nil {
+			return "", github.PRHead{}, err
+		}
+
+		return branch, github.PRHead{}, nil
 
 	case github.KindPR:
 		head, err := github.FetchPRHead(link.NWO(), link.Number)
 		if err != nil {
-			return "", fmt.Errorf("fetching PR metadata: %w", err)
+			return "", github.PRHead{}, fmt.Errorf("fetching PR metadata: %w", err)
 		}
 
 		slog.Debug("resolved PR head",
 			slog.String("branch", head.Branch),
 			slog.Bool("fork", head.IsFork),
 			slog.String("clone_url", head.CloneURL),
+			slog.String("state", head.State),
+			slog.String("sha", head.HeadSHA),
 		)
 
+		localBranch, err := github.PRBranch(link, head, rc.PRBranchTemplate)
+		if err != nil {
+			return "", github.PRHead{}, err
+		}
+
 		// For fork PRs, prefix the local branch with the fork owner
 		// so it does not collide with identically named branches in
 		// the base repository.
-		localBranch := head.Branch
 		if head.IsFork {
-			localBranch = head.ForkOwner + "/" + head.Branch
+			localBranch = head.ForkOwner + "/" + localBranch
+		}
+
+		// Creating a branch for a merged or closed PR would resurrect
+		// a stale branch, so ask first. Reopening a branch that
+		// already exists only warns.
+		if state := finishedState(head.State); state != "" {
+			if git.BranchExists(repoPath, localBranch) {
+				fmt.Printf("warning: pull request #%d is %s\n", link.Number, state)
+			} else {
+				ok, err := prompt.Confirm(fmt.Sprintf("PR #%d is %s; create worktree anyway? [y/N] ", link.Number, state))
+				if err != nil {
+					return "", github.PRHead{}, err
+				}
+
+				if !ok {
+					return "", github.PRHead{}, errDeclined
+				}
+			}
+		}
+
+		// A pinned worktree checks out the head commit rather than
+		// the branch, so the branch is left alone.
+		if pinFlag {
+			return localBranch, head, nil
+		}
+
+		// gh pr checkout fetches the branch and sets up fork remotes
+		// itself once the worktree exists.
+		if rc.PRFetchStrategy == config.PRFetchGHCheckout && !git.BranchExists(repoPath, localBranch) {
+			return localBranch, head, nil
 		}
 
 		if head.IsFork {
 			if err := git.EnsureRemote(repoPath, head.ForkOwner, head.CloneURL); err != nil {
-				return "", fmt.Errorf("adding fork remote: %w", err)
+				return "", github.PRHead{}, fmt.Errorf("adding fork remote: %w", err)
 			}
 		}
 
 		// The branch may not exist locally. Fetch it from the head
-		// repository. Skip the fetch when the local branch already
-		// exists. For same-repo PRs, the fetch writes directly to the
-		// local branch name. That fails when the branch is already
-		// checked out in another worktree.
-		//
-		// For fork PRs, the local branch name is prefixed with the fork
-		// owner to avoid collisions. The upstream should still point to
-		// the fork's actual branch name, not the prefixed local branch.
+		// repository, skipping the fetch when the local branch already
+		// exists. The local branch may be named differently from the
+		// PR's head branch, by pr_branch_template or the fork owner
+		// prefix, so its upstream is set to the head branch explicitly.
 		if !git.BranchExists(repoPath, localBranch) {
-			if head.IsFork {
-				fmt.Printf("Fetching branch %q from %s\n", head.Branch, head.ForkOwner)
-
-				if err := git.FetchBranch(repoPath, head.ForkOwner, head.Branch); err != nil {
-					return "", fmt.Errorf("fetching branch: %w", err)
-				}
-
-				if err := git.CreateTrackingBranch(repoPath, localBranch, head.ForkOwner, head.Branch); err != nil {
-					return "", fmt.Errorf("creating tracking branch: %w", err)
-				}
-			} else {
-				fmt.Printf("Fetching branch %q from %s\n", localBranch, head.CloneURL)
-
-				if err := git.Fetch(repoPath, head.CloneURL, head.Branch, localBranch); err != nil {
-					return "", fmt.Errorf("fetching branch: %w", err)
-				}
+			if err := fetchPRBranch(repoPath, localBranch, head); err != nil {
+				return "", github.PRHead{}, err
 			}
 		} else if head.IsFork {
 			// Older worktrees may have been created before fork PR
 			// upstreams were configured explicitly. Repair them when the
 			// user reopens the PR by URL.
 			if err := git.SetBranchUpstream(repoPath, localBranch, head.ForkOwner, head.Branch); err != nil {
-				return "", fmt.Errorf("setting branch upstream: %w", err)
+				return "", github.PRHead{}, fmt.Errorf("setting branch upstream: %w", err)
 			}
 		}
 
-		return localBranch, nil
+		return localBranch, head, nil
+
+	case github.KindBranch:
+		// AddTree fetches the branch from the link's remote when it
+		// does not exist locally, and validates its name if it must
+		// create it.
+		return linkBranch(link, repoPath), github.PRHead{}, nil
+
+	default:
+		return "", github.PRHead{}, fmt.Errorf("unexpected link kind: %d", link.Kind)
+	}
+}
+
+// fetchPRBranch creates localBranch from a PR's head branch. The
+// branch tracks the head branch on the remote for the head repository,
+// so that a plain git push updates the PR even when pr_branch_template
+// or a fork prefix gives the local branch another name.
+func fetchPRBranch(repoPath, localBranch string, head github.PRHead) error {
+	remote := prHeadRemote(repoPath, head)
+
+	// Without a remote for the head repository there is nothing to
+	// track; fetch straight into the local branch.
+	if remote == "" {
+		fmt.Printf("Fetching branch %q from %s\n", head.Branch, head.CloneURL)
+
+		if err := git.FetchWithProgress(repoPath, head.CloneURL, head.Branch, localBranch, os.Stderr); err != nil {
+			return fmt.Errorf("fetching branch: %w", err)
+		}
+
+		return nil
+	}
+
+	// A previous fetch may already have the PR branch as a remote
+	// tracking ref. Branch from it directly rather than fetching
+	// again.
+	if git.RemoteRefExists(repoPath, remote, head.Branch) {
+		fmt.Printf("Using existing ref %s/%s\n", remote, head.Branch)
+	} else {
+		fmt.Printf("Fetching branch %q from %s\n", head.Branch, remote)
+
+		if err := git.FetchBranchWithProgress(repoPath, remote, head.Branch, os.Stderr); err != nil {
+			return fmt.Errorf("fetching branch: %w", err)
+		}
+	}
+
+	if err := git.CreateTrackingBranch(repoPath, localBranch, remote, head.Branch); err != nil {
+		return fmt.Errorf("creating tracking branch: %w", err)
+	}
+
+	return nil
+}
+
+// linkRemote returns the remote of repoPath for a link's repository,
+// or an empty string if there is none.
+func linkRemote(link github.Link, repoPath string) string {
+	return git.RemoteForURL(repoPath, "https://github.com/"+link.NWO()+".git")
+}
+
+// linkBranch picks the branch a GitHub branch URL refers to: the
+// longest prefix of its path that exists locally or on the remote for
+// the link's repository, so that URLs pointing into a directory of a
+// branch with slashes in its name still work. When none exists, the
+// whole path is used and becomes a new branch.
+func linkBranch(link github.Link, repoPath string) string {
+	candidates := link.BranchCandidates()
+	if len(candidates) == 1 {
+		return link.Branch
+	}
+
+	var remoteBranches map[string]bool
+
+	if remote := linkRemote(link, repoPath); remote != "" {
+		var err error
+
+		remoteBranches, err = git.RemoteBranches(repoPath, remote)
+		if err != nil {
+			slog.Debug("could not list remote branches", slog.String("remote", remote), slog.Any("err", err))
+		}
+	}
+
+	for _, branch := range candidates {
+		if git.BranchExists(repoPath, branch) || remoteBranches[branch] {
+			return branch
+		}
+	}
+
+	return link.Branch
+}
+
+// prBaseRef returns the ref for a PR's base branch in repoPath: the
+// local branch if it exists, otherwise the remote tracking ref of the
+// remote for the base repository. It returns an empty string when
+// neither exists, leaving the project's base branch in place.
+func prBaseRef(repoPath string, link github.Link, base string) string {
+	if base == "" {
+		return ""
+	}
+
+	if git.BranchExists(repoPath, base) {
+		return base
+	}
+
+	remote := git.RemoteForURL(repoPath, "https://github.com/"+link.NWO()+".git")
+	if remote != "" && git.RemoteRefExists(repoPath, remote, base) {
+		return remote + "/" + base
+	}
+
+	return ""
+}
 
+// finishedState returns "merged" or "closed" for a PR that is no longer
+// open, and the empty string otherwise.
+func finishedState(state string) string {
+	switch state {
+	case github.PRMerged:
+		return "merged"
+	case github.PRClosed:
+		return "closed"
 	default:
-		return "", fmt.Errorf("unexpected link kind: %d", link.Kind)
+		return ""
 	}
 }
 
+// prHeadRemote returns the local remote for a PR's head repository:
+// the fork owner's remote for fork PRs, or the remote whose URL
+// matches the head repository for same-repo PRs.
+func prHeadRemote(repoPath string, head github.PRHead) string {
+	if head.IsFork {
+		return head.ForkOwner
+	}
+
+	return git.RemoteForURL(repoPath, head.CloneURL)
+}
+
 // resolveProject determines the project name from the flag value,
 // falling back to inference from the working directory.
 func resolveProject(flagValue string) (string, error) {
@@ -149,5 +444,51 @@ func resolveProject(flagValue string) (string, error) {
 		return flagValue, nil
 	}
 
-	return config.InferProject()
+	name, err := config.InferProject()
+	if err != nil {
+		return chooseProject(err)
+	}
+
+	return name, nil
+}
+
+// linkProject finds the project for a GitHub link: the --project flag
+// when given, otherwise the project whose remotes match the link's
+// repository.
+func linkProject(link github.Link, projectFlag string) (string, config.ResolvedConfig, error) {
+	name := projectFlag
+
+	if name == "" {
+		found, rc, err := config.FindProjectByRemote(link.NWO())
+		if err == nil {
+			return found, rc, nil
+		}
+
+		name, err = chooseProject(err)
+		if err != nil {
+			return "", rc, err
+		}
+	}
+
+	rc, err := config.Resolve(name)
+
+	return name, rc, err
+}
+
+// chooseProject asks the user to pick one of the candidates of an
+// ambiguous project match. Any other error, or an ambiguous match
+// without a terminal to ask on or with --yes, is returned unchanged,
+// telling the user to pass --project.
+func chooseProject(err error) (string, error) {
+	var ambiguous *config.AmbiguousProjectError
+	if !errors.As(err, &ambiguous) || prompt.AssumeYes() || !prompt.IsInteractive() {
+		return "", err
+	}
+
+	i, err := prompt.Choose(fmt.Sprintf("Several projects match %s:", ambiguous.NWO), ambiguous.Candidates)
+	if err != nil {
+		return "", err
+	}
+
+	return ambiguous.Candidates[i], nil
 }
@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/debug"
+
+	"github.com/spf13/cobra"
+)
+
+// commit and date are set at build time via -ldflags. When empty, they
+// are read from the VCS stamp in the Go build info, which go build adds
+// when building from a git checkout.
+var (
+	commit = ""
+	date   = ""
+)
+
+// buildDetails describes the running binary, for bug reports.
+type buildDetails struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	Date      string `json:"date"`
+	GoVersion string `json:"go_version"`
+	Platform  string `json:"platform"`
+}
+
+// readBuildDetails collects the version, commit, and build date from
+// ldflags, falling back to the Go build info. Unknown values are
+// reported as "unknown".
+func readBuildDetails() buildDetails {
+	d := buildDetails{
+		Version:   resolveVersion(),
+		Commit:    commit,
+		Date:      date,
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+	}
+
+	if info, ok := debug.ReadBuildInfo(); ok {
+		modified := false
+
+		for _, s := range info.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				if d.Commit == "" {
+					d.Commit = s.Value
+				}
+			case "vcs.time":
+				if d.Date == "" {
+					d.Date = s.Value
+				}
+			case "vcs.modified":
+				modified = s.Value == "true"
+			}
+		}
+
+		if modified && commit == "" && d.Commit != "" {
+			d.Commit += "-dirty"
+		}
+	}
+
+	if d.Commit == "" {
+		d.Commit = "unknown"
+	}
+
+	if d.Date == "" {
+		d.Date = "unknown"
+	}
+
+	return d
+}
+
+var versionJSONFlag bool
+
+func versionCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "version",
+		Short: "Print version and build details",
+		Long: `Print the forest version, the git commit and date it was built from,
+and the Go version and platform. Include this output in bug reports.
+
+Use --json to print the details as a JSON object.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			d := readBuildDetails()
+
+			if versionJSONFlag {
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetIndent("", "  ")
+
+				return enc.Encode(d)
+			}
+
+			fmt.Printf("forest %s\n", d.Version)
+			fmt.Printf("commit:   %s\n", d.Commit)
+			fmt.Printf("built:    %s\n", d.Date)
+			fmt.Printf("go:       %s\n", d.GoVersion)
+			fmt.Printf("platform: %s\n", d.Platform)
+
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&versionJSONFlag, "json", false, "print build details as JSON")
+
+	return cmd
+}
@@ -29,11 +29,14 @@ abbr --add fp   "forest project"
 abbr --add fpa  "forest project add"
 abbr --add fpl  "forest project list"
 abbr --add fpr  "forest project remove"
+abbr --add fpmv "forest project relocate"
+abbr --add fprf "forest project refresh"
 
 # session: tmux session management.
 abbr --add fs   "forest session"
 abbr --add fsl  "forest session list"
 abbr --add fsk  "forest session kill"
+abbr --add fspr "forest session prune"
 
 # session with --project override.
 abbr --add fsp   "forest session --project"
@@ -42,3 +45,9 @@ abbr --add fskp  "forest session kill --project"
 
 # config: open config in editor.
 abbr --add fc  "forest config"
+abbr --add fcg "forest config global"
+abbr --add fcp "forest config project"
+abbr --add fcs "forest config set"
+
+# version: print build details.
+abbr --add fv "forest version"
@@ -0,0 +1,78 @@
+// Package clipboard reads and writes the system clipboard by shelling
+// out to the platform's clipboard commands. Shelling out keeps forest
+// free of clipboard libraries and their cgo requirements.
+package clipboard
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// ErrUnavailable is returned when none of the supported clipboard
+// commands are installed.
+var ErrUnavailable = errors.New("no clipboard command found (install pbcopy/pbpaste, wl-clipboard, xclip or xsel)")
+
+// readCommands lists commands that print the clipboard, tried in order.
+var readCommands = [][]string{
+	{"pbpaste"},
+	{"wl-paste", "--no-newline"},
+	{"xclip", "-selection", "clipboard", "-out"},
+	{"xsel", "--clipboard", "--output"},
+}
+
+// writeCommands lists commands that replace the clipboard with their
+// stdin, tried in order.
+var writeCommands = [][]string{
+	{"pbcopy"},
+	{"wl-copy"},
+	{"xclip", "-selection", "clipboard", "-in"},
+	{"xsel", "--clipboard", "--input"},
+}
+
+// Read returns the contents of the system clipboard using the first
+// available clipboard command.
+func Read() (string, error) {
+	args, path, err := find(readCommands)
+	if err != nil {
+		return "", err
+	}
+
+	output, err := exec.Command(path, args[1:]...).Output()
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", args[0], err)
+	}
+
+	return string(output), nil
+}
+
+// Write replaces the contents of the system clipboard with text using
+// the first available clipboard command.
+func Write(text string) error {
+	args, path, err := find(writeCommands)
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command(path, args[1:]...)
+	cmd.Stdin = strings.NewReader(text)
+
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s: %s: %w", args[0], strings.TrimSpace(string(output)), err)
+	}
+
+	return nil
+}
+
+// find returns the first command in candidates that is installed,
+// along with its resolved path.
+func find(candidates [][]string) ([]string, string, error) {
+	for _, args := range candidates {
+		if path, err := exec.LookPath(args[0]); err == nil {
+			return args, path, nil
+		}
+	}
+
+	return nil, "", ErrUnavailable
+}
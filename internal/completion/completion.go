@@ -46,7 +46,7 @@ func Branches(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Com
 	if err != nil {
 		return nil, cobra.ShellCompDirectiveError
 	}
-	trees, err := git.List(proj.Repo)
+	trees, err := git.List(proj.RepoPath())
 	if err != nil {
 		return nil, cobra.ShellCompDirectiveError
 	}
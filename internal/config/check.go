@@ -0,0 +1,96 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// LayoutIssue describes a layout window whose program could not be
+// found.
+type LayoutIssue struct {
+	// Window is the window's name, or its 1-based position when unnamed.
+	Window string
+
+	// Program is the first word of the command or shell that failed to
+	// resolve.
+	Program string
+}
+
+func (i LayoutIssue) String() string {
+	return fmt.Sprintf("window %s: %q not found on PATH or in the repository", i.Window, i.Program)
+}
+
+// shellBuiltins are command words that resolve inside the shell rather
+// than on PATH.
+var shellBuiltins = map[string]bool{
+	".": true, "alias": true, "cd": true, "command": true, "echo": true,
+	"eval": true, "exec": true, "export": true, "set": true,
+	"source": true, "test": true, "[": true, "true": true, "false": true,
+}
+
+// CheckLayout reports layout windows whose command or shell starts
+// with a program that neither resolves on PATH nor exists relative to
+// repo. It is a best-effort check: words it cannot judge, such as
+// shell builtins or ones containing variables, are assumed fine.
+func CheckLayout(layout []Window, repo string) []LayoutIssue {
+	var issues []LayoutIssue
+
+	for i, w := range layout {
+		name := w.Name
+		if name == "" {
+			name = fmt.Sprintf("%d", i+1)
+		}
+
+		for _, cmd := range []string{w.Shell, w.Command} {
+			program := firstProgram(cmd)
+			if program == "" || programExists(program, repo) {
+				continue
+			}
+
+			issues = append(issues, LayoutIssue{Window: name, Program: program})
+		}
+	}
+
+	return issues
+}
+
+// firstProgram returns the first word of cmd that names a program,
+// skipping leading VAR=value assignments. It returns "" when the word
+// cannot be checked.
+func firstProgram(cmd string) string {
+	for _, word := range strings.Fields(cmd) {
+		if strings.Contains(word, "=") && !strings.HasPrefix(word, "=") {
+			continue
+		}
+
+		if shellBuiltins[word] || strings.ContainsAny(word, "$`'\"(){};|&<>*?") {
+			return ""
+		}
+
+		return word
+	}
+
+	return ""
+}
+
+// programExists reports whether program resolves on PATH or, when it
+// is a path, exists on disk. Relative paths are checked against repo
+// since new worktrees start as a checkout of the same files.
+func programExists(program, repo string) bool {
+	if !strings.Contains(program, "/") && !strings.HasPrefix(program, "~") {
+		_, err := exec.LookPath(program)
+		return err == nil
+	}
+
+	program = ExpandPath(program)
+	if !filepath.IsAbs(program) {
+		program = filepath.Join(repo, program)
+	}
+
+	_, err := os.Stat(program)
+
+	return err == nil
+}
@@ -0,0 +1,35 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckLayout(t *testing.T) {
+	repo := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(repo, "bin"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(repo, "bin", "dev"), []byte("#!/bin/sh\n"), 0o755))
+
+	layout := []Window{
+		{Name: "shell"},
+		{Name: "builtin", Command: "cd web && npm start"},
+		{Name: "env", Command: "FOO=1 sh -c true"},
+		{Name: "script", Command: "./bin/dev --watch"},
+		{Name: "missing-script", Command: "./scripts/setup.sh"},
+		{Command: "forest-no-such-program"},
+		{Name: "shell-missing", Shell: "forest-no-such-shell -l", Command: "sh"},
+		{Name: "variable", Command: "$EDITOR ."},
+	}
+
+	issues := CheckLayout(layout, repo)
+
+	assert.Equal(t, []LayoutIssue{
+		{Window: "missing-script", Program: "./scripts/setup.sh"},
+		{Window: "6", Program: "forest-no-such-program"},
+		{Window: "shell-missing", Program: "forest-no-such-shell"},
+	}, issues)
+}
@@ -6,8 +6,11 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
+	"time"
 
 	"gopkg.in/yaml.v3"
+
+	"github.com/mhamza15/forest/internal/github"
 )
 
 // Window describes a tmux window to create as part of a session layout.
@@ -18,6 +21,11 @@ type Window struct {
 	// Command is the shell command to run in this window.
 	// An empty string opens a plain shell.
 	Command string `yaml:"command"`
+
+	// Shell is the program the window runs instead of tmux's default
+	// shell, such as "fish" or "bash -l" for a login shell. Command is
+	// typed into it.
+	Shell string `yaml:"shell,omitempty"`
 }
 
 // GlobalConfig holds the top-level forest configuration.
@@ -28,7 +36,8 @@ type GlobalConfig struct {
 	// falling back to ~/.local/share/forest/worktrees.
 	WorktreeDir string `yaml:"worktree_dir,omitempty"`
 
-	// Branch is the default base branch for new worktrees.
+	// Branch is the default base branch for new worktrees. The values
+	// "@default" and "origin/HEAD" follow the remote's default branch.
 	Branch string `yaml:"branch"`
 
 	// ProjectsDir is the starting directory for the project add file
@@ -37,12 +46,148 @@ type GlobalConfig struct {
 
 	// Layout defines the tmux windows to create for each new session.
 	Layout []Window `yaml:"layout,omitempty"`
+
+	// OnSession lists tmux commands to run once a new session has been
+	// created and its layout applied. {session}, {project} and {branch}
+	// are substituted.
+	OnSession []string `yaml:"on_session,omitempty"`
+
+	// PruneExclude lists glob patterns of branches that tree prune
+	// never removes, such as long-lived "staging" branches.
+	PruneExclude []string `yaml:"prune_exclude,omitempty"`
+
+	// AutoSession controls whether switching to a worktree creates
+	// and switches to a tmux session. When omitted, defaults to true.
+	AutoSession *bool `yaml:"auto_session,omitempty"`
+
+	// WarnMissingCopies controls whether warnings about missing copy
+	// and symlink files are printed when switching to a new worktree.
+	// When omitted, defaults to true.
+	WarnMissingCopies *bool `yaml:"warn_missing_copies,omitempty"`
+
+	// IssueBranchTemplate is a text/template for the branch name used
+	// for GitHub issue links. When omitted, defaults to
+	// "issue-{{.Number}}".
+	IssueBranchTemplate string `yaml:"issue_branch_template,omitempty"`
+
+	// PRBranchTemplate is a text/template for the local branch name used
+	// for GitHub pull request links. When omitted, defaults to
+	// "{{.HeadBranch}}".
+	PRBranchTemplate string `yaml:"pr_branch_template,omitempty"`
+
+	// PRFetchStrategy controls how the branch of a pull request link is
+	// fetched: clone-url (default) or gh-checkout.
+	PRFetchStrategy string `yaml:"pr_fetch_strategy,omitempty"`
+
+	// SchemaMode controls how saved config files reference their JSON
+	// schema: url (default), absolute, relative, or none.
+	SchemaMode string `yaml:"schema_mode,omitempty"`
+
+	// SessionReadyTimeout is how long to wait for a new tmux window's
+	// shell to start before sending it a layout command, as a Go
+	// duration such as "3s". "0" sends immediately. When omitted,
+	// defaults to DefaultSessionReadyTimeout.
+	SessionReadyTimeout string `yaml:"session_ready_timeout,omitempty"`
+
+	// TUIKeys overrides keys in the tree browser, mapping an action
+	// such as "delete" to the key that triggers it.
+	TUIKeys map[string]string `yaml:"tui_keys,omitempty"`
 }
 
 const (
 	defaultBranch = "main"
 )
 
+// PR fetch strategies control how a pull request's branch is fetched
+// when switching to a PR link.
+const (
+	// PRFetchCloneURL fetches the head branch from the head
+	// repository's clone URL, or its fork remote. This is the default.
+	PRFetchCloneURL = "clone-url"
+
+	// PRFetchGHCheckout runs gh pr checkout in the new worktree, so gh
+	// handles authentication and fork remotes.
+	PRFetchGHCheckout = "gh-checkout"
+)
+
+// validPRFetchStrategy reports whether strategy is a recognized PR
+// fetch strategy. The empty string selects the default.
+func validPRFetchStrategy(strategy string) bool {
+	switch strategy {
+	case "", PRFetchCloneURL, PRFetchGHCheckout:
+		return true
+	default:
+		return false
+	}
+}
+
+// DefaultSessionReadyTimeout is the session_ready_timeout used when
+// none is configured.
+const DefaultSessionReadyTimeout = 3 * time.Second
+
+// ReadyTimeout parses SessionReadyTimeout, returning
+// DefaultSessionReadyTimeout when it is empty.
+func (c GlobalConfig) ReadyTimeout() (time.Duration, error) {
+	if c.SessionReadyTimeout == "" {
+		return DefaultSessionReadyTimeout, nil
+	}
+
+	d, err := time.ParseDuration(c.SessionReadyTimeout)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid session_ready_timeout %q (want a duration such as 2s)", c.SessionReadyTimeout)
+	}
+
+	return d, nil
+}
+
+// validate checks the settings that take one of a fixed set of values
+// or must parse. Empty values select defaults and are valid.
+func (c GlobalConfig) validate() error {
+	if c.SchemaMode != "" && !validSchemaMode(c.SchemaMode) {
+		return fmt.Errorf("unknown schema_mode %q", c.SchemaMode)
+	}
+
+	if !validPRFetchStrategy(c.PRFetchStrategy) {
+		return fmt.Errorf("unknown pr_fetch_strategy %q", c.PRFetchStrategy)
+	}
+
+	_, err := c.ReadyTimeout()
+
+	return err
+}
+
+// withDefaults returns c with the defaults of settings that are
+// otherwise left unset by LoadGlobal filled in, for display.
+func (c GlobalConfig) withDefaults() GlobalConfig {
+	enabled := true
+
+	if c.AutoSession == nil {
+		c.AutoSession = &enabled
+	}
+
+	if c.WarnMissingCopies == nil {
+		c.WarnMissingCopies = &enabled
+	}
+
+	if c.IssueBranchTemplate == "" {
+		c.IssueBranchTemplate = github.DefaultIssueBranchTemplate
+	}
+
+	if c.PRBranchTemplate == "" {
+		c.PRBranchTemplate = github.DefaultPRBranchTemplate
+	}
+
+	if c.PRFetchStrategy == "" {
+		c.PRFetchStrategy = PRFetchCloneURL
+	}
+
+	if c.SessionReadyTimeout == "" {
+		c.SessionReadyTimeout = DefaultSessionReadyTimeout.String()
+	}
+
+	return c
+}
+
 // LoadGlobal reads the global config file and returns it with defaults
 // applied for any unset fields. If the file does not exist, the defaults
 // are returned without error.
@@ -50,6 +195,7 @@ func LoadGlobal() (GlobalConfig, error) {
 	cfg := GlobalConfig{
 		WorktreeDir: DefaultWorktreeDir(),
 		Branch:      defaultBranch,
+		SchemaMode:  SchemaModeURL,
 	}
 
 	data, err := os.ReadFile(GlobalConfigPath())
@@ -73,6 +219,14 @@ func LoadGlobal() (GlobalConfig, error) {
 		cfg.Branch = defaultBranch
 	}
 
+	if cfg.SchemaMode == "" {
+		cfg.SchemaMode = SchemaModeURL
+	}
+
+	if err := cfg.validate(); err != nil {
+		return cfg, fmt.Errorf("parsing global config: %w", err)
+	}
+
 	cfg.WorktreeDir = ExpandPath(cfg.WorktreeDir)
 
 	if cfg.ProjectsDir != "" {
@@ -82,6 +236,55 @@ func LoadGlobal() (GlobalConfig, error) {
 	return cfg, nil
 }
 
+// globalSchemaMode returns the schema_mode of the global config, or
+// SchemaModeURL when it is unset or the config cannot be read. Unlike
+// LoadGlobal, it ignores problems with other settings, so that they do
+// not stop project configs from being saved.
+func globalSchemaMode() string {
+	var cfg struct {
+		SchemaMode string `yaml:"schema_mode"`
+	}
+
+	data, err := os.ReadFile(GlobalConfigPath())
+	if err != nil || yaml.Unmarshal(data, &cfg) != nil || !validSchemaMode(cfg.SchemaMode) {
+		return SchemaModeURL
+	}
+
+	return cfg.SchemaMode
+}
+
+// SaveGlobal writes the global config to disk with a schema modeline
+// for cfg.SchemaMode, creating parent directories as needed. Comments
+// in an existing file are not preserved.
+func SaveGlobal(cfg GlobalConfig) error {
+	p := GlobalConfigPath()
+
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return fmt.Errorf("creating config directory: %w", err)
+	}
+
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		return fmt.Errorf("marshaling global config: %w", err)
+	}
+
+	mode := cfg.SchemaMode
+	if mode == "" {
+		mode = SchemaModeURL
+	}
+
+	content, err := withModeline(mode, ConfigSchemaModeline(mode), string(data))
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+		return fmt.Errorf("writing global config: %w", err)
+	}
+
+	return nil
+}
+
 // WriteDefaultGlobal writes a default global config file if one does not
 // already exist. It creates parent directories as needed.
 func WriteDefaultGlobal() error {
@@ -95,7 +298,7 @@ func WriteDefaultGlobal() error {
 		return fmt.Errorf("creating config directory: %w", err)
 	}
 
-	content := ConfigSchemaModeline() + "\n" + `
+	content := `
 # Forest global configuration
 
 # Default directory for storing worktrees. Worktrees are organized
@@ -103,14 +306,37 @@ func WriteDefaultGlobal() error {
 # Defaults to $XDG_DATA_HOME/forest/worktrees, or ~/.local/share/forest/worktrees.
 # worktree_dir:
 
-# Default branch to base new worktrees on (default: main)
+# Default branch to base new worktrees on (default: main). Use @default
+# to follow the remote's default branch.
 branch: main
 
+# Create and switch to a tmux session when switching to a worktree
+# (default: true). Set to false to only manage worktrees.
+# auto_session: true
+
+# Tmux commands to run after a new session is created and its layout
+# applied. {session}, {project} and {branch} are substituted.
+# on_session:
+#   - set-option -t {session} status-style bg=colour52
+
 # Default directory that your projects live in. Used as starting
 # point in ` + "`" + `project add` + "`" + ` directory picker. Example: ~/dev.
 # projects_dir:
+
+# How saved config files reference their JSON schema: url (default),
+# absolute, relative, or none.
+# schema_mode: url
+
+# How long to wait for a new tmux window's shell to start before sending
+# it a layout command (default: 3s). 0 sends immediately.
+# session_ready_timeout: 3s
 `
 
+	content, err := withModeline(SchemaModeURL, ConfigSchemaModeline(SchemaModeURL), content)
+	if err != nil {
+		return err
+	}
+
 	if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
 		return fmt.Errorf("writing default global config: %w", err)
 	}
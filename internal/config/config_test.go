@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -74,6 +75,40 @@ func TestLoadGlobal_TildeExpansion(t *testing.T) {
 	assert.Equal(t, filepath.Join(home, "my-trees"), cfg.WorktreeDir)
 }
 
+func TestLoadGlobal_EnvExpansion(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("FOREST_TEST_WORK", "/srv/work")
+
+	configDir := filepath.Join(dir, "forest")
+	require.NoError(t, os.MkdirAll(configDir, 0o755))
+
+	content := []byte("worktree_dir: $FOREST_TEST_WORK/trees\nprojects_dir: ${FOREST_TEST_WORK}/src\n")
+	require.NoError(t, os.WriteFile(filepath.Join(configDir, "config.yaml"), content, 0o644))
+
+	cfg, err := LoadGlobal()
+	require.NoError(t, err)
+
+	assert.Equal(t, "/srv/work/trees", cfg.WorktreeDir)
+	assert.Equal(t, "/srv/work/src", cfg.ProjectsDir)
+}
+
+func TestLoadGlobal_PRFetchStrategy(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	require.NoError(t, os.MkdirAll(ConfigDir(), 0o755))
+
+	require.NoError(t, os.WriteFile(GlobalConfigPath(), []byte("pr_fetch_strategy: gh-checkout\n"), 0o644))
+
+	cfg, err := LoadGlobal()
+	require.NoError(t, err)
+	assert.Equal(t, PRFetchGHCheckout, cfg.PRFetchStrategy)
+
+	require.NoError(t, os.WriteFile(GlobalConfigPath(), []byte("pr_fetch_strategy: rsync\n"), 0o644))
+
+	_, err = LoadGlobal()
+	assert.ErrorContains(t, err, "pr_fetch_strategy")
+}
+
 func TestWriteDefaultGlobal_CreatesFile(t *testing.T) {
 	dir := t.TempDir()
 	t.Setenv("XDG_CONFIG_HOME", dir)
@@ -102,3 +137,58 @@ func TestWriteDefaultGlobal_DoesNotOverwrite(t *testing.T) {
 
 	assert.Equal(t, existing, data)
 }
+
+func TestSaveAndLoadGlobal(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+
+	autoSession := false
+	cfg := GlobalConfig{
+		WorktreeDir:  "/home/user/worktrees",
+		Branch:       "develop",
+		PruneExclude: []string{"staging"},
+		AutoSession:  &autoSession,
+		SchemaMode:   SchemaModeNone,
+	}
+
+	require.NoError(t, SaveGlobal(cfg))
+
+	loaded, err := LoadGlobal()
+	require.NoError(t, err)
+
+	assert.Equal(t, cfg.WorktreeDir, loaded.WorktreeDir)
+	assert.Equal(t, cfg.Branch, loaded.Branch)
+	assert.Equal(t, cfg.PruneExclude, loaded.PruneExclude)
+	require.NotNil(t, loaded.AutoSession)
+	assert.False(t, *loaded.AutoSession)
+
+	data, err := os.ReadFile(GlobalConfigPath())
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "yaml-language-server")
+}
+
+func TestLoadGlobal_SessionReadyTimeout(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	require.NoError(t, os.MkdirAll(ConfigDir(), 0o755))
+
+	cfg, err := LoadGlobal()
+	require.NoError(t, err)
+
+	timeout, err := cfg.ReadyTimeout()
+	require.NoError(t, err)
+	assert.Equal(t, DefaultSessionReadyTimeout, timeout)
+
+	require.NoError(t, os.WriteFile(GlobalConfigPath(), []byte("session_ready_timeout: 500ms\n"), 0o644))
+
+	cfg, err = LoadGlobal()
+	require.NoError(t, err)
+
+	timeout, err = cfg.ReadyTimeout()
+	require.NoError(t, err)
+	assert.Equal(t, 500*time.Millisecond, timeout)
+
+	require.NoError(t, os.WriteFile(GlobalConfigPath(), []byte("session_ready_timeout: soon\n"), 0o644))
+
+	_, err = LoadGlobal()
+	assert.ErrorContains(t, err, "session_ready_timeout")
+}
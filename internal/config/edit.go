@@ -0,0 +1,271 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SetGlobalValue sets key to value in the global config file, creating
+// the file with defaults first if needed. Nested keys are separated by
+// dots. The value is parsed as YAML, so "true", "3" and "[a, b]" keep
+// their types. Comments and other settings in the file are preserved.
+// Unknown keys and values of the wrong type are rejected.
+func SetGlobalValue(key, value string) error {
+	if err := checkKey(GlobalConfig{}, key); err != nil {
+		return err
+	}
+
+	if err := WriteDefaultGlobal(); err != nil {
+		return err
+	}
+
+	return setFileValue(GlobalConfigPath(), key, value, func(data []byte) error {
+		var cfg GlobalConfig
+		if err := decodeStrict(data, &cfg); err != nil {
+			return err
+		}
+
+		return cfg.validate()
+	})
+}
+
+// SetProjectValue is like SetGlobalValue for the named project's
+// config.
+func SetProjectValue(name, key, value string) error {
+	if err := checkKey(ProjectConfig{}, key); err != nil {
+		return err
+	}
+
+	if _, err := LoadProject(name); err != nil {
+		return err
+	}
+
+	return setFileValue(ProjectConfigPath(name), key, value, func(data []byte) error {
+		var cfg ProjectConfig
+		return decodeStrict(data, &cfg)
+	})
+}
+
+// GetGlobalValue returns the effective value of key in the global
+// config, with defaults applied. Scalars are returned as is, and lists
+// and mappings as YAML. Keys that are known but unset and have no
+// default return an empty string.
+func GetGlobalValue(key string) (string, error) {
+	if err := checkKey(GlobalConfig{}, key); err != nil {
+		return "", err
+	}
+
+	cfg, err := LoadGlobal()
+	if err != nil {
+		return "", err
+	}
+
+	return lookupValue(cfg.withDefaults(), key)
+}
+
+// GetProjectValue returns the value of key in the named project's
+// config. Settings the project does not override return an empty
+// string.
+func GetProjectValue(name, key string) (string, error) {
+	if err := checkKey(ProjectConfig{}, key); err != nil {
+		return "", err
+	}
+
+	cfg, err := LoadProject(name)
+	if err != nil {
+		return "", err
+	}
+
+	return lookupValue(cfg, key)
+}
+
+// checkKey returns an error unless the first segment of key matches a
+// yaml field of cfg.
+func checkKey(cfg any, key string) error {
+	first, _, _ := strings.Cut(key, ".")
+
+	t := reflect.TypeOf(cfg)
+	for i := range t.NumField() {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+		if name == first {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("unknown config key %q", key)
+}
+
+// decodeStrict decodes data into v, rejecting unknown fields and
+// values of the wrong type.
+func decodeStrict(data []byte, v any) error {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+
+	if err := dec.Decode(v); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("invalid value: %w", err)
+	}
+
+	return nil
+}
+
+// lookupValue marshals cfg and returns the value at the dotted key.
+func lookupValue(cfg any, key string) (string, error) {
+	var doc yaml.Node
+	if err := doc.Encode(cfg); err != nil {
+		return "", fmt.Errorf("encoding config: %w", err)
+	}
+
+	node := &doc
+
+	for _, part := range strings.Split(key, ".") {
+		if node.Kind != yaml.MappingNode {
+			return "", fmt.Errorf("%s is not a mapping", part)
+		}
+
+		var next *yaml.Node
+
+		for i := 0; i < len(node.Content); i += 2 {
+			if node.Content[i].Value == part {
+				next = node.Content[i+1]
+				break
+			}
+		}
+
+		if next == nil {
+			return "", nil
+		}
+
+		node = next
+	}
+
+	if node.Kind == yaml.ScalarNode {
+		return node.Value, nil
+	}
+
+	out, err := yaml.Marshal(node)
+	if err != nil {
+		return "", fmt.Errorf("encoding %s: %w", key, err)
+	}
+
+	return strings.TrimSuffix(string(out), "\n"), nil
+}
+
+// setFileValue edits a single key in the YAML file at path by walking
+// its node tree, so that the rest of the file is written back as is.
+// The edited file is passed to validate before it is written.
+func setFileValue(path, key, value string, validate func([]byte) error) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading config: %w", err)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("parsing config %s: %w", path, err)
+	}
+
+	// A file holding only comments parses to an empty document.
+	if len(doc.Content) == 0 {
+		doc.Kind = yaml.DocumentNode
+		doc.Content = []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}
+	}
+
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return fmt.Errorf("config %s is not a YAML mapping", path)
+	}
+
+	newValue, err := parseValue(value)
+	if err != nil {
+		return err
+	}
+
+	if err := setNode(root, strings.Split(key, "."), newValue); err != nil {
+		return fmt.Errorf("setting %s: %w", key, err)
+	}
+
+	var buf bytes.Buffer
+
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+
+	if err := enc.Encode(&doc); err != nil {
+		return fmt.Errorf("encoding config: %w", err)
+	}
+
+	if err := enc.Close(); err != nil {
+		return fmt.Errorf("encoding config: %w", err)
+	}
+
+	if validate != nil {
+		if err := validate(buf.Bytes()); err != nil {
+			return fmt.Errorf("setting %s: %w", key, err)
+		}
+	}
+
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("writing config %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// parseValue parses a command-line value as a YAML node. An empty
+// value becomes an empty string.
+func parseValue(value string) (*yaml.Node, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(value), &doc); err != nil {
+		return nil, fmt.Errorf("parsing value %q: %w", value, err)
+	}
+
+	if len(doc.Content) == 0 {
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}, nil
+	}
+
+	return doc.Content[0], nil
+}
+
+// setNode sets the value at path within mapping, creating intermediate
+// mappings as needed. Comments attached to a replaced value are kept.
+func setNode(mapping *yaml.Node, path []string, value *yaml.Node) error {
+	for i := 0; i < len(mapping.Content); i += 2 {
+		k, v := mapping.Content[i], mapping.Content[i+1]
+		if k.Value != path[0] {
+			continue
+		}
+
+		if len(path) == 1 {
+			value.HeadComment = v.HeadComment
+			value.LineComment = v.LineComment
+			value.FootComment = v.FootComment
+			mapping.Content[i+1] = value
+
+			return nil
+		}
+
+		if v.Kind != yaml.MappingNode {
+			return fmt.Errorf("%s is not a mapping", path[0])
+		}
+
+		return setNode(v, path[1:], value)
+	}
+
+	keyNode := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: path[0]}
+
+	if len(path) == 1 {
+		mapping.Content = append(mapping.Content, keyNode, value)
+		return nil
+	}
+
+	child := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	mapping.Content = append(mapping.Content, keyNode, child)
+
+	return setNode(child, path[1:], value)
+}
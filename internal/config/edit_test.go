@@ -0,0 +1,112 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetFileValue(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+
+	content := "# Base branch.\nbranch: main # inline\n# Nested.\nextra:\n  depth: 1\n"
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+
+	require.NoError(t, setFileValue(path, "branch", "develop", nil))
+	require.NoError(t, setFileValue(path, "extra.depth", "2", nil))
+	require.NoError(t, setFileValue(path, "auto_session", "false", nil))
+	require.NoError(t, setFileValue(path, "prune_exclude", "[staging, release/*]", nil))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	assert.Equal(t,
+		"# Base branch.\nbranch: develop # inline\n# Nested.\nextra:\n  depth: 2\nauto_session: false\nprune_exclude: [staging, release/*]\n",
+		string(data),
+	)
+
+	assert.Error(t, setFileValue(path, "branch.name", "x", nil))
+}
+
+func TestSetAndGetGlobalValue(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("XDG_DATA_HOME", filepath.Join(dir, "data"))
+
+	value, err := GetGlobalValue("branch")
+	require.NoError(t, err)
+	assert.Equal(t, "main", value)
+
+	require.NoError(t, SetGlobalValue("branch", "develop"))
+	require.NoError(t, SetGlobalValue("prune_exclude", "[staging]"))
+
+	value, err = GetGlobalValue("branch")
+	require.NoError(t, err)
+	assert.Equal(t, "develop", value)
+
+	value, err = GetGlobalValue("prune_exclude")
+	require.NoError(t, err)
+	assert.Equal(t, "- staging", value)
+
+	assert.ErrorContains(t, SetGlobalValue("no_such_key", "x"), "unknown config key")
+	assert.ErrorContains(t, SetGlobalValue("auto_session", "maybe"), "invalid value")
+	assert.ErrorContains(t, SetGlobalValue("schema_mode", "bogus"), "schema_mode")
+
+	_, err = GetGlobalValue("no_such_key")
+	assert.ErrorContains(t, err, "unknown config key")
+
+	// Rejected values leave the file untouched, so the default shows.
+	value, err = GetGlobalValue("auto_session")
+	require.NoError(t, err)
+	assert.Equal(t, "true", value)
+}
+
+func TestGetGlobalValue_Defaults(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("XDG_DATA_HOME", filepath.Join(dir, "data"))
+
+	defaults := map[string]string{
+		"auto_session":          "true",
+		"warn_missing_copies":   "true",
+		"pr_fetch_strategy":     PRFetchCloneURL,
+		"session_ready_timeout": "3s",
+		"issue_branch_template": "issue-{{.Number}}",
+		"schema_mode":           SchemaModeURL,
+		"projects_dir":          "",
+	}
+
+	for key, want := range defaults {
+		value, err := GetGlobalValue(key)
+		require.NoError(t, err, key)
+		assert.Equal(t, want, value, key)
+	}
+
+	require.NoError(t, SetGlobalValue("auto_session", "false"))
+
+	value, err := GetGlobalValue("auto_session")
+	require.NoError(t, err)
+	assert.Equal(t, "false", value)
+}
+
+func TestSetAndGetProjectValue(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+
+	require.NoError(t, SaveProject("myapp", ProjectConfig{Repo: "/tmp/myapp"}))
+
+	require.NoError(t, SetProjectValue("myapp", "branch", "develop"))
+
+	value, err := GetProjectValue("myapp", "branch")
+	require.NoError(t, err)
+	assert.Equal(t, "develop", value)
+
+	value, err = GetProjectValue("myapp", "copy_from")
+	require.NoError(t, err)
+	assert.Empty(t, value)
+
+	assert.ErrorIs(t, SetProjectValue("missing", "branch", "x"), ErrProjectNotFound)
+}
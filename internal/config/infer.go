@@ -1,8 +1,10 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 
 	"github.com/mhamza15/forest/internal/git"
 )
@@ -21,7 +23,9 @@ func InferProject() (string, error) {
 }
 
 // InferProjectFromDir determines the project for the given directory
-// by matching its git remote URLs against registered projects.
+// by matching its git remote URLs against registered projects. When
+// several projects match a remote, the one whose repository dir belongs
+// to is used; if none does, an *AmbiguousProjectError is returned.
 func InferProjectFromDir(dir string) (string, error) {
 	remotes, err := git.Remotes(dir)
 	if err != nil {
@@ -40,7 +44,48 @@ func InferProjectFromDir(dir string) (string, error) {
 		if err == nil {
 			return name, nil
 		}
+
+		var ambiguous *AmbiguousProjectError
+		if errors.As(err, &ambiguous) {
+			if name := projectForRepo(dir, ambiguous.Candidates); name != "" {
+				return name, nil
+			}
+
+			return "", err
+		}
+	}
+
+	return "", fmt.Errorf("%w for current repository, use --project", ErrProjectNotFound)
+}
+
+// projectForRepo returns the project among names whose repository is
+// the main working tree of the repository containing dir, or an empty
+// string if there is none.
+func projectForRepo(dir string, names []string) string {
+	trees, err := git.List(dir)
+	if err != nil || len(trees) == 0 {
+		return ""
+	}
+
+	// git worktree list reports the main working tree first.
+	main := realPath(trees[0].Path)
+
+	for _, name := range names {
+		proj, err := LoadProject(name)
+		if err == nil && realPath(proj.RepoPath()) == main {
+			return name
+		}
+	}
+
+	return ""
+}
+
+// realPath resolves symlinks in path so that paths reported by git
+// compare equal to configured ones, falling back to the cleaned path.
+func realPath(path string) string {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		return resolved
 	}
 
-	return "", fmt.Errorf("no registered project matches current repository, use --project")
+	return filepath.Clean(path)
 }
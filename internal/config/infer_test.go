@@ -101,3 +101,29 @@ func TestInferProjectFromDir_NotGitRepo(t *testing.T) {
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "--project")
 }
+
+func TestInferProjectFromDir_AmbiguousPrefersOwnRepo(t *testing.T) {
+	configDir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configDir)
+
+	// Two clones of the same repository registered as separate projects.
+	const url = "https://github.com/acme/widgets.git"
+
+	first := initTestRepo(t, url)
+	registerProject(t, "widgets", first)
+
+	second := initTestRepo(t, url)
+	registerProject(t, "widgets-review", second)
+
+	name, err := InferProjectFromDir(second)
+	require.NoError(t, err)
+	assert.Equal(t, "widgets-review", name)
+
+	// A third, unregistered clone cannot be attributed to either.
+	_, err = InferProjectFromDir(initTestRepo(t, url))
+
+	var ambiguous *AmbiguousProjectError
+	require.ErrorAs(t, err, &ambiguous)
+	assert.ElementsMatch(t, []string{"widgets", "widgets-review"}, ambiguous.Candidates)
+	assert.ErrorIs(t, err, ErrAmbiguousProject)
+}
@@ -42,6 +42,24 @@ func DataDir() string {
 	return filepath.Join(base, appName)
 }
 
+// StateDir returns the directory where forest stores state that may
+// change on every run, such as caches and recently used entries, and
+// that is safe to delete. It respects $XDG_STATE_HOME, falling back to
+// ~/.local/state/forest.
+func StateDir() string {
+	base := os.Getenv("XDG_STATE_HOME")
+
+	if base == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return filepath.Join(".local", "state", appName)
+		}
+		base = filepath.Join(home, ".local", "state")
+	}
+
+	return filepath.Join(base, appName)
+}
+
 // GlobalConfigPath returns the path to the global config.yaml file.
 func GlobalConfigPath() string {
 	return filepath.Join(ConfigDir(), "config.yaml")
@@ -57,14 +75,30 @@ func ProjectConfigPath(name string) string {
 	return filepath.Join(ProjectsDir(), name+".yaml")
 }
 
+// SchemaDir returns the directory where local copies of the config
+// JSON schemas are written when schema_mode is absolute or relative.
+func SchemaDir() string {
+	return filepath.Join(ConfigDir(), "schema")
+}
+
 // DefaultWorktreeDir returns the default base directory for worktrees.
 func DefaultWorktreeDir() string {
 	return filepath.Join(DataDir(), "worktrees")
 }
 
-// ExpandPath replaces a leading ~ with the user's home directory.
-// If the path does not start with ~, it is returned unchanged.
+// ExpandPath expands environment variables such as $HOME or ${WORK}
+// in p, then replaces a leading ~ with the user's home directory.
+// Unset variables are left as written, so a path that happens to
+// contain a literal $ is not silently mangled.
 func ExpandPath(p string) string {
+	p = os.Expand(p, func(name string) string {
+		if value, ok := os.LookupEnv(name); ok {
+			return value
+		}
+
+		return "$" + name
+	})
+
 	if p != "~" && !strings.HasPrefix(p, "~/") {
 		return p
 	}
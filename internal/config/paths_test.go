@@ -39,6 +39,21 @@ func TestDataDir_XDGUnset(t *testing.T) {
 	assert.Equal(t, filepath.Join(home, ".local", "share", "forest"), DataDir())
 }
 
+func TestStateDir_XDGSet(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", "/tmp/xdg-state")
+
+	assert.Equal(t, "/tmp/xdg-state/forest", StateDir())
+}
+
+func TestStateDir_XDGUnset(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", "")
+
+	home, err := os.UserHomeDir()
+	require.NoError(t, err)
+
+	assert.Equal(t, filepath.Join(home, ".local", "state", "forest"), StateDir())
+}
+
 func TestGlobalConfigPath(t *testing.T) {
 	t.Setenv("XDG_CONFIG_HOME", "/tmp/xdg-config")
 
@@ -67,6 +82,8 @@ func TestExpandPath(t *testing.T) {
 	home, err := os.UserHomeDir()
 	require.NoError(t, err)
 
+	t.Setenv("FOREST_TEST_WORK", "/srv/work")
+
 	tests := []struct {
 		name string
 		path string
@@ -97,6 +114,21 @@ func TestExpandPath(t *testing.T) {
 			path: "~user/foo",
 			want: "~user/foo",
 		},
+		{
+			name: "env var",
+			path: "$FOREST_TEST_WORK/repos",
+			want: "/srv/work/repos",
+		},
+		{
+			name: "braced env var",
+			path: "${FOREST_TEST_WORK}/repos",
+			want: "/srv/work/repos",
+		},
+		{
+			name: "unset env var unchanged",
+			path: "/data/$FOREST_TEST_UNSET/x",
+			want: "/data/$FOREST_TEST_UNSET/x",
+		},
 		{
 			name: "empty string unchanged",
 			path: "",
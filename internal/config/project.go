@@ -6,22 +6,68 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 
 	"github.com/mhamza15/forest/internal/git"
 )
 
+// ErrProjectNotFound is returned when a project is not registered, or
+// when no registered project matches a repository.
+var ErrProjectNotFound = errors.New("project not found")
+
+// ErrAmbiguousProject is matched by an *AmbiguousProjectError.
+var ErrAmbiguousProject = errors.New("ambiguous project")
+
+// AmbiguousProjectError is returned when several projects match a
+// remote equally well, so that the caller can ask the user to pick one
+// instead of silently using the first.
+type AmbiguousProjectError struct {
+	// NWO is the "owner/repo" string that was looked up.
+	NWO string
+
+	// Candidates are the names of the matching projects.
+	Candidates []string
+}
+
+func (e *AmbiguousProjectError) Error() string {
+	return fmt.Sprintf("several projects match %s (%s); use --project to pick one", e.NWO, strings.Join(e.Candidates, ", "))
+}
+
+// Is reports whether target is ErrAmbiguousProject.
+func (e *AmbiguousProjectError) Is(target error) bool {
+	return target == ErrAmbiguousProject
+}
+
+// ErrRepoMissing is returned when a project's repository no longer
+// exists on disk.
+var ErrRepoMissing = errors.New("project repository does not exist")
+
 // ProjectConfig holds per-project overrides. Empty fields fall through
 // to the global config during resolution.
 type ProjectConfig struct {
-	// Repo is the absolute path to the git repository.
+	// Repo is the absolute path to the git repository. Hand-written
+	// configs may use ~ and environment variables, so read it through
+	// RepoPath.
 	Repo string `yaml:"repo"`
 
+	// Remote is the repository's origin remote in normalized
+	// "owner/repo" form, recorded when the project is registered. It
+	// lets FindProjectByRemote skip querying git, and project relocate
+	// --search find the repository after it has been moved. Refresh it
+	// with project refresh.
+	Remote string `yaml:"remote,omitempty"`
+
 	// WorktreeDir overrides the global worktree directory for this project.
 	WorktreeDir string `yaml:"worktree_dir,omitempty"`
 
+	// WorktreePrefix is prepended to the directory name of each new
+	// worktree, e.g. "wt-" for <worktree_dir>/<project>/wt-feature.
+	WorktreePrefix string `yaml:"worktree_prefix,omitempty"`
+
 	// Branch overrides the global base branch for this project.
 	Branch string `yaml:"branch,omitempty"`
 
@@ -37,10 +83,66 @@ type ProjectConfig struct {
 	// to that worktree.
 	Remove []string `yaml:"remove,omitempty"`
 
-	// Layout overrides the global tmux window layout for this project.
+	// CopyFrom names a branch whose worktree is used as the source for
+	// copy and symlink instead of the repo root. Useful when local-only
+	// files live in a designated "template" worktree.
+	CopyFrom string `yaml:"copy_from,omitempty"`
+
+	// CopyGitConfig lists git config keys whose values in the repo are
+	// written to each new worktree's own config, so that they can later
+	// be changed per worktree (e.g. user.email or core.hooksPath).
+	CopyGitConfig []string `yaml:"copy_git_config,omitempty"`
+
+	// Layout overrides the global tmux window layout for this project,
+	// or extends it when LayoutMode is "append".
 	Layout []Window `yaml:"layout,omitempty"`
+
+	// LayoutMode controls how Layout combines with the global layout:
+	// "replace" (the default) uses only the project's windows, and
+	// "append" opens the global windows first, then the project's.
+	LayoutMode string `yaml:"layout_mode,omitempty"`
+
+	// OnSession overrides the global on_session tmux commands for this
+	// project.
+	OnSession []string `yaml:"on_session,omitempty"`
+
+	// PruneExclude lists additional glob patterns of branches that tree
+	// prune never removes. They are combined with the global patterns.
+	PruneExclude []string `yaml:"prune_exclude,omitempty"`
+
+	// UpstreamRemote names the remote whose branches decide whether a
+	// branch is gone for tree prune and tree list --stale. When
+	// omitted, defaults to origin.
+	UpstreamRemote string `yaml:"upstream_remote,omitempty"`
+
+	// AutoSession overrides the global auto_session setting for this
+	// project.
+	AutoSession *bool `yaml:"auto_session,omitempty"`
+
+	// WarnMissingCopies overrides the global warn_missing_copies
+	// setting for this project.
+	WarnMissingCopies *bool `yaml:"warn_missing_copies,omitempty"`
+}
+
+// RepoPath returns Repo with ~ and environment variables expanded.
+// Repo itself is left as written so that saving the config back does
+// not replace them.
+func (p ProjectConfig) RepoPath() string {
+	return ExpandPath(p.Repo)
 }
 
+// Layout modes control how a project's layout combines with the global
+// layout.
+const (
+	// LayoutReplace uses the project's layout in place of the global
+	// one. This is the default.
+	LayoutReplace = "replace"
+
+	// LayoutAppend opens the global layout's windows first, followed by
+	// the project's.
+	LayoutAppend = "append"
+)
+
 // ResolvedConfig is the final configuration for a project after merging
 // project-level overrides onto the global defaults.
 type ResolvedConfig struct {
@@ -53,6 +155,9 @@ type ResolvedConfig struct {
 	// WorktreeDir is the resolved base directory for worktrees.
 	WorktreeDir string
 
+	// WorktreePrefix is prepended to new worktree directory names.
+	WorktreePrefix string
+
 	// Branch is the resolved base branch for new worktrees.
 	Branch string
 
@@ -65,8 +170,49 @@ type ResolvedConfig struct {
 	// Remove lists files to remove from each new worktree.
 	Remove []string
 
+	// CopyFrom is the branch whose worktree copy and symlink source
+	// files from. Empty uses the repo root.
+	CopyFrom string
+
+	// CopyGitConfig lists git config keys to copy into each new
+	// worktree's own config.
+	CopyGitConfig []string
+
 	// Layout defines the tmux windows to create for each new session.
 	Layout []Window
+
+	// OnSession lists tmux commands to run after a new session's
+	// layout is applied.
+	OnSession []string
+
+	// PruneExclude lists glob patterns of branches that tree prune
+	// never removes, combining global and project patterns.
+	PruneExclude []string
+
+	// UpstreamRemote is the remote checked for gone branches.
+	UpstreamRemote string
+
+	// AutoSession is true if switching to a worktree should create
+	// and switch to its tmux session.
+	AutoSession bool
+
+	// WarnMissingCopies is true if warnings about missing copy and
+	// symlink files should be printed. When false they are only logged
+	// at debug level.
+	WarnMissingCopies bool
+
+	// IssueBranchTemplate and PRBranchTemplate name the branches
+	// created for GitHub issue and pull request links. Empty values use
+	// the built-in defaults.
+	IssueBranchTemplate string
+	PRBranchTemplate    string
+
+	// PRFetchStrategy is PRFetchCloneURL or PRFetchGHCheckout.
+	PRFetchStrategy string
+
+	// SessionReadyTimeout bounds how long layout commands wait for
+	// their window's shell to start.
+	SessionReadyTimeout time.Duration
 }
 
 // LoadProject reads a project config file by name.
@@ -75,6 +221,10 @@ func LoadProject(name string) (ProjectConfig, error) {
 
 	data, err := os.ReadFile(ProjectConfigPath(name))
 	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return cfg, fmt.Errorf("%w: %s", ErrProjectNotFound, name)
+		}
+
 		return cfg, fmt.Errorf("reading project config %q: %w", name, err)
 	}
 
@@ -99,7 +249,12 @@ func SaveProject(name string, cfg ProjectConfig) error {
 		return fmt.Errorf("marshaling project config: %w", err)
 	}
 
-	content := ProjectSchemaModeline() + "\n" + string(data)
+	mode := globalSchemaMode()
+
+	content, err := withModeline(mode, ProjectSchemaModeline(mode), string(data))
+	if err != nil {
+		return err
+	}
 
 	if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
 		return fmt.Errorf("writing project config %q: %w", name, err)
@@ -134,21 +289,53 @@ func Resolve(name string) (ResolvedConfig, error) {
 	}
 
 	layout := global.Layout
-	if len(proj.Layout) > 0 {
-		layout = proj.Layout
+
+	switch proj.LayoutMode {
+	case "", LayoutReplace:
+		if len(proj.Layout) > 0 {
+			layout = proj.Layout
+		}
+	case LayoutAppend:
+		layout = append(slices.Clip(global.Layout), proj.Layout...)
+	default:
+		return ResolvedConfig{}, fmt.Errorf("layout_mode %q for project %q must be %s or %s", proj.LayoutMode, name, LayoutReplace, LayoutAppend)
+	}
+
+	onSession := global.OnSession
+	if len(proj.OnSession) > 0 {
+		onSession = proj.OnSession
 	}
 
 	rc := ResolvedConfig{
-		Name:        name,
-		Repo:        proj.Repo,
-		WorktreeDir: global.WorktreeDir,
-		Branch:      global.Branch,
-		Copy:        proj.Copy,
-		Symlink:     proj.Symlink,
-		Remove:      proj.Remove,
-		Layout:      layout,
+		Name:           name,
+		Repo:           proj.RepoPath(),
+		WorktreeDir:    global.WorktreeDir,
+		Branch:         global.Branch,
+		WorktreePrefix: proj.WorktreePrefix,
+		Copy:           proj.Copy,
+		Symlink:        proj.Symlink,
+		Remove:         proj.Remove,
+		CopyFrom:       proj.CopyFrom,
+		CopyGitConfig:  proj.CopyGitConfig,
+		Layout:         layout,
+		OnSession:      onSession,
+
+		IssueBranchTemplate: global.IssueBranchTemplate,
+		PRBranchTemplate:    global.PRBranchTemplate,
+		PRFetchStrategy:     global.PRFetchStrategy,
+	}
+
+	if rc.PRFetchStrategy == "" {
+		rc.PRFetchStrategy = PRFetchCloneURL
+	}
+
+	if strings.ContainsAny(proj.WorktreePrefix, `/\`) {
+		return rc, fmt.Errorf("worktree_prefix %q for project %q must not contain a path separator", proj.WorktreePrefix, name)
 	}
 
+	rc.PruneExclude = append(rc.PruneExclude, global.PruneExclude...)
+	rc.PruneExclude = append(rc.PruneExclude, proj.PruneExclude...)
+
 	if proj.WorktreeDir != "" {
 		rc.WorktreeDir = ExpandPath(proj.WorktreeDir)
 	}
@@ -157,14 +344,91 @@ func Resolve(name string) (ResolvedConfig, error) {
 		rc.Branch = proj.Branch
 	}
 
+	// LoadGlobal has already validated the timeout.
+	rc.SessionReadyTimeout, _ = global.ReadyTimeout()
+
+	rc.UpstreamRemote = "origin"
+	if proj.UpstreamRemote != "" {
+		rc.UpstreamRemote = proj.UpstreamRemote
+	}
+
+	rc.AutoSession = true
+	if global.AutoSession != nil {
+		rc.AutoSession = *global.AutoSession
+	}
+
+	if proj.AutoSession != nil {
+		rc.AutoSession = *proj.AutoSession
+	}
+
+	rc.WarnMissingCopies = true
+	if global.WarnMissingCopies != nil {
+		rc.WarnMissingCopies = *global.WarnMissingCopies
+	}
+
+	if proj.WarnMissingCopies != nil {
+		rc.WarnMissingCopies = *proj.WarnMissingCopies
+	}
+
 	return rc, nil
 }
 
+// RepoMissingError reports that a project's repository is no longer at
+// its configured path. It matches ErrRepoMissing with errors.Is, and
+// its message tells the user how to point the project at the new path.
+type RepoMissingError struct {
+	// Project is the project name.
+	Project string
+
+	// Path is the configured repository path that no longer exists.
+	Path string
+}
+
+func (e *RepoMissingError) Error() string {
+	return fmt.Sprintf(
+		"project %s's repo path %s no longer exists; update it with forest project relocate %s <new-path> or forest config project %s",
+		e.Project, e.Path, e.Project, e.Project,
+	)
+}
+
+// Is reports whether target is ErrRepoMissing.
+func (e *RepoMissingError) Is(target error) bool {
+	return target == ErrRepoMissing
+}
+
+// ValidateRepo returns a *RepoMissingError if the repository of project
+// name at path does not exist, for example after it was moved or
+// deleted without updating the project.
+func ValidateRepo(name, path string) error {
+	if _, err := os.Stat(path); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return &RepoMissingError{Project: name, Path: path}
+		}
+
+		return fmt.Errorf("checking project repository: %w", err)
+	}
+
+	return nil
+}
+
+// refreshRemotes disables the remote cache in FindProjectByRemote.
+var refreshRemotes bool
+
+// SetRefreshRemotes makes FindProjectByRemote ignore the origin remotes
+// cached in project configs and query git for every project.
+func SetRefreshRemotes(refresh bool) {
+	refreshRemotes = refresh
+}
+
 // FindProjectByRemote finds a registered project whose git remote URL
 // matches the given "owner/repo" string.
 //
 // Resolution order:
 //
+//  0. Cached origin match: the origin remote recorded in the project
+//     config equals the target NWO. Reading configs avoids running git
+//     for every project, so this is tried first unless
+//     SetRefreshRemotes is enabled.
 //  1. Origin exact match: the project's origin remote normalizes to the
 //     target NWO. This is the strongest identity signal.
 //  2. Origin repo-name match: some non-origin remote matches the target
@@ -173,19 +437,24 @@ func Resolve(name string) (ResolvedConfig, error) {
 //     fork (e.g. user/repo) of the target (org/repo).
 //  3. Any remote match: any remote normalizes to the target NWO. This is
 //     the weakest signal and serves as a fallback.
+//
+// The first step with any match decides. If several projects match in
+// that step, an *AmbiguousProjectError listing them is returned.
 func FindProjectByRemote(nwo string) (string, ResolvedConfig, error) {
 	names, err := ListProjects()
 	if err != nil {
 		return "", ResolvedConfig{}, err
 	}
 
-	_, targetRepo, _ := strings.Cut(nwo, "/")
-	type projectRemotes struct {
-		name    string
-		rc      ResolvedConfig
-		remotes map[string]string // remote name -> normalized NWO
+	if !refreshRemotes {
+		if matches := findCachedRemotes(names, nwo); len(matches) > 0 {
+			m, err := pickMatch(nwo, matches)
+			return m.name, m.rc, err
+		}
 	}
 
+	_, targetRepo, _ := strings.Cut(nwo, "/")
+
 	projects := make([]projectRemotes, 0, len(names))
 	for _, name := range names {
 		rc, err := Resolve(name)
@@ -208,41 +477,99 @@ func FindProjectByRemote(nwo string) (string, ResolvedConfig, error) {
 		projects = append(projects, projectRemotes{name: name, rc: rc, remotes: normalized})
 	}
 
-	// Pass 1: origin exact match.
-	for _, p := range projects {
-		if p.remotes["origin"] == nwo {
-			return p.name, p.rc, nil
-		}
+	passes := []func(p projectRemotes) bool{
+		// Pass 1: origin exact match.
+		func(p projectRemotes) bool {
+			return p.remotes["origin"] == nwo
+		},
+
+		// Pass 2: any remote matches NWO, and origin shares the same
+		// repo name. This picks a personal fork (user/myproject) over
+		// an unrelated repo (corp/myproject-internal) that merely
+		// tracks the target as upstream.
+		func(p projectRemotes) bool {
+			origin := p.remotes["origin"]
+			if origin == "" || !hasRemoteNWO(p.remotes, nwo) {
+				return false
+			}
+
+			_, originRepo, _ := strings.Cut(origin, "/")
+
+			return originRepo == targetRepo
+		},
+
+		// Pass 3: any remote matches NWO.
+		func(p projectRemotes) bool {
+			return hasRemoteNWO(p.remotes, nwo)
+		},
 	}
 
-	// Pass 2: any remote matches NWO, and origin shares the same repo
-	// name. This picks a personal fork (user/myproject) over an
-	// unrelated repo (corp/myproject-internal) that merely tracks
-	// the target as upstream.
-	for _, p := range projects {
-		if !hasRemoteNWO(p.remotes, nwo) {
-			continue
+	for _, match := range passes {
+		var matches []projectMatch
+
+		for _, p := range projects {
+			if match(p) {
+				matches = append(matches, projectMatch{name: p.name, rc: p.rc})
+			}
 		}
 
-		origin := p.remotes["origin"]
-		if origin == "" {
-			continue
+		if len(matches) > 0 {
+			m, err := pickMatch(nwo, matches)
+			return m.name, m.rc, err
 		}
+	}
 
-		_, originRepo, _ := strings.Cut(origin, "/")
+	return "", ResolvedConfig{}, fmt.Errorf("%w with remote matching %q", ErrProjectNotFound, nwo)
+}
 
-		if originRepo == targetRepo {
-			return p.name, p.rc, nil
-		}
+// projectRemotes holds a project's remotes, normalized to "owner/repo".
+type projectRemotes struct {
+	name    string
+	rc      ResolvedConfig
+	remotes map[string]string // remote name -> normalized NWO
+}
+
+// projectMatch is a project that matched a pass of FindProjectByRemote.
+type projectMatch struct {
+	name string
+	rc   ResolvedConfig
+}
+
+// pickMatch returns the only project in matches, or an
+// *AmbiguousProjectError when there are several.
+func pickMatch(nwo string, matches []projectMatch) (projectMatch, error) {
+	if len(matches) == 1 {
+		return matches[0], nil
 	}
 
-	// Pass 3: any remote matches NWO.
-	for _, p := range projects {
-		if hasRemoteNWO(p.remotes, nwo) {
-			return p.name, p.rc, nil
+	err := &AmbiguousProjectError{NWO: nwo}
+	for _, m := range matches {
+		err.Candidates = append(err.Candidates, m.name)
+	}
+
+	return projectMatch{}, err
+}
+
+// findCachedRemotes returns the projects among names whose cached
+// remote equals nwo.
+func findCachedRemotes(names []string, nwo string) []projectMatch {
+	var matches []projectMatch
+
+	for _, name := range names {
+		proj, err := LoadProject(name)
+		if err != nil || proj.Remote != nwo {
+			continue
 		}
+
+		rc, err := Resolve(name)
+		if err != nil {
+			continue
+		}
+
+		matches = append(matches, projectMatch{name: name, rc: rc})
 	}
-	return "", ResolvedConfig{}, fmt.Errorf("no project found with remote matching %q", nwo)
+
+	return matches
 }
 
 // hasRemoteNWO reports whether any remote in the map normalizes to nwo.
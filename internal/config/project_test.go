@@ -1,8 +1,10 @@
 package config
 
 import (
+	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -38,7 +40,19 @@ func TestLoadProject_NotFound(t *testing.T) {
 	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
 
 	_, err := LoadProject("nonexistent")
-	assert.Error(t, err)
+	assert.ErrorIs(t, err, ErrProjectNotFound)
+}
+
+func TestValidateRepo(t *testing.T) {
+	dir := t.TempDir()
+
+	assert.NoError(t, ValidateRepo("myapp", dir))
+
+	missing := filepath.Join(dir, "missing")
+	err := ValidateRepo("myapp", missing)
+	assert.ErrorIs(t, err, ErrRepoMissing)
+	assert.ErrorContains(t, err, "project myapp's repo path "+missing+" no longer exists")
+	assert.ErrorContains(t, err, "forest project relocate myapp <new-path>")
 }
 
 func TestResolve_GlobalDefaults(t *testing.T) {
@@ -85,6 +99,181 @@ func TestResolve_ProjectOverrides(t *testing.T) {
 	assert.Equal(t, cfg.Remove, rc.Remove)
 }
 
+func TestResolve_AutoSession(t *testing.T) {
+	enabled := true
+	disabled := false
+
+	tests := []struct {
+		name    string
+		global  string
+		project *bool
+		want    bool
+	}{
+		{name: "default", global: "", project: nil, want: true},
+		{name: "global off", global: "auto_session: false\n", project: nil, want: false},
+		{name: "project overrides global", global: "auto_session: false\n", project: &enabled, want: true},
+		{name: "project off", global: "", project: &disabled, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			t.Setenv("XDG_CONFIG_HOME", dir)
+			t.Setenv("XDG_DATA_HOME", filepath.Join(dir, "data"))
+
+			require.NoError(t, os.MkdirAll(filepath.Join(dir, "forest"), 0o755))
+			require.NoError(t, os.WriteFile(GlobalConfigPath(), []byte(tt.global), 0o644))
+
+			require.NoError(t, SaveProject("myapp", ProjectConfig{
+				Repo:        "/home/user/repos/myapp",
+				AutoSession: tt.project,
+			}))
+
+			rc, err := Resolve("myapp")
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.want, rc.AutoSession)
+		})
+	}
+}
+
+func TestResolve_WarnMissingCopies(t *testing.T) {
+	enabled := true
+
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("XDG_DATA_HOME", filepath.Join(dir, "data"))
+
+	require.NoError(t, SaveProject("inherits", ProjectConfig{Repo: "/repos/inherits"}))
+	require.NoError(t, SaveProject("overrides", ProjectConfig{
+		Repo:              "/repos/overrides",
+		WarnMissingCopies: &enabled,
+	}))
+
+	rc, err := Resolve("inherits")
+	require.NoError(t, err)
+	assert.True(t, rc.WarnMissingCopies)
+
+	require.NoError(t, os.WriteFile(GlobalConfigPath(), []byte("warn_missing_copies: false\n"), 0o644))
+
+	rc, err = Resolve("inherits")
+	require.NoError(t, err)
+	assert.False(t, rc.WarnMissingCopies)
+
+	rc, err = Resolve("overrides")
+	require.NoError(t, err)
+	assert.True(t, rc.WarnMissingCopies)
+}
+
+func TestResolve_UpstreamRemote(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("XDG_DATA_HOME", filepath.Join(dir, "data"))
+
+	require.NoError(t, SaveProject("inherits", ProjectConfig{Repo: "/repos/inherits"}))
+	require.NoError(t, SaveProject("overrides", ProjectConfig{Repo: "/repos/overrides", UpstreamRemote: "upstream"}))
+
+	rc, err := Resolve("inherits")
+	require.NoError(t, err)
+	assert.Equal(t, "origin", rc.UpstreamRemote)
+
+	rc, err = Resolve("overrides")
+	require.NoError(t, err)
+	assert.Equal(t, "upstream", rc.UpstreamRemote)
+}
+
+func TestResolve_ExpandsPaths(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("XDG_DATA_HOME", filepath.Join(dir, "data"))
+	t.Setenv("FOREST_TEST_DEV", "/srv/dev")
+
+	home, err := os.UserHomeDir()
+	require.NoError(t, err)
+
+	require.NoError(t, SaveProject("tilde", ProjectConfig{Repo: "~/dev/myapp"}))
+	require.NoError(t, SaveProject("env", ProjectConfig{Repo: "$FOREST_TEST_DEV/myapp"}))
+
+	rc, err := Resolve("tilde")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(home, "dev", "myapp"), rc.Repo)
+
+	rc, err = Resolve("env")
+	require.NoError(t, err)
+	assert.Equal(t, "/srv/dev/myapp", rc.Repo)
+
+	require.NoError(t, SaveProject("trees", ProjectConfig{Repo: "/repos/trees", WorktreeDir: "$FOREST_TEST_DEV/trees"}))
+
+	rc, err = Resolve("trees")
+	require.NoError(t, err)
+	assert.Equal(t, "/srv/dev/trees", rc.WorktreeDir)
+
+	// The config keeps the path as written so saving it back does not
+	// replace ~ or the variable.
+	proj, err := LoadProject("env")
+	require.NoError(t, err)
+	assert.Equal(t, "$FOREST_TEST_DEV/myapp", proj.Repo)
+	assert.Equal(t, "/srv/dev/myapp", proj.RepoPath())
+}
+
+func TestResolve_WorktreePrefix(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("XDG_DATA_HOME", filepath.Join(dir, "data"))
+
+	require.NoError(t, SaveProject("prefixed", ProjectConfig{Repo: "/repos/prefixed", WorktreePrefix: "wt-"}))
+	require.NoError(t, SaveProject("nested", ProjectConfig{Repo: "/repos/nested", WorktreePrefix: "wt/"}))
+
+	rc, err := Resolve("prefixed")
+	require.NoError(t, err)
+	assert.Equal(t, "wt-", rc.WorktreePrefix)
+
+	_, err = Resolve("nested")
+	require.ErrorContains(t, err, "must not contain a path separator")
+}
+
+func TestResolve_OnSession(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("XDG_DATA_HOME", filepath.Join(dir, "data"))
+
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "forest"), 0o755))
+	require.NoError(t, os.WriteFile(GlobalConfigPath(), []byte("on_session:\n  - set-option -t {session} @global 1\n"), 0o644))
+
+	require.NoError(t, SaveProject("inherits", ProjectConfig{Repo: "/repos/inherits"}))
+	require.NoError(t, SaveProject("overrides", ProjectConfig{
+		Repo:      "/repos/overrides",
+		OnSession: []string{"set-option -t {session} @project 1"},
+	}))
+
+	rc, err := Resolve("inherits")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"set-option -t {session} @global 1"}, rc.OnSession)
+
+	rc, err = Resolve("overrides")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"set-option -t {session} @project 1"}, rc.OnSession)
+}
+
+func TestResolve_PruneExcludeCombinesGlobalAndProject(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("XDG_DATA_HOME", filepath.Join(dir, "data"))
+
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "forest"), 0o755))
+	require.NoError(t, os.WriteFile(GlobalConfigPath(), []byte("prune_exclude:\n  - staging\n"), 0o644))
+
+	require.NoError(t, SaveProject("myapp", ProjectConfig{
+		Repo:         "/repos/myapp",
+		PruneExclude: []string{"release/*"},
+	}))
+
+	rc, err := Resolve("myapp")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"staging", "release/*"}, rc.PruneExclude)
+}
+
 func TestRemoveProject(t *testing.T) {
 	dir := t.TempDir()
 	t.Setenv("XDG_CONFIG_HOME", dir)
@@ -222,3 +411,87 @@ func TestFindProjectByRemote_NoMatch(t *testing.T) {
 	_, _, err := FindProjectByRemote("acme/unknown")
 	assert.Error(t, err)
 }
+
+func TestFindProjectByRemote_UsesCachedRemote(t *testing.T) {
+	configDir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configDir)
+	t.Setenv("XDG_DATA_HOME", filepath.Join(configDir, "data"))
+
+	// The cached remote no longer matches the repository's origin, so
+	// only the cache can produce this match.
+	repo := initTestRepo(t, "https://github.com/acme/renamed.git")
+	require.NoError(t, SaveProject("widgets", ProjectConfig{Repo: repo, Remote: "acme/widgets"}))
+
+	name, rc, err := FindProjectByRemote("acme/widgets")
+	require.NoError(t, err)
+	assert.Equal(t, "widgets", name)
+	assert.Equal(t, repo, rc.Repo)
+
+	SetRefreshRemotes(true)
+	t.Cleanup(func() { SetRefreshRemotes(false) })
+
+	_, _, err = FindProjectByRemote("acme/widgets")
+	assert.ErrorIs(t, err, ErrProjectNotFound)
+
+	name, _, err = FindProjectByRemote("acme/renamed")
+	require.NoError(t, err)
+	assert.Equal(t, "widgets", name)
+}
+
+func TestFindProjectByRemote_Ambiguous(t *testing.T) {
+	configDir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configDir)
+	t.Setenv("XDG_DATA_HOME", filepath.Join(configDir, "data"))
+
+	// Two forks of org/myproject that both track it as upstream tie in
+	// the fork pass.
+	for _, owner := range []string{"alice", "bob"} {
+		repo := initTestRepo(t, "git@github.com:"+owner+"/myproject.git")
+		addRemote(t, repo, "upstream", "git@github.com:org/myproject.git")
+		registerProject(t, owner, repo)
+	}
+
+	_, _, err := FindProjectByRemote("org/myproject")
+	require.ErrorIs(t, err, ErrAmbiguousProject)
+	assert.ErrorContains(t, err, "several projects match org/myproject (alice, bob); use --project to pick one")
+}
+
+func TestResolve_LayoutMode(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("XDG_DATA_HOME", filepath.Join(dir, "data"))
+
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "forest"), 0o755))
+	require.NoError(t, os.WriteFile(GlobalConfigPath(), []byte("layout:\n  - name: editor\n    command: nvim\n  - name: shell\n    command: \"\"\n"), 0o644))
+
+	global := []Window{{Name: "editor", Command: "nvim"}, {Name: "shell"}}
+	server := Window{Name: "server", Command: "make dev"}
+
+	tests := []struct {
+		name    string
+		project ProjectConfig
+		want    []Window
+	}{
+		{"inherits", ProjectConfig{}, global},
+		{"replace by default", ProjectConfig{Layout: []Window{server}}, []Window{server}},
+		{"replace", ProjectConfig{Layout: []Window{server}, LayoutMode: LayoutReplace}, []Window{server}},
+		{"append", ProjectConfig{Layout: []Window{server}, LayoutMode: LayoutAppend}, append(slices.Clone(global), server)},
+		{"append nothing", ProjectConfig{LayoutMode: LayoutAppend}, global},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.project.Repo = "/repos/layout"
+			require.NoError(t, SaveProject("layout", tt.project))
+
+			rc, err := Resolve("layout")
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, rc.Layout)
+		})
+	}
+
+	require.NoError(t, SaveProject("layout", ProjectConfig{Repo: "/repos/layout", LayoutMode: "merge"}))
+
+	_, err := Resolve("layout")
+	require.ErrorContains(t, err, `layout_mode "merge"`)
+}
@@ -1,15 +1,120 @@
 package config
 
+import (
+	"embed"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
 const schemaBase = "https://raw.githubusercontent.com/mhamza15/forest/main/internal/config/schema"
 
+const (
+	configSchemaFile  = "config.schema.json"
+	projectSchemaFile = "project.schema.json"
+)
+
+// Schema modes control how config files reference their JSON schema.
+const (
+	// SchemaModeURL references the schemas hosted in the forest
+	// repository. This is the default.
+	SchemaModeURL = "url"
+
+	// SchemaModeAbsolute writes the schemas to SchemaDir and
+	// references them by absolute path.
+	SchemaModeAbsolute = "absolute"
+
+	// SchemaModeRelative writes the schemas to SchemaDir and
+	// references them relative to each config file, so the config
+	// directory is portable between machines.
+	SchemaModeRelative = "relative"
+
+	// SchemaModeNone omits the modeline entirely.
+	SchemaModeNone = "none"
+)
+
+//go:embed schema/*.json
+var schemaFS embed.FS
+
 // ConfigSchemaModeline returns the yaml-language-server modeline
-// comment for the global config schema.
-func ConfigSchemaModeline() string {
-	return "# yaml-language-server: $schema=" + schemaBase + "/config.schema.json"
+// comment for the global config schema, or an empty string when mode
+// is SchemaModeNone.
+func ConfigSchemaModeline(mode string) string {
+	return schemaModeline(mode, configSchemaFile, ".")
 }
 
 // ProjectSchemaModeline returns the yaml-language-server modeline
-// comment for the project config schema.
-func ProjectSchemaModeline() string {
-	return "# yaml-language-server: $schema=" + schemaBase + "/project.schema.json"
+// comment for the project config schema, or an empty string when mode
+// is SchemaModeNone.
+func ProjectSchemaModeline(mode string) string {
+	return schemaModeline(mode, projectSchemaFile, "..")
+}
+
+// schemaModeline builds the modeline for the named schema file. rel is
+// the path from the config file's directory back to ConfigDir.
+func schemaModeline(mode, file, rel string) string {
+	var ref string
+
+	switch mode {
+	case SchemaModeNone:
+		return ""
+	case SchemaModeAbsolute:
+		ref = filepath.Join(SchemaDir(), file)
+	case SchemaModeRelative:
+		ref = rel + "/" + filepath.Base(SchemaDir()) + "/" + file
+	default:
+		ref = schemaBase + "/" + file
+	}
+
+	return "# yaml-language-server: $schema=" + ref
+}
+
+// WriteSchemas writes the bundled JSON schemas to SchemaDir,
+// overwriting any existing copies so they match this version of
+// forest.
+func WriteSchemas() error {
+	dir := SchemaDir()
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("creating schema directory: %w", err)
+	}
+
+	for _, file := range []string{configSchemaFile, projectSchemaFile} {
+		data, err := schemaFS.ReadFile("schema/" + file)
+		if err != nil {
+			return fmt.Errorf("reading bundled schema %s: %w", file, err)
+		}
+
+		if err := os.WriteFile(filepath.Join(dir, file), data, 0o644); err != nil {
+			return fmt.Errorf("writing schema %s: %w", file, err)
+		}
+	}
+
+	return nil
+}
+
+// withModeline prefixes content with the modeline for mode, writing
+// local schema copies first when the modeline references them.
+func withModeline(mode, modeline, content string) (string, error) {
+	if modeline == "" {
+		return content, nil
+	}
+
+	if mode == SchemaModeAbsolute || mode == SchemaModeRelative {
+		if err := WriteSchemas(); err != nil {
+			return "", err
+		}
+	}
+
+	return modeline + "\n" + content, nil
+}
+
+// validSchemaMode reports whether mode is a recognized schema mode.
+func validSchemaMode(mode string) bool {
+	switch mode {
+	case SchemaModeURL, SchemaModeAbsolute, SchemaModeRelative, SchemaModeNone:
+		return true
+	default:
+		return false
+	}
 }
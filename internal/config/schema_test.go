@@ -0,0 +1,103 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The modelines reference the schemas hosted from this repository, so
+// each referenced file must exist under internal/config/schema.
+func TestSchemaModelines_ReferenceBundledSchemas(t *testing.T) {
+	for _, modeline := range []string{ConfigSchemaModeline(SchemaModeURL), ProjectSchemaModeline(SchemaModeURL)} {
+		_, ref, ok := strings.Cut(modeline, "$schema=")
+		require.True(t, ok, "modeline without $schema: %s", modeline)
+
+		rel, ok := strings.CutPrefix(ref, schemaBase+"/")
+		require.True(t, ok, "schema %s not under %s", ref, schemaBase)
+
+		_, err := os.Stat(filepath.Join("schema", rel))
+		assert.NoError(t, err, "modeline references missing schema %s", rel)
+	}
+}
+
+func TestSaveProject_WritesSchemaModeline(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	require.NoError(t, SaveProject("myapp", ProjectConfig{Repo: "/repos/myapp"}))
+
+	data, err := os.ReadFile(ProjectConfigPath("myapp"))
+	require.NoError(t, err)
+
+	firstLine, _, _ := strings.Cut(string(data), "\n")
+	assert.Equal(t, ProjectSchemaModeline(SchemaModeURL), firstLine)
+}
+
+func TestSaveProject_RelativeSchemaMode(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	require.NoError(t, os.MkdirAll(ConfigDir(), 0o755))
+	require.NoError(t, os.WriteFile(GlobalConfigPath(), []byte("schema_mode: relative\n"), 0o644))
+
+	require.NoError(t, SaveProject("myapp", ProjectConfig{Repo: "/repos/myapp"}))
+
+	data, err := os.ReadFile(ProjectConfigPath("myapp"))
+	require.NoError(t, err)
+
+	firstLine, _, _ := strings.Cut(string(data), "\n")
+	assert.Equal(t, "# yaml-language-server: $schema=../schema/project.schema.json", firstLine)
+
+	// The relative reference must resolve to the written schema.
+	_, err = os.Stat(filepath.Join(ProjectsDir(), "..", "schema", "project.schema.json"))
+	assert.NoError(t, err)
+}
+
+func TestSaveProject_NoSchemaMode(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	require.NoError(t, os.MkdirAll(ConfigDir(), 0o755))
+	require.NoError(t, os.WriteFile(GlobalConfigPath(), []byte("schema_mode: none\n"), 0o644))
+
+	require.NoError(t, SaveProject("myapp", ProjectConfig{Repo: "/repos/myapp"}))
+
+	data, err := os.ReadFile(ProjectConfigPath("myapp"))
+	require.NoError(t, err)
+
+	assert.NotContains(t, string(data), "yaml-language-server")
+}
+
+func TestSaveProject_InvalidGlobalConfig(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	// Problems with other global settings, or an unknown schema_mode,
+	// must not stop project configs from being saved.
+	require.NoError(t, os.MkdirAll(ConfigDir(), 0o755))
+	require.NoError(t, os.WriteFile(GlobalConfigPath(), []byte("schema_mode: none\npr_fetch_strategy: bogus\n"), 0o644))
+
+	require.NoError(t, SaveProject("myapp", ProjectConfig{Repo: "/repos/myapp"}))
+
+	data, err := os.ReadFile(ProjectConfigPath("myapp"))
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "yaml-language-server")
+
+	require.NoError(t, os.WriteFile(GlobalConfigPath(), []byte("schema_mode: bogus\n"), 0o644))
+	require.NoError(t, SaveProject("myapp", ProjectConfig{Repo: "/repos/myapp"}))
+
+	data, err = os.ReadFile(ProjectConfigPath("myapp"))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "yaml-language-server")
+}
+
+func TestLoadGlobal_InvalidSchemaMode(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	require.NoError(t, os.MkdirAll(ConfigDir(), 0o755))
+	require.NoError(t, os.WriteFile(GlobalConfigPath(), []byte("schema_mode: bogus\n"), 0o644))
+
+	_, err := LoadGlobal()
+	assert.ErrorContains(t, err, "schema_mode")
+}
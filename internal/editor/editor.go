@@ -0,0 +1,135 @@
+// Package editor launches the user's preferred text editor.
+package editor
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// fallback is used when neither $VISUAL nor $EDITOR is set.
+const fallback = "vi"
+
+// Open opens path in the editor named by $VISUAL or else $EDITOR,
+// falling back to vi, and waits for it to exit. The editor inherits
+// the terminal.
+func Open(path string) error {
+	editor := command()
+
+	args, err := split(editor)
+	if err != nil {
+		return fmt.Errorf("parsing editor %q: %w", editor, err)
+	}
+
+	if len(args) == 0 {
+		args = []string{fallback}
+	}
+
+	c := exec.Command(args[0], append(args[1:], path)...)
+	c.Stdin = os.Stdin
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+
+	return c.Run()
+}
+
+// ShellCommand returns a shell command line that opens path in the
+// configured editor, for running inside a tmux window. The editor
+// command is used as written, since it is already shell syntax.
+func ShellCommand(path string) string {
+	return command() + " " + quote(path)
+}
+
+// quote single-quotes s for a POSIX shell.
+func quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// command returns the configured editor command. VISUAL takes
+// precedence over EDITOR, as it names the full-screen editor that
+// forest always needs.
+func command() string {
+	for _, name := range []string{"VISUAL", "EDITOR"} {
+		if editor := os.Getenv(name); editor != "" {
+			return editor
+		}
+	}
+
+	return fallback
+}
+
+// split breaks an editor command such as `code --wait` into words the
+// way a POSIX shell would, honoring single quotes, double quotes and
+// backslash escapes. It does not expand variables or globs.
+func split(s string) ([]string, error) {
+	var (
+		words   []string
+		word    strings.Builder
+		inWord  bool
+		quote   rune
+		escaped bool
+	)
+
+	for _, r := range s {
+		switch {
+		case escaped:
+			if quote == '"' && !strings.ContainsRune("$`\"\\\n", r) {
+				word.WriteRune('\\')
+			}
+
+			word.WriteRune(r)
+			escaped = false
+
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+
+		case r == '\\':
+			// Inside double quotes a backslash only escapes characters
+			// that are special there; elsewhere it escapes anything.
+			escaped = true
+			inWord = true
+
+		case quote == '"':
+			if r == '"' {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+
+		case r == '\'' || r == '"':
+			quote = r
+			inWord = true
+
+		case r == ' ' || r == '\t' || r == '\n':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+
+	if quote != 0 {
+		return nil, errors.New("unterminated quote")
+	}
+
+	if escaped {
+		return nil, errors.New("trailing backslash")
+	}
+
+	if inWord {
+		words = append(words, word.String())
+	}
+
+	return words, nil
+}
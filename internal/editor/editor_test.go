@@ -0,0 +1,57 @@
+package editor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"vim", []string{"vim"}},
+		{"code --wait", []string{"code", "--wait"}},
+		{"  emacsclient   -c  ", []string{"emacsclient", "-c"}},
+		{`"/Applications/Sublime Text.app/subl" -w`, []string{"/Applications/Sublime Text.app/subl", "-w"}},
+		{`'my editor' --flag='a b'`, []string{"my editor", "--flag=a b"}},
+		{`nvim\ qt -f`, []string{"nvim qt", "-f"}},
+		{`vim ""`, []string{"vim", ""}},
+		{`"C:\bin\ed" "say \"hi\""`, []string{`C:\bin\ed`, `say "hi"`}},
+		{"", nil},
+	}
+
+	for _, tt := range tests {
+		got, err := split(tt.in)
+		require.NoError(t, err, tt.in)
+		assert.Equal(t, tt.want, got, tt.in)
+	}
+}
+
+func TestSplit_Errors(t *testing.T) {
+	_, err := split(`code "--wait`)
+	assert.ErrorContains(t, err, "unterminated quote")
+
+	_, err = split(`vim \`)
+	assert.ErrorContains(t, err, "trailing backslash")
+}
+
+func TestCommand(t *testing.T) {
+	t.Setenv("VISUAL", "")
+	t.Setenv("EDITOR", "")
+	assert.Equal(t, "vi", command())
+
+	t.Setenv("EDITOR", "nano")
+	assert.Equal(t, "nano", command())
+
+	t.Setenv("VISUAL", "emacsclient -c")
+	assert.Equal(t, "emacsclient -c", command())
+}
+
+func TestShellCommand(t *testing.T) {
+	t.Setenv("VISUAL", "code --wait")
+	assert.Equal(t, "code --wait '/tmp/my tree'", ShellCommand("/tmp/my tree"))
+	assert.Equal(t, `code --wait 'it'\''s'`, ShellCommand("it's"))
+}
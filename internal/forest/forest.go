@@ -9,9 +9,11 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/mhamza15/forest/internal/config"
 	"github.com/mhamza15/forest/internal/git"
+	"github.com/mhamza15/forest/internal/network"
 	"github.com/mhamza15/forest/internal/tmux"
 )
 
@@ -39,10 +41,22 @@ type AddTreeResult struct {
 	// files into the new worktree.
 	SymlinkWarnings []string
 
+	// MissingWarnings lists copy and symlink entries that were not
+	// found in the source. warn_missing_copies silences only these.
+	MissingWarnings []string
+
 	// RemoveWarnings contains any warnings generated while removing
 	// configured files from the new worktree.
 	RemoveWarnings []string
 
+	// ConfigWarnings contains any errors copying copy_git_config
+	// values into the new worktree.
+	ConfigWarnings []string
+
+	// BaseWarning is set when a new branch was created off a base
+	// branch that is behind its upstream.
+	BaseWarning string
+
 	// Fetched is true if the branch was fetched from a remote
 	// because it did not exist locally.
 	Fetched bool
@@ -52,16 +66,253 @@ type AddTreeResult struct {
 	Remote string
 }
 
-// AddTree creates a worktree for the given project and branch. If the
-// worktree already exists, it reuses it. The caller is responsible for
-// creating a tmux session and switching to it.
+// AddTreeOptions controls optional behavior when creating a worktree.
+// The zero value creates a fully checked out worktree.
+type AddTreeOptions struct {
+	// NoCheckout creates the worktree without populating its files.
+	NoCheckout bool
+
+	// ForceBase creates a missing branch off the configured base
+	// branch instead of fetching and tracking a remote branch of the
+	// same name.
+	ForceBase bool
+
+	// CopyFrom overrides the configured copy_from branch for this
+	// worktree. Unlike the config field, the branch's worktree must
+	// exist.
+	CopyFrom string
+
+	// UpdateBase fetches the base branch's upstream and fast-forwards
+	// the base to it before creating a new branch off it.
+	UpdateBase bool
+
+	// Remote is the only remote a missing branch is fetched from, such
+	// as the remote of a GitHub branch link's repository. When empty,
+	// each remote is tried in order.
+	Remote string
+
+	// Checkout creates a missing branch by running in a worktree
+	// detached at the base, instead of forest fetching or branching
+	// it. It is used for gh pr checkout and must leave the worktree on
+	// the branch.
+	Checkout func(wtPath string) error
+}
+
+// RegisterProject validates that repoPath is a git repository and
+// writes a project config for it, returning the project name and the
+// absolute repo path. An empty name defaults to the repository's
+// directory name. The remote's default branch is stored as the
+// project's base branch when it can be detected.
+func RegisterProject(repoPath, name string) (string, string, error) {
+	absPath, err := filepath.Abs(config.ExpandPath(repoPath))
+	if err != nil {
+		return "", "", fmt.Errorf("resolving path: %w", err)
+	}
+
+	if err := git.ValidateRepo(absPath); err != nil {
+		return "", "", err
+	}
+
+	if name == "" {
+		name = filepath.Base(absPath)
+	}
+
+	cfg := config.ProjectConfig{
+		Repo:   absPath,
+		Remote: originRemote(absPath),
+	}
+
+	// Store the remote's default branch so that new worktrees and
+	// prune use it rather than the global default, which may not
+	// match. Repos without an origin HEAD keep the global default.
+	if branch, err := git.RemoteDefaultBranch(absPath, "origin"); err == nil {
+		cfg.Branch = branch
+	}
+
+	if err := config.SaveProject(name, cfg); err != nil {
+		return "", "", err
+	}
+
+	return name, absPath, nil
+}
+
+// RelocateProject points project name at a repository that has moved
+// to repoPath, returning the absolute path. The new path must be a git
+// repository. Linked worktrees are repaired so that they find the
+// repository at its new location.
+func RelocateProject(name, repoPath string) (string, error) {
+	cfg, err := config.LoadProject(name)
+	if err != nil {
+		return "", err
+	}
+
+	absPath, err := filepath.Abs(config.ExpandPath(repoPath))
+	if err != nil {
+		return "", fmt.Errorf("resolving path: %w", err)
+	}
+
+	if err := git.ValidateRepo(absPath); err != nil {
+		return "", err
+	}
+
+	if err := git.Repair(absPath); err != nil {
+		return "", err
+	}
+
+	cfg.Repo = absPath
+	cfg.Remote = originRemote(absPath)
+
+	if err := config.SaveProject(name, cfg); err != nil {
+		return "", err
+	}
+
+	return absPath, nil
+}
+
+// RefreshProjectRemote records the current origin remote of project
+// name in its config, so that project inference does not act on a
+// stale cached remote. It returns the remote, which is empty when the
+// repository has no origin; the config is then left unchanged.
+func RefreshProjectRemote(name string) (string, error) {
+	cfg, err := config.LoadProject(name)
+	if err != nil {
+		return "", err
+	}
+
+	if err := config.ValidateRepo(name, cfg.RepoPath()); err != nil {
+		return "", err
+	}
+
+	remote := originRemote(cfg.RepoPath())
+	if remote == "" || remote == cfg.Remote {
+		return remote, nil
+	}
+
+	if err := config.SetProjectValue(name, "remote", remote); err != nil {
+		return "", err
+	}
+
+	return remote, nil
+}
+
+// maxSearchDepth limits how many directory levels below the search
+// root FindRepo descends.
+const maxSearchDepth = 4
+
+// FindRepo searches dir for a git repository whose origin normalizes
+// to nwo and returns its path. When no origin matches, a repository
+// with any other remote normalizing to nwo (such as a fork's upstream)
+// is returned instead. Linked worktrees, whose .git is a file, are
+// skipped so that only main repositories match, and the search does
+// not descend into repositories or more than maxSearchDepth levels
+// below dir.
+func FindRepo(dir, nwo string) (string, error) {
+	root, err := filepath.Abs(config.ExpandPath(dir))
+	if err != nil {
+		return "", fmt.Errorf("resolving path: %w", err)
+	}
+
+	var found, fallback string
+
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// Unreadable directories are skipped rather than ending
+			// the search.
+			if d != nil && d.IsDir() && path != root {
+				return fs.SkipDir
+			}
+
+			return err
+		}
+
+		if !d.IsDir() {
+			return nil
+		}
+
+		rel, _ := filepath.Rel(root, path)
+		if rel != "." && strings.Count(rel, string(filepath.Separator)) >= maxSearchDepth {
+			return fs.SkipDir
+		}
+
+		info, err := os.Stat(filepath.Join(path, ".git"))
+		if err != nil || !info.IsDir() {
+			return nil
+		}
+
+		if originRemote(path) == nwo {
+			found = path
+			return fs.SkipAll
+		}
+
+		if fallback == "" && hasRemote(path, nwo) {
+			fallback = path
+		}
+
+		return fs.SkipDir
+	})
+	if err != nil {
+		return "", fmt.Errorf("searching %s: %w", root, err)
+	}
+
+	if found == "" {
+		found = fallback
+	}
+
+	if found == "" {
+		return "", fmt.Errorf("no repository with remote %s found in %s", nwo, root)
+	}
+
+	return found, nil
+}
+
+// hasRemote reports whether any remote of repoPath normalizes to nwo.
+func hasRemote(repoPath, nwo string) bool {
+	remotes, err := git.Remotes(repoPath)
+	if err != nil {
+		return false
+	}
+
+	for _, remote := range remotes {
+		url, err := git.RemoteURL(repoPath, remote)
+		if err == nil && git.NormalizeRemoteURL(url) == nwo {
+			return true
+		}
+	}
+
+	return false
+}
+
+// originRemote returns the normalized "owner/repo" form of the origin
+// remote of repoPath, or an empty string if it has none.
+func originRemote(repoPath string) string {
+	url, err := git.RemoteURL(repoPath, "origin")
+	if err != nil {
+		return ""
+	}
+
+	return git.NormalizeRemoteURL(url)
+}
+
+// AddTree creates a worktree for the given project and branch using
+// default options. See AddTreeWithOptions.
 func AddTree(rc config.ResolvedConfig, branch string) (AddTreeResult, error) {
+	return AddTreeWithOptions(rc, branch, AddTreeOptions{})
+}
+
+// AddTreeWithOptions creates a worktree for the given project and
+// branch. If the worktree already exists, it reuses it. The caller is
+// responsible for creating a tmux session and switching to it.
+func AddTreeWithOptions(rc config.ResolvedConfig, branch string, opts AddTreeOptions) (AddTreeResult, error) {
 	sessionName := tmux.SessionName(rc.Name, branch)
 
 	result := AddTreeResult{
 		SessionName: sessionName,
 	}
 
+	if err := config.ValidateRepo(rc.Name, rc.Repo); err != nil {
+		return result, err
+	}
+
 	// Check if a worktree for this branch already exists (at any
 	// path, including paths created before the SafeBranchDir
 	// convention).
@@ -75,11 +326,40 @@ func AddTree(rc config.ResolvedConfig, branch string) (AddTreeResult, error) {
 		return result, nil
 	}
 
+	// An existing branch is used as is, whatever its name; only a
+	// branch that may be created here must have a valid name.
+	if !git.BranchExists(rc.Repo, branch) {
+		if err := git.ValidateBranchName(rc.Repo, branch); err != nil {
+			return result, err
+		}
+	}
+
+	if opts.CopyFrom != "" {
+		if git.FindByBranch(rc.Repo, opts.CopyFrom) == nil {
+			return result, fmt.Errorf("no worktree found for copy source branch %q in project %q", opts.CopyFrom, rc.Name)
+		}
+
+		rc.CopyFrom = opts.CopyFrom
+	}
+
+	if opts.Checkout != nil && !git.BranchExists(rc.Repo, branch) {
+		return addCheckoutTree(rc, branch, opts)
+	}
+
 	// If the branch does not exist locally, fetch the latest from
 	// the remote so that git can create a worktree tracking it.
-	if !git.BranchExists(rc.Repo, branch) {
-		remote, err := git.FetchRemoteBranch(rc.Repo, branch)
-		if err == nil {
+	if !opts.ForceBase && !git.BranchExists(rc.Repo, branch) {
+		remote := opts.Remote
+
+		var err error
+		if remote != "" {
+			err = git.FetchRemoteBranchFrom(rc.Repo, remote, branch)
+		} else {
+			remote, err = git.FetchRemoteBranch(rc.Repo, branch)
+		}
+
+		switch {
+		case err == nil:
 			result.Fetched = true
 			result.Remote = remote
 
@@ -87,10 +367,33 @@ func AddTree(rc config.ResolvedConfig, branch string) (AddTreeResult, error) {
 				slog.String("branch", branch),
 				slog.String("remote", remote),
 			)
+
+		case errors.Is(err, git.ErrBranchNotFound), errors.Is(err, git.ErrNoRemotes):
+			// A brand new branch: create it off the base below.
+
+		case errors.Is(err, network.ErrTimeout):
+			// Falling back to a new branch off the base would hide
+			// that the remote branch may exist.
+			return result, err
 		}
 	}
 
-	wtPath := filepath.Join(rc.WorktreeDir, rc.Name, git.SafeBranchDir(branch))
+	base := git.ResolveBase(rc.Repo, rc.Branch)
+	wtPath := treePath(rc, branch)
+
+	// Only a brand new branch starts from the base, so only then does
+	// a stale base matter, and only then is the base recorded.
+	newBranch := !result.Fetched && !git.BranchExists(rc.Repo, branch)
+
+	if newBranch {
+		if opts.UpdateBase {
+			if err := updateBase(rc.Repo, base); err != nil {
+				return result, err
+			}
+		} else {
+			result.BaseWarning = staleBaseWarning(rc.Repo, base)
+		}
+	}
 
 	pathWarnings, err := prepareWorktreePath(rc.Repo, wtPath)
 	if err != nil {
@@ -99,38 +402,314 @@ func AddTree(rc config.ResolvedConfig, branch string) (AddTreeResult, error) {
 
 	result.PathWarnings = pathWarnings
 
-	slog.Debug("creating worktree", slog.String("path", wtPath), slog.String("base", rc.Branch))
+	slog.Debug("creating worktree", slog.String("path", wtPath), slog.String("base", base))
 
 	if err := os.MkdirAll(filepath.Dir(wtPath), 0o755); err != nil {
 		return result, fmt.Errorf("creating worktree parent dir: %w", err)
 	}
 
-	if err := git.Add(rc.Repo, wtPath, branch, rc.Branch); err != nil {
+	addOpts := git.AddOptions{
+		NoCheckout: opts.NoCheckout,
+		NoTrack:    opts.ForceBase,
+	}
+
+	// Track the remote the branch was fetched from, even when another
+	// remote has a tracking ref of the same name.
+	if result.Fetched {
+		addOpts.Track = result.Remote + "/" + branch
+	}
+
+	if err := git.AddWithOptions(rc.Repo, wtPath, branch, base, addOpts); err != nil {
 		return result, err
 	}
 
 	result.Created = true
 	result.WorktreePath = wtPath
 
-	if len(rc.Copy) > 0 {
-		result.CopyWarnings = git.CopyFiles(rc.Repo, wtPath, rc.Copy)
+	if newBranch {
+		RecordBranchBase(rc.Repo, branch, base)
 	}
 
-	if len(rc.Symlink) > 0 {
-		result.SymlinkWarnings = git.SymlinkFiles(rc.Repo, wtPath, rc.Symlink)
+	populateWorktree(rc, wtPath, &result)
+
+	if err := git.ConfigureWorktreePush(rc.Repo, wtPath, branch); err != nil {
+		return result, fmt.Errorf("configuring worktree push: %w", err)
 	}
 
-	if len(rc.Remove) > 0 {
-		result.RemoveWarnings = git.RemoveFiles(wtPath, rc.Remove)
+	return result, nil
+}
+
+// addCheckoutTree creates a worktree for branch by detaching it at the
+// base and running opts.Checkout in it, then populates it. The worktree
+// is removed again if the checkout fails or does not produce the
+// branch. An existing detached worktree at the same path is left alone.
+func addCheckoutTree(rc config.ResolvedConfig, branch string, opts AddTreeOptions) (AddTreeResult, error) {
+	base := git.ResolveBase(rc.Repo, rc.Branch)
+
+	result, rc, err := createDetachedTree(rc, branch, base, opts)
+	if err != nil {
+		return result, err
 	}
 
-	if err := git.ConfigureWorktreePush(rc.Repo, wtPath, branch); err != nil {
+	if !result.Created {
+		return result, fmt.Errorf("a detached worktree already exists at %s; remove it before checking out %s", result.WorktreePath, branch)
+	}
+
+	result.SessionName = tmux.SessionName(rc.Name, branch)
+
+	err = opts.Checkout(result.WorktreePath)
+	if err == nil && !git.BranchExists(rc.Repo, branch) {
+		err = fmt.Errorf("%w: %s was not created by the checkout", git.ErrBranchNotFound, branch)
+	}
+
+	if err != nil {
+		if rmErr := git.ForceRemove(rc.Repo, result.WorktreePath); rmErr != nil {
+			slog.Debug("could not remove worktree after failed checkout", slog.Any("err", rmErr))
+		}
+
+		return result, err
+	}
+
+	populateWorktree(rc, result.WorktreePath, &result)
+	RecordBranchBase(rc.Repo, branch, base)
+
+	if err := git.ConfigureWorktreePush(rc.Repo, result.WorktreePath, branch); err != nil {
 		return result, fmt.Errorf("configuring worktree push: %w", err)
 	}
 
 	return result, nil
 }
 
+// staleBaseWarning returns a warning when the local base branch is
+// behind its upstream tracking ref, or an empty string otherwise. The
+// tracking ref is not fetched, so this only catches commits that git
+// already knows about.
+func staleBaseWarning(repoPath, base string) string {
+	upstream := git.Upstream(repoPath, base)
+	if upstream == "" {
+		return ""
+	}
+
+	_, behind, err := git.AheadBehind(repoPath, base, upstream)
+	if err != nil {
+		slog.Debug("could not compare base with upstream", slog.String("base", base), slog.Any("err", err))
+		return ""
+	}
+
+	if behind == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("base branch %s is %d commit(s) behind %s; pull it or use --update-base", base, behind, upstream)
+}
+
+// updateBase fast-forwards the local base branch to its upstream.
+// Bases that are not local branches, such as origin/HEAD, are left
+// alone.
+func updateBase(repoPath, base string) error {
+	if !git.BranchExists(repoPath, base) {
+		return nil
+	}
+
+	return git.FastForward(repoPath, base)
+}
+
+// AddDetachedTree creates a worktree for the given project with a
+// detached HEAD at ref, for inspecting a commit without creating a
+// branch. The worktree directory and session name are derived from
+// ref. If a detached worktree for ref already exists, it is reused.
+func AddDetachedTree(rc config.ResolvedConfig, ref string, opts AddTreeOptions) (AddTreeResult, error) {
+	return addDetachedTree(rc, ref, ref, opts)
+}
+
+// TagTreeName returns the name used for the worktree directory and
+// session of a tag worktree, e.g. "tag-v1.2.0".
+func TagTreeName(tag string) string {
+	return "tag-" + git.SafeBranchDir(tag)
+}
+
+// AddTagTree creates a detached worktree at the given tag, named by
+// TagTreeName, for reviewing a release alongside ongoing work. It
+// fails if the tag does not exist.
+func AddTagTree(rc config.ResolvedConfig, tag string, opts AddTreeOptions) (AddTreeResult, error) {
+	if err := config.ValidateRepo(rc.Name, rc.Repo); err != nil {
+		return AddTreeResult{}, err
+	}
+
+	if !git.TagExists(rc.Repo, tag) {
+		return AddTreeResult{}, fmt.Errorf("tag %q not found in project %q", tag, rc.Name)
+	}
+
+	return addDetachedTree(rc, TagTreeName(tag), "refs/tags/"+tag, opts)
+}
+
+// PinTreeName returns the name used for the worktree directory and
+// session of a worktree pinned to a pull request's head commit, e.g.
+// "pr-42-1a2b3c4".
+func PinTreeName(number int, sha string) string {
+	return fmt.Sprintf("pr-%d-%s", number, sha[:min(len(sha), 7)])
+}
+
+// AddPinTree creates a detached worktree at a pull request's head
+// commit, named by PinTreeName. It is separate from any worktree of the
+// PR's branch, which stays on its branch. The commit must already be
+// in the repository.
+func AddPinTree(rc config.ResolvedConfig, number int, sha string, opts AddTreeOptions) (AddTreeResult, error) {
+	if err := config.ValidateRepo(rc.Name, rc.Repo); err != nil {
+		return AddTreeResult{}, err
+	}
+
+	if !git.CommitExists(rc.Repo, sha) {
+		return AddTreeResult{}, fmt.Errorf("commit %s not found in project %q", sha, rc.Name)
+	}
+
+	return addDetachedTree(rc, PinTreeName(number, sha), sha, opts)
+}
+
+// treePath returns where a new worktree named name is created. Branch
+// worktrees are found by branch through git rather than by this path,
+// so changing worktree_prefix does not lose track of existing ones.
+func treePath(rc config.ResolvedConfig, name string) string {
+	return filepath.Join(rc.WorktreeDir, rc.Name, rc.WorktreePrefix+git.SafeBranchDir(name))
+}
+
+// addDetachedTree creates or reuses a detached worktree named name
+// with its HEAD at ref.
+func addDetachedTree(rc config.ResolvedConfig, name, ref string, opts AddTreeOptions) (AddTreeResult, error) {
+	result, rc, err := createDetachedTree(rc, name, ref, opts)
+	if err != nil || !result.Created {
+		return result, err
+	}
+
+	populateWorktree(rc, result.WorktreePath, &result)
+
+	return result, nil
+}
+
+// createDetachedTree creates or reuses a detached worktree named name
+// with its HEAD at ref, without populating it. It returns rc with
+// opts.CopyFrom applied, for populating the worktree later.
+func createDetachedTree(rc config.ResolvedConfig, name, ref string, opts AddTreeOptions) (AddTreeResult, config.ResolvedConfig, error) {
+	result := AddTreeResult{
+		SessionName: tmux.SessionName(rc.Name, name),
+	}
+
+	if err := config.ValidateRepo(rc.Name, rc.Repo); err != nil {
+		return result, rc, err
+	}
+
+	wtPath := treePath(rc, name)
+
+	if existing := git.FindByPath(rc.Repo, wtPath); existing != nil && existing.Branch == "" {
+		result.WorktreePath = existing.Path
+		return result, rc, nil
+	}
+
+	if opts.CopyFrom != "" {
+		if git.FindByBranch(rc.Repo, opts.CopyFrom) == nil {
+			return result, rc, fmt.Errorf("no worktree found for copy source branch %q in project %q", opts.CopyFrom, rc.Name)
+		}
+
+		rc.CopyFrom = opts.CopyFrom
+	}
+
+	pathWarnings, err := prepareWorktreePath(rc.Repo, wtPath)
+	if err != nil {
+		return result, rc, err
+	}
+
+	result.PathWarnings = pathWarnings
+
+	slog.Debug("creating detached worktree", slog.String("path", wtPath), slog.String("ref", ref))
+
+	if err := os.MkdirAll(filepath.Dir(wtPath), 0o755); err != nil {
+		return result, rc, fmt.Errorf("creating worktree parent dir: %w", err)
+	}
+
+	if err := git.AddDetached(rc.Repo, wtPath, ref); err != nil {
+		return result, rc, err
+	}
+
+	result.Created = true
+	result.WorktreePath = wtPath
+
+	return result, rc, nil
+}
+
+// populateWorktree applies the project's copy, symlink, remove and
+// copy_git_config settings to a newly created worktree, collecting
+// any warnings in result.
+func populateWorktree(rc config.ResolvedConfig, wtPath string, result *AddTreeResult) {
+	if len(rc.Copy) > 0 || len(rc.Symlink) > 0 {
+		source, warning := copySource(rc, wtPath)
+		if warning != "" {
+			result.CopyWarnings = append(result.CopyWarnings, warning)
+		}
+
+		if len(rc.Copy) > 0 {
+			missing, warnings := git.CopyFiles(source, wtPath, rc.Copy)
+			result.MissingWarnings = append(result.MissingWarnings, missing...)
+			result.CopyWarnings = append(result.CopyWarnings, warnings...)
+		}
+
+		if len(rc.Symlink) > 0 {
+			missing, warnings := git.SymlinkFiles(source, wtPath, rc.Symlink)
+			result.MissingWarnings = append(result.MissingWarnings, missing...)
+			result.SymlinkWarnings = warnings
+		}
+	}
+
+	if len(rc.Remove) > 0 {
+		result.RemoveWarnings = git.RemoveFiles(wtPath, rc.Remove)
+	}
+
+	if err := git.CopyConfig(rc.Repo, wtPath, rc.CopyGitConfig); err != nil {
+		result.ConfigWarnings = append(result.ConfigWarnings, fmt.Sprintf("copy_git_config: %s", err))
+	}
+}
+
+// copySource returns the directory that copy and symlink entries are
+// read from: the worktree of rc.CopyFrom when set, otherwise the repo
+// root. If the configured worktree does not exist, or is the new
+// worktree at wtPath itself, the repo root is used and a warning is
+// returned.
+func copySource(rc config.ResolvedConfig, wtPath string) (string, string) {
+	if rc.CopyFrom == "" {
+		return rc.Repo, ""
+	}
+
+	wt := git.FindByBranch(rc.Repo, rc.CopyFrom)
+	if wt == nil {
+		return rc.Repo, fmt.Sprintf("copy_from: no worktree for branch %q, copying from repo root", rc.CopyFrom)
+	}
+
+	if filepath.Clean(wt.Path) == filepath.Clean(wtPath) {
+		return rc.Repo, fmt.Sprintf("copy_from: branch %q is the new worktree itself, copying from repo root", rc.CopyFrom)
+	}
+
+	return wt.Path, ""
+}
+
+// RecordBranchBase stores the base branch for branch unless one was
+// already recorded, so that prune can check merge status against the
+// base the branch was actually created from. Failures are only logged:
+// prune falls back to the project's base branch without one.
+func RecordBranchBase(repoPath, branch, base string) {
+	existing, err := git.GetBranchBase(repoPath, branch)
+	if err != nil {
+		slog.Debug("could not read branch base", slog.String("branch", branch), slog.Any("err", err))
+		return
+	}
+
+	if existing != "" {
+		return
+	}
+
+	if err := git.SetBranchBase(repoPath, branch, base); err != nil {
+		slog.Debug("could not record branch base", slog.String("branch", branch), slog.Any("err", err))
+	}
+}
+
 func prepareWorktreePath(repoPath, worktreePath string) ([]string, error) {
 	if existing := git.FindByPath(repoPath, worktreePath); existing != nil {
 		return nil, fmt.Errorf("worktree path %q is already in use by %s", worktreePath, describeWorktree(existing))
@@ -144,6 +723,12 @@ func prepareWorktreePath(repoPath, worktreePath string) ([]string, error) {
 		return nil, fmt.Errorf("statting worktree path %q: %w", worktreePath, err)
 	}
 
+	// Moving aside another repository's worktree would break it, for
+	// example when two projects share a custom worktree_dir.
+	if other, ok := git.ForeignWorktree(repoPath, worktreePath); ok {
+		return nil, fmt.Errorf("worktree path %q is already in use by another repository (%s); give the projects distinct worktree_dir values", worktreePath, other)
+	}
+
 	backupPath, err := moveAsideWorktreePath(worktreePath)
 	if err != nil {
 		return nil, err
@@ -193,29 +778,63 @@ func nextRecoveredWorktreePath(worktreePath string) (string, error) {
 }
 
 // OpenSession creates a tmux session for an existing worktree if one
-// does not already exist, and applies the configured layout. It does
-// not switch to the session.
-func OpenSession(rc config.ResolvedConfig, branch string, wtPath string) error {
+// does not already exist, applies the configured layout, and then runs
+// the configured on_session tmux commands. It does not switch to the
+// session. It reports whether a new session was created, so callers
+// can stay quiet when the session was already set up.
+func OpenSession(rc config.ResolvedConfig, branch string, wtPath string) (bool, error) {
 	sessionName := tmux.SessionName(rc.Name, branch)
 
 	if tmux.SessionExists(sessionName) {
-		return nil
+		return false, nil
 	}
 
-	if err := tmux.NewSession(sessionName, wtPath); err != nil {
-		return err
+	// The first layout window is the session's initial window, so a
+	// shell for it has to be given when the session is created.
+	var firstShell string
+	if len(rc.Layout) > 0 {
+		firstShell = rc.Layout[0].Shell
 	}
 
-	if len(rc.Layout) == 0 {
-		return nil
+	var err error
+	if firstShell != "" {
+		err = tmux.NewSessionCmd(sessionName, wtPath, firstShell)
+	} else {
+		err = tmux.NewSession(sessionName, wtPath)
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	if err := tmux.TagSession(sessionName, rc.Name, branch, wtPath); err != nil {
+		return true, err
+	}
+
+	if len(rc.Layout) > 0 {
+		windows := make([]tmux.LayoutWindow, len(rc.Layout))
+		for i, w := range rc.Layout {
+			windows[i] = tmux.LayoutWindow{Name: w.Name, Command: w.Command, Shell: w.Shell}
+		}
+
+		if err := tmux.ApplyLayout(sessionName, wtPath, windows, rc.SessionReadyTimeout); err != nil {
+			return true, err
+		}
 	}
 
-	windows := make([]tmux.LayoutWindow, len(rc.Layout))
-	for i, w := range rc.Layout {
-		windows[i] = tmux.LayoutWindow{Name: w.Name, Command: w.Command}
+	replacer := strings.NewReplacer(
+		"{session}", sessionName,
+		"{project}", rc.Name,
+		"{branch}", branch,
+	)
+
+	for _, line := range rc.OnSession {
+		if err := tmux.RunCommand(replacer.Replace(line)); err != nil {
+			return true, fmt.Errorf("running on_session command: %w", err)
+		}
 	}
 
-	return tmux.ApplyLayout(sessionName, wtPath, windows)
+	return true, nil
 }
 
 // RemoveTree removes a worktree and its tmux session. If force is
@@ -223,6 +842,10 @@ func OpenSession(rc config.ResolvedConfig, branch string, wtPath string) error {
 // git.ErrWorktreeDirty (wrapped) if the worktree has modifications
 // and force is false.
 func RemoveTree(rc config.ResolvedConfig, branch string, force bool) error {
+	if err := config.ValidateRepo(rc.Name, rc.Repo); err != nil {
+		return err
+	}
+
 	existing := git.FindByBranch(rc.Repo, branch)
 	if existing == nil {
 		return fmt.Errorf("no worktree found for branch %q in project %q", branch, rc.Name)
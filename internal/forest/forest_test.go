@@ -12,6 +12,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/mhamza15/forest/internal/config"
+	"github.com/mhamza15/forest/internal/git"
 )
 
 func TestAddTree_RemovesConfiguredFiles(t *testing.T) {
@@ -46,6 +47,128 @@ func TestAddTree_RemovesConfiguredFiles(t *testing.T) {
 	assert.Equal(t, "S .env", flags)
 }
 
+func TestAddTree_SeparatesMissingCopyWarnings(t *testing.T) {
+	repo := initTestRepo(t)
+
+	rc := config.ResolvedConfig{
+		Name:        "demo",
+		Repo:        repo,
+		WorktreeDir: t.TempDir(),
+		Branch:      "main",
+		Copy:        []string{"missing.env"},
+		Symlink:     []string{"missing-cache"},
+		CopyFrom:    "no-such-branch",
+	}
+
+	result, err := AddTree(rc, "feature")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"copy: missing.env not found, skipping",
+		"symlink: missing-cache not found, skipping",
+	}, result.MissingWarnings)
+	assert.Equal(t, []string{`copy_from: no worktree for branch "no-such-branch", copying from repo root`}, result.CopyWarnings)
+	assert.Empty(t, result.SymlinkWarnings)
+}
+
+func TestAddTree_CopiesFromConfiguredWorktree(t *testing.T) {
+	repo := initTestRepo(t)
+
+	rc := config.ResolvedConfig{
+		Name:        "demo",
+		Repo:        repo,
+		WorktreeDir: t.TempDir(),
+		Branch:      "main",
+	}
+
+	template, err := AddTree(rc, "template")
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(filepath.Join(template.WorktreePath, ".env"), []byte("SECRET=local"), 0o644))
+
+	rc.Copy = []string{".env"}
+	rc.CopyFrom = "template"
+
+	result, err := AddTree(rc, "feature")
+	require.NoError(t, err)
+
+	assert.Empty(t, result.CopyWarnings)
+
+	data, err := os.ReadFile(filepath.Join(result.WorktreePath, ".env"))
+	require.NoError(t, err)
+	assert.Equal(t, "SECRET=local", string(data))
+}
+
+func TestAddTree_CopyFromMissingWorktreeFallsBackToRepo(t *testing.T) {
+	repo := initTestRepo(t)
+
+	require.NoError(t, os.WriteFile(filepath.Join(repo, ".env"), []byte("SECRET=repo"), 0o644))
+
+	rc := config.ResolvedConfig{
+		Name:        "demo",
+		Repo:        repo,
+		WorktreeDir: t.TempDir(),
+		Branch:      "main",
+		Copy:        []string{".env"},
+		CopyFrom:    "missing",
+	}
+
+	result, err := AddTree(rc, "feature")
+	require.NoError(t, err)
+
+	require.Len(t, result.CopyWarnings, 1)
+	assert.Contains(t, result.CopyWarnings[0], "missing")
+
+	data, err := os.ReadFile(filepath.Join(result.WorktreePath, ".env"))
+	require.NoError(t, err)
+	assert.Equal(t, "SECRET=repo", string(data))
+}
+
+func TestAddTree_CopyFromOwnBranchFallsBackToRepo(t *testing.T) {
+	repo := initTestRepo(t)
+	require.NoError(t, os.WriteFile(filepath.Join(repo, "settings.json"), []byte(`{"tracked":true}`), 0o644))
+	runGit(t, repo, "add", "settings.json")
+	runGit(t, repo, "commit", "-m", "settings")
+	require.NoError(t, os.WriteFile(filepath.Join(repo, "settings.json"), []byte(`{"local":true}`), 0o644))
+
+	rc := config.ResolvedConfig{
+		Name:        "demo",
+		Repo:        repo,
+		WorktreeDir: t.TempDir(),
+		Branch:      "main",
+		Copy:        []string{"settings.json"},
+		CopyFrom:    "feature",
+	}
+
+	// copy_from names the branch being created, whose worktree is the
+	// copy destination. Copying onto itself would empty the file.
+	result, err := AddTree(rc, "feature")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{`copy_from: branch "feature" is the new worktree itself, copying from repo root`}, result.CopyWarnings)
+
+	data, err := os.ReadFile(filepath.Join(result.WorktreePath, "settings.json"))
+	require.NoError(t, err)
+	assert.Equal(t, `{"local":true}`, string(data))
+}
+
+func TestAddTreeWithOptions_CopyFromRequiresWorktree(t *testing.T) {
+	repo := initTestRepo(t)
+
+	rc := config.ResolvedConfig{
+		Name:        "demo",
+		Repo:        repo,
+		WorktreeDir: t.TempDir(),
+		Branch:      "main",
+		Copy:        []string{".env"},
+	}
+
+	_, err := AddTreeWithOptions(rc, "feature", AddTreeOptions{CopyFrom: "missing"})
+	require.ErrorContains(t, err, "missing")
+
+	assert.Nil(t, git.FindByBranch(repo, "feature"))
+}
+
 func TestAddTree_MovesBlockingPathAside(t *testing.T) {
 	repo := initTestRepo(t)
 
@@ -79,6 +202,31 @@ func TestAddTree_MovesBlockingPathAside(t *testing.T) {
 	assert.Equal(t, "feature", strings.TrimSpace(runGit(t, result.WorktreePath, "rev-parse", "--abbrev-ref", "HEAD")))
 }
 
+func TestAddTree_ErrorsWhenTargetPathBelongsToAnotherRepo(t *testing.T) {
+	worktreeRoot := t.TempDir()
+
+	// Two projects whose worktree paths collide: a project-level
+	// worktree_dir pointing at another project's directory.
+	other := config.ResolvedConfig{
+		Name:        "demo",
+		Repo:        initTestRepo(t),
+		WorktreeDir: worktreeRoot,
+		Branch:      "main",
+	}
+
+	existing, err := AddTree(other, "feature")
+	require.NoError(t, err)
+
+	rc := other
+	rc.Repo = initTestRepo(t)
+
+	_, err = AddTree(rc, "feature")
+	require.ErrorContains(t, err, "another repository")
+
+	// The other project's worktree must be left in place.
+	assert.Equal(t, "feature", strings.TrimSpace(runGit(t, existing.WorktreePath, "rev-parse", "--abbrev-ref", "HEAD")))
+}
+
 func TestAddTree_ErrorsWhenTargetPathBelongsToAnotherWorktree(t *testing.T) {
 	repo := initTestRepo(t)
 
@@ -100,6 +248,636 @@ func TestAddTree_ErrorsWhenTargetPathBelongsToAnotherWorktree(t *testing.T) {
 	require.ErrorContains(t, err, `branch "feature/login"`)
 }
 
+func TestAddTree_FetchesRemoteBranch(t *testing.T) {
+	local, remote := initTestRepoWithRemote(t)
+	runGit(t, remote, "checkout", "-b", "feature")
+	runGit(t, remote, "commit", "--allow-empty", "-m", "feature work")
+	runGit(t, remote, "checkout", "main")
+
+	rc := config.ResolvedConfig{
+		Name:        "demo",
+		Repo:        local,
+		WorktreeDir: t.TempDir(),
+		Branch:      "main",
+	}
+
+	result, err := AddTree(rc, "feature")
+	require.NoError(t, err)
+
+	assert.True(t, result.Fetched)
+	assert.Equal(t, "origin", result.Remote)
+	assert.Equal(t, "refs/heads/feature", strings.TrimSpace(runGit(t, local, "config", "branch.feature.merge")))
+}
+
+func TestAddTree_ForceBaseIgnoresRemoteBranch(t *testing.T) {
+	local, remote := initTestRepoWithRemote(t)
+	runGit(t, remote, "checkout", "-b", "feature")
+	runGit(t, remote, "commit", "--allow-empty", "-m", "feature work")
+	runGit(t, remote, "checkout", "main")
+
+	rc := config.ResolvedConfig{
+		Name:        "demo",
+		Repo:        local,
+		WorktreeDir: t.TempDir(),
+		Branch:      "main",
+	}
+
+	result, err := AddTreeWithOptions(rc, "feature", AddTreeOptions{ForceBase: true})
+	require.NoError(t, err)
+
+	assert.False(t, result.Fetched)
+
+	head := strings.TrimSpace(runGit(t, result.WorktreePath, "rev-parse", "HEAD"))
+	base := strings.TrimSpace(runGit(t, local, "rev-parse", "main"))
+	assert.Equal(t, base, head)
+}
+
+func TestAddTree_WarnsWhenBaseIsBehind(t *testing.T) {
+	local, remote := initTestRepoWithRemote(t)
+	runGit(t, remote, "commit", "--allow-empty", "-m", "upstream work")
+	runGit(t, local, "fetch", "origin")
+
+	rc := config.ResolvedConfig{
+		Name:        "demo",
+		Repo:        local,
+		WorktreeDir: t.TempDir(),
+		Branch:      "main",
+	}
+
+	result, err := AddTree(rc, "feature")
+	require.NoError(t, err)
+	assert.Equal(t, "base branch main is 1 commit(s) behind origin/main; pull it or use --update-base", result.BaseWarning)
+
+	// An existing branch does not start from the base.
+	again, err := AddTree(rc, "feature")
+	require.NoError(t, err)
+	assert.Empty(t, again.BaseWarning)
+}
+
+func TestAddTreeWithOptions_UpdateBase(t *testing.T) {
+	local, remote := initTestRepoWithRemote(t)
+	runGit(t, remote, "commit", "--allow-empty", "-m", "upstream work")
+
+	rc := config.ResolvedConfig{
+		Name:        "demo",
+		Repo:        local,
+		WorktreeDir: t.TempDir(),
+		Branch:      "main",
+	}
+
+	result, err := AddTreeWithOptions(rc, "feature", AddTreeOptions{UpdateBase: true})
+	require.NoError(t, err)
+	assert.Empty(t, result.BaseWarning)
+
+	// main is checked out in the local repo, which is fast-forwarded
+	// along with the branch.
+	upstream := strings.TrimSpace(runGit(t, remote, "rev-parse", "main"))
+	assert.Equal(t, upstream, strings.TrimSpace(runGit(t, local, "rev-parse", "main")))
+	assert.Equal(t, upstream, strings.TrimSpace(runGit(t, result.WorktreePath, "rev-parse", "HEAD")))
+
+	// A diverged base is not overwritten.
+	runGit(t, remote, "commit", "--allow-empty", "-m", "more upstream work")
+	runGit(t, local, "commit", "--allow-empty", "-m", "local work")
+
+	_, err = AddTreeWithOptions(rc, "other", AddTreeOptions{UpdateBase: true})
+	assert.ErrorContains(t, err, "diverged")
+}
+
+func TestAddTree_RecordsBranchBase(t *testing.T) {
+	repo := initTestRepo(t)
+	runGit(t, repo, "branch", "develop", "main")
+
+	rc := config.ResolvedConfig{
+		Name:        "demo",
+		Repo:        repo,
+		WorktreeDir: t.TempDir(),
+		Branch:      "develop",
+	}
+
+	_, err := AddTree(rc, "feature")
+	require.NoError(t, err)
+
+	assert.Equal(t, "develop", strings.TrimSpace(runGit(t, repo, "config", "branch.feature.forestBase")))
+}
+
+func TestAddTree_RecordsBaseOnlyForNewBranches(t *testing.T) {
+	local, remote := initTestRepoWithRemote(t)
+	runGit(t, remote, "checkout", "-b", "fetched")
+	runGit(t, remote, "commit", "--allow-empty", "-m", "fetched work")
+	runGit(t, remote, "checkout", "main")
+	runGit(t, local, "branch", "develop", "main")
+	runGit(t, local, "branch", "existing", "main")
+
+	rc := config.ResolvedConfig{
+		Name:        "demo",
+		Repo:        local,
+		WorktreeDir: t.TempDir(),
+		Branch:      "develop",
+	}
+
+	// Neither branch was created off develop, so neither gets it
+	// recorded as its base.
+	for _, branch := range []string{"fetched", "existing"} {
+		result, err := AddTree(rc, branch)
+		require.NoError(t, err)
+		assert.True(t, result.Created)
+
+		base, err := git.GetBranchBase(local, branch)
+		require.NoError(t, err)
+		assert.Empty(t, base, branch)
+	}
+}
+
+func TestAddTree_WorktreePrefix(t *testing.T) {
+	repo := initTestRepo(t)
+	worktreeDir := t.TempDir()
+
+	rc := config.ResolvedConfig{
+		Name:           "demo",
+		Repo:           repo,
+		WorktreeDir:    worktreeDir,
+		WorktreePrefix: "wt-",
+		Branch:         "main",
+	}
+
+	result, err := AddTree(rc, "feature/login")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(worktreeDir, "demo", "wt-feature-login"), result.WorktreePath)
+
+	// The existing worktree is still found after the prefix changes.
+	rc.WorktreePrefix = ""
+
+	result, err = AddTree(rc, "feature/login")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(worktreeDir, "demo", "wt-feature-login"), result.WorktreePath)
+}
+
+func TestAddTreeWithOptions_Checkout(t *testing.T) {
+	repo := initTestRepo(t)
+
+	rc := config.ResolvedConfig{
+		Name:        "demo",
+		Repo:        repo,
+		WorktreeDir: t.TempDir(),
+		Branch:      "main",
+	}
+
+	var ranIn string
+
+	opts := AddTreeOptions{Checkout: func(wtPath string) error {
+		ranIn = wtPath
+		runGit(t, wtPath, "checkout", "-b", "pr-7")
+		return nil
+	}}
+
+	result, err := AddTreeWithOptions(rc, "pr-7", opts)
+	require.NoError(t, err)
+
+	assert.True(t, result.Created)
+	assert.Equal(t, ranIn, result.WorktreePath)
+	assert.Equal(t, "pr-7", git.CurrentBranch(result.WorktreePath))
+	assert.Equal(t, "main", strings.TrimSpace(runGit(t, repo, "config", "branch.pr-7.forestBase")))
+}
+
+func TestAddTreeWithOptions_CheckoutFailureRemovesWorktree(t *testing.T) {
+	repo := initTestRepo(t)
+
+	rc := config.ResolvedConfig{
+		Name:        "demo",
+		Repo:        repo,
+		WorktreeDir: t.TempDir(),
+		Branch:      "main",
+	}
+
+	opts := AddTreeOptions{Checkout: func(string) error { return nil }}
+
+	_, err := AddTreeWithOptions(rc, "pr-7", opts)
+	require.ErrorIs(t, err, git.ErrBranchNotFound)
+
+	trees, err := git.List(repo)
+	require.NoError(t, err)
+	assert.Len(t, trees, 1)
+}
+
+func TestAddTreeWithOptions_CheckoutBeforePopulating(t *testing.T) {
+	repo := initTestRepo(t)
+
+	require.NoError(t, os.WriteFile(filepath.Join(repo, ".env"), []byte("SECRET=abc"), 0o644))
+	runGit(t, repo, "add", ".env")
+	runGit(t, repo, "commit", "-m", "add env")
+
+	rc := config.ResolvedConfig{
+		Name:        "demo",
+		Repo:        repo,
+		WorktreeDir: t.TempDir(),
+		Branch:      "main",
+		Remove:      []string{".env"},
+	}
+
+	var statusAtCheckout string
+
+	opts := AddTreeOptions{Checkout: func(wtPath string) error {
+		statusAtCheckout = strings.TrimSpace(runGit(t, wtPath, "status", "--short"))
+		runGit(t, wtPath, "checkout", "-b", "pr-7")
+		return nil
+	}}
+
+	result, err := AddTreeWithOptions(rc, "pr-7", opts)
+	require.NoError(t, err)
+
+	assert.Empty(t, statusAtCheckout, "remove must not run before the checkout")
+	assert.Empty(t, result.RemoveWarnings)
+
+	_, err = os.Stat(filepath.Join(result.WorktreePath, ".env"))
+	require.ErrorIs(t, err, fs.ErrNotExist)
+	assert.Empty(t, strings.TrimSpace(runGit(t, result.WorktreePath, "status", "--short")))
+}
+
+func TestAddTreeWithOptions_CheckoutKeepsExistingDetachedWorktree(t *testing.T) {
+	repo := initTestRepo(t)
+
+	rc := config.ResolvedConfig{
+		Name:        "demo",
+		Repo:        repo,
+		WorktreeDir: t.TempDir(),
+		Branch:      "main",
+	}
+
+	// A detached worktree left where the PR's worktree would go.
+	wtPath := treePath(rc, "pr-7")
+	runGit(t, repo, "worktree", "add", "--detach", wtPath, "main")
+
+	ran := false
+	opts := AddTreeOptions{Checkout: func(string) error {
+		ran = true
+		return nil
+	}}
+
+	_, err := AddTreeWithOptions(rc, "pr-7", opts)
+	require.ErrorContains(t, err, "already exists")
+
+	assert.False(t, ran)
+	assert.NotNil(t, git.FindByPath(repo, wtPath))
+}
+
+func TestAddTree_RejectsInvalidBranchName(t *testing.T) {
+	repo := initTestRepo(t)
+
+	rc := config.ResolvedConfig{
+		Name:        "demo",
+		Repo:        repo,
+		WorktreeDir: t.TempDir(),
+		Branch:      "main",
+	}
+
+	_, err := AddTree(rc, "feature//login")
+	require.ErrorContains(t, err, "invalid branch name")
+}
+
+func TestAddTree_ReusesWorktreeWithoutValidatingName(t *testing.T) {
+	repo := initTestRepo(t)
+
+	// Git can be made to check out a branch that check-ref-format
+	// would refuse to create; reusing its worktree must still work.
+	wtPath := filepath.Join(t.TempDir(), "odd")
+	runGit(t, repo, "update-ref", "refs/heads/-odd", "main")
+	runGit(t, repo, "worktree", "add", "--detach", wtPath)
+	runGit(t, wtPath, "symbolic-ref", "HEAD", "refs/heads/-odd")
+
+	rc := config.ResolvedConfig{
+		Name:        "demo",
+		Repo:        repo,
+		WorktreeDir: t.TempDir(),
+		Branch:      "main",
+	}
+
+	result, err := AddTree(rc, "-odd")
+	require.NoError(t, err)
+	assert.Equal(t, wtPath, result.WorktreePath)
+}
+
+func TestAddDetachedTree(t *testing.T) {
+	repo := initTestRepo(t)
+	runGit(t, repo, "tag", "v1.0")
+	runGit(t, repo, "commit", "--allow-empty", "-m", "second")
+
+	rc := config.ResolvedConfig{
+		Name:        "demo",
+		Repo:        repo,
+		WorktreeDir: t.TempDir(),
+		Branch:      "main",
+	}
+
+	result, err := AddDetachedTree(rc, "v1.0", AddTreeOptions{})
+	require.NoError(t, err)
+	assert.True(t, result.Created)
+	assert.Equal(t, filepath.Join(rc.WorktreeDir, "demo", "v1.0"), result.WorktreePath)
+	assert.Equal(t, "demo-v1_0", result.SessionName)
+
+	// The worktree has no branch and sits at the tagged commit.
+	wt := git.FindByPath(repo, result.WorktreePath)
+	require.NotNil(t, wt)
+	assert.Empty(t, wt.Branch)
+	assert.Equal(t,
+		strings.TrimSpace(runGit(t, repo, "rev-parse", "v1.0")),
+		strings.TrimSpace(runGit(t, result.WorktreePath, "rev-parse", "HEAD")),
+	)
+	assert.False(t, git.BranchExists(repo, "v1.0"))
+
+	again, err := AddDetachedTree(rc, "v1.0", AddTreeOptions{})
+	require.NoError(t, err)
+	assert.False(t, again.Created)
+	assert.Equal(t, result.WorktreePath, again.WorktreePath)
+
+	_, err = AddDetachedTree(rc, "no-such-ref", AddTreeOptions{})
+	assert.Error(t, err)
+}
+
+func TestAddTagTree(t *testing.T) {
+	repo := initTestRepo(t)
+	runGit(t, repo, "tag", "-a", "release/1.2", "-m", "release")
+	runGit(t, repo, "commit", "--allow-empty", "-m", "second")
+
+	rc := config.ResolvedConfig{
+		Name:        "demo",
+		Repo:        repo,
+		WorktreeDir: t.TempDir(),
+		Branch:      "main",
+	}
+
+	result, err := AddTagTree(rc, "release/1.2", AddTreeOptions{})
+	require.NoError(t, err)
+	assert.True(t, result.Created)
+	assert.Equal(t, filepath.Join(rc.WorktreeDir, "demo", "tag-release-1.2"), result.WorktreePath)
+	assert.Equal(t, "demo-tag-release-1_2", result.SessionName)
+
+	wt := git.FindByPath(repo, result.WorktreePath)
+	require.NotNil(t, wt)
+	assert.Empty(t, wt.Branch)
+	assert.Equal(t,
+		strings.TrimSpace(runGit(t, repo, "rev-parse", "release/1.2^{commit}")),
+		strings.TrimSpace(runGit(t, result.WorktreePath, "rev-parse", "HEAD")),
+	)
+
+	// Branches are not tags.
+	_, err = AddTagTree(rc, "main", AddTreeOptions{})
+	assert.ErrorContains(t, err, `tag "main" not found`)
+}
+
+func TestAddPinTree(t *testing.T) {
+	repo := initTestRepo(t)
+	sha := strings.TrimSpace(runGit(t, repo, "rev-parse", "main"))
+
+	rc := config.ResolvedConfig{
+		Name:        "demo",
+		Repo:        repo,
+		WorktreeDir: t.TempDir(),
+		Branch:      "main",
+	}
+
+	result, err := AddPinTree(rc, 42, sha, AddTreeOptions{})
+	require.NoError(t, err)
+	assert.True(t, result.Created)
+	assert.Equal(t, filepath.Join(rc.WorktreeDir, "demo", "pr-42-"+sha[:7]), result.WorktreePath)
+	assert.Equal(t, "demo-pr-42-"+sha[:7], result.SessionName)
+
+	wt := git.FindByPath(repo, result.WorktreePath)
+	require.NotNil(t, wt)
+	assert.Empty(t, wt.Branch)
+
+	_, err = AddPinTree(rc, 42, strings.Repeat("0", 40), AddTreeOptions{})
+	assert.ErrorContains(t, err, "not found")
+}
+
+func TestRelocateProject(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(dir, "config"))
+	t.Setenv("XDG_DATA_HOME", filepath.Join(dir, "data"))
+
+	repo := initTestRepo(t)
+
+	name, _, err := RegisterProject(repo, "myapp")
+	require.NoError(t, err)
+
+	rc, err := config.Resolve(name)
+	require.NoError(t, err)
+
+	result, err := AddTree(rc, "feature")
+	require.NoError(t, err)
+
+	moved := filepath.Join(dir, "moved")
+	require.NoError(t, os.Rename(repo, moved))
+
+	assert.ErrorIs(t, config.ValidateRepo(name, repo), config.ErrRepoMissing)
+
+	_, err = RelocateProject(name, filepath.Join(dir, "missing"))
+	require.Error(t, err)
+
+	got, err := RelocateProject(name, moved)
+	require.NoError(t, err)
+	assert.Equal(t, moved, got)
+
+	proj, err := config.LoadProject(name)
+	require.NoError(t, err)
+	assert.Equal(t, moved, proj.Repo)
+
+	// The worktree finds the repository at its new location again.
+	assert.Equal(t, "feature", git.CurrentBranch(result.WorktreePath))
+	assert.NotNil(t, git.FindByBranch(moved, "feature"))
+}
+
+func TestFindRepo(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(dir, "config"))
+	t.Setenv("XDG_DATA_HOME", filepath.Join(dir, "data"))
+
+	repo := initTestRepo(t)
+	runGit(t, repo, "remote", "add", "origin", "git@github.com:acme/app.git")
+
+	name, _, err := RegisterProject(repo, "app")
+	require.NoError(t, err)
+
+	proj, err := config.LoadProject(name)
+	require.NoError(t, err)
+	assert.Equal(t, "acme/app", proj.Remote)
+
+	rc, err := config.Resolve(name)
+	require.NoError(t, err)
+
+	_, err = AddTree(rc, "feature")
+	require.NoError(t, err)
+
+	// Move the repository a few levels down, next to an unrelated one.
+	search := filepath.Join(dir, "code")
+	moved := filepath.Join(search, "acme", "app")
+	require.NoError(t, os.MkdirAll(filepath.Dir(moved), 0o755))
+	require.NoError(t, os.Rename(repo, moved))
+
+	other := initTestRepo(t)
+	runGit(t, other, "remote", "add", "origin", "https://github.com/acme/other.git")
+	require.NoError(t, os.Rename(other, filepath.Join(search, "acme", "other")))
+
+	got, err := FindRepo(search, proj.Remote)
+	require.NoError(t, err)
+	assert.Equal(t, moved, got)
+
+	_, err = FindRepo(search, "acme/missing")
+	assert.ErrorContains(t, err, "no repository with remote acme/missing")
+}
+
+func TestFindRepo_PrefersOrigin(t *testing.T) {
+	search := t.TempDir()
+
+	// A fork clone sorts first and only has acme/app as its upstream.
+	fork := initTestRepo(t)
+	runGit(t, fork, "remote", "add", "origin", "https://github.com/me/app.git")
+	runGit(t, fork, "remote", "add", "upstream", "https://github.com/acme/app.git")
+	require.NoError(t, os.Rename(fork, filepath.Join(search, "a-fork")))
+
+	got, err := FindRepo(search, "acme/app")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(search, "a-fork"), got, "falls back to another remote")
+
+	clone := initTestRepo(t)
+	runGit(t, clone, "remote", "add", "origin", "git@github.com:acme/app.git")
+	require.NoError(t, os.Rename(clone, filepath.Join(search, "b-app")))
+
+	got, err = FindRepo(search, "acme/app")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(search, "b-app"), got)
+}
+
+func TestRefreshProjectRemote(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(dir, "config"))
+	t.Setenv("XDG_DATA_HOME", filepath.Join(dir, "data"))
+
+	repo := initTestRepo(t)
+	require.NoError(t, config.SaveProject("app", config.ProjectConfig{Repo: repo}))
+
+	// Without an origin there is nothing to record.
+	remote, err := RefreshProjectRemote("app")
+	require.NoError(t, err)
+	assert.Empty(t, remote)
+
+	runGit(t, repo, "remote", "add", "origin", "https://github.com/acme/app.git")
+
+	remote, err = RefreshProjectRemote("app")
+	require.NoError(t, err)
+	assert.Equal(t, "acme/app", remote)
+
+	proj, err := config.LoadProject("app")
+	require.NoError(t, err)
+	assert.Equal(t, "acme/app", proj.Remote)
+}
+
+func TestOpenSession_ExistingWorktreeMissingSession(t *testing.T) {
+	isolatedTmux(t)
+
+	repo := initTestRepo(t)
+
+	rc := config.ResolvedConfig{
+		Name:        "demo",
+		Repo:        repo,
+		WorktreeDir: t.TempDir(),
+		Branch:      "main",
+		Layout: []config.Window{
+			{Name: "editor"},
+			{Name: "shell"},
+		},
+	}
+
+	first, err := AddTree(rc, "feature")
+	require.NoError(t, err)
+	require.True(t, first.Created)
+
+	// Reusing the worktree does not create it again, and no session
+	// exists for it yet.
+	result, err := AddTree(rc, "feature")
+	require.NoError(t, err)
+	assert.False(t, result.Created)
+	assert.Equal(t, first.WorktreePath, result.WorktreePath)
+
+	created, err := OpenSession(rc, "feature", result.WorktreePath)
+	require.NoError(t, err)
+	assert.True(t, created)
+
+	out, err := exec.Command("tmux", "list-windows", "-t", result.SessionName, "-F", "#{window_name}").Output()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"editor", "shell"}, strings.Fields(string(out)))
+
+	// A second call finds the session and leaves it alone.
+	created, err = OpenSession(rc, "feature", result.WorktreePath)
+	require.NoError(t, err)
+	assert.False(t, created)
+}
+
+func TestOpenSession_WindowShells(t *testing.T) {
+	// The fake tmux reports that no session exists yet.
+	calls := fakeTmux(t, `[ "$1" = has-session ] && exit 1; exit 0`)
+
+	rc := config.ResolvedConfig{
+		Name: "demo",
+		Layout: []config.Window{
+			{Name: "editor", Shell: "fish", Command: "nvim"},
+			{Name: "logs", Shell: "bash --norc", Command: "tail -f log"},
+			{Name: "plain"},
+		},
+	}
+
+	created, err := OpenSession(rc, "feature", "/wt")
+	require.NoError(t, err)
+	assert.True(t, created)
+
+	var windows [][]string
+
+	for _, call := range calls() {
+		if call[0] == "new-session" || call[0] == "new-window" {
+			windows = append(windows, call)
+		}
+	}
+
+	assert.Equal(t, [][]string{
+		{"new-session", "-d", "-s", "demo-feature", "-c", "/wt", "fish"},
+		{"new-window", "-t", "demo-feature", "-c", "/wt", "-n", "logs", "bash --norc"},
+		{"new-window", "-t", "demo-feature", "-c", "/wt", "-n", "plain"},
+	}, windows)
+}
+
+func TestOpenSession_DefaultShell(t *testing.T) {
+	calls := fakeTmux(t, `[ "$1" = has-session ] && exit 1; exit 0`)
+
+	rc := config.ResolvedConfig{
+		Name:   "demo",
+		Layout: []config.Window{{Name: "editor"}, {Name: "logs", Shell: "zsh"}},
+	}
+
+	_, err := OpenSession(rc, "feature", "/wt")
+	require.NoError(t, err)
+
+	// Without a shell for the first window, tmux starts its default.
+	assert.Contains(t, calls(), []string{"new-session", "-d", "-s", "demo-feature", "-c", "/wt"})
+}
+
+// initTestRepoWithRemote creates a repo and a clone of it, returning
+// (local, remote) paths. The local clone has "origin" pointing at the
+// remote.
+func initTestRepoWithRemote(t *testing.T) (string, string) {
+	t.Helper()
+
+	remote := initTestRepo(t)
+	local := filepath.Join(t.TempDir(), "local")
+
+	cmd := exec.Command("git", "clone", remote, local)
+	output, err := cmd.CombinedOutput()
+	require.NoError(t, err, "clone failed: %s", output)
+
+	runGit(t, local, "config", "user.email", "test@test.com")
+	runGit(t, local, "config", "user.name", "test")
+
+	return local, remote
+}
+
 func initTestRepo(t *testing.T) string {
 	t.Helper()
 
@@ -136,3 +914,54 @@ func runGit(t *testing.T, dir string, args ...string) string {
 
 	return string(output)
 }
+
+// isolatedTmux points tmux at a private server for the duration of the
+// test, so sessions never touch the user's own server.
+func isolatedTmux(t *testing.T) {
+	t.Helper()
+
+	if _, err := exec.LookPath("tmux"); err != nil {
+		t.Skip("tmux not installed")
+	}
+
+	// Keep the socket path short; tmux rejects long socket paths.
+	dir, err := os.MkdirTemp("/tmp", "forest-tmux")
+	require.NoError(t, err)
+
+	t.Setenv("TMUX", "")
+	t.Setenv("TMUX_TMPDIR", dir)
+
+	t.Cleanup(func() {
+		_ = exec.Command("tmux", "kill-server").Run()
+		_ = os.RemoveAll(dir)
+	})
+}
+
+// fakeTmux puts a tmux on PATH that records its arguments and then
+// runs script, and returns a function listing the recorded calls, one
+// slice of arguments per call.
+func fakeTmux(t *testing.T, script string) func() [][]string {
+	t.Helper()
+
+	bin := t.TempDir()
+	log := filepath.Join(bin, "tmux.log")
+
+	// Each argument goes on its own line and each call ends with an
+	// empty line, so arguments with spaces stay intact.
+	body := "#!/bin/sh\nfor arg; do printf '%s\\n' \"$arg\"; done >> " + log + "\necho >> " + log + "\n" + script + "\n"
+	require.NoError(t, os.WriteFile(filepath.Join(bin, "tmux"), []byte(body), 0o755))
+	t.Setenv("PATH", bin)
+
+	return func() [][]string {
+		data, err := os.ReadFile(log)
+		require.NoError(t, err)
+
+		var calls [][]string
+
+		for _, call := range strings.Split(strings.TrimSuffix(string(data), "\n\n"), "\n\n") {
+			calls = append(calls, strings.Split(call, "\n"))
+		}
+
+		return calls
+	}
+}
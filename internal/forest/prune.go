@@ -0,0 +1,258 @@
+package forest
+
+import (
+	"log/slog"
+	"path"
+	"path/filepath"
+
+	"github.com/mhamza15/forest/internal/config"
+	"github.com/mhamza15/forest/internal/git"
+	"github.com/mhamza15/forest/internal/github"
+)
+
+// PruneLabel describes why a worktree can be pruned.
+type PruneLabel string
+
+const (
+	// PruneLabelMerged marks a branch merged into its base.
+	PruneLabelMerged PruneLabel = "merged"
+
+	// PruneLabelPRMerged marks a branch whose pull request gh reports
+	// as merged, such as after a squash merge.
+	PruneLabelPRMerged PruneLabel = "PR merged"
+
+	// PruneLabelGone marks a branch deleted from the remote that could
+	// not be confirmed as merged.
+	PruneLabelGone PruneLabel = "gone"
+)
+
+// PruneCandidate is a worktree that prune would remove, along with
+// why. Confirm is set for branches that are gone from the remote but
+// could not be confirmed as merged, which need the user's approval.
+type PruneCandidate struct {
+	Branch  string
+	Path    string
+	Label   PruneLabel
+	Confirm bool
+}
+
+// PruneOptions narrows which worktrees PruneCandidates considers.
+type PruneOptions struct {
+	// Remote overrides the project's upstream_remote as the remote
+	// whose branches decide whether a branch is gone, and whose
+	// repository is asked for merged PRs.
+	Remote string
+
+	// Exclude lists glob patterns of branches to never prune, on top
+	// of the project's prune_exclude.
+	Exclude []string
+
+	// CheckPRs asks gh whether branches still on the remote had their
+	// pull request merged.
+	CheckPRs bool
+
+	// MergedOnly and GoneOnly limit the candidates to merged branches
+	// or to branches deleted from the remote.
+	MergedOnly bool
+	GoneOnly   bool
+}
+
+// LoadPruneProject resolves a project and lists its worktrees for
+// PruneCandidates.
+func LoadPruneProject(name string) (config.ResolvedConfig, []git.Worktree, error) {
+	rc, err := config.Resolve(name)
+	if err != nil {
+		return rc, nil, err
+	}
+
+	if err := config.ValidateRepo(name, rc.Repo); err != nil {
+		return rc, nil, err
+	}
+
+	trees, err := git.List(rc.Repo)
+	if err != nil {
+		return rc, nil, err
+	}
+
+	return rc, trees, nil
+}
+
+// PruneCandidates decides which of a project's worktrees are prunable
+// and why. It only queries git and gh; removing worktrees and
+// prompting for gone branches are left to the caller.
+func PruneCandidates(rc config.ResolvedConfig, trees []git.Worktree, opts PruneOptions) []PruneCandidate {
+	remote := opts.Remote
+	if remote == "" {
+		remote = rc.UpstreamRemote
+	}
+
+	// Fetch remote branches once per project so we can detect
+	// branches deleted after a squash-merge PR.
+	remoteBranches, err := git.RemoteBranches(rc.Repo, remote)
+	if err != nil {
+		slog.Debug("could not fetch remote branches", slog.String("project", rc.Name), slog.Any("err", err))
+	}
+
+	// Resolve NWO once per project for gh PR lookups, from the same
+	// remote whose branches decide goneness: in fork workflows PRs
+	// are opened against upstream, not origin. A failure here is
+	// non-fatal; we fall back to interactive confirmation.
+	prs := &mergedPRs{nwo: RemoteNWO(rc.Repo, remote)}
+
+	var candidates []PruneCandidate
+
+	for _, t := range trees {
+		if !PruneEligible(t, rc, opts.Exclude) {
+			continue
+		}
+
+		reason := git.PruneCheck(rc.Repo, t.Branch, PruneTarget(rc, t.Branch), remoteBranches)
+		label := PruneLabelMerged
+
+		// A squash-merged PR leaves the branch unmerged locally,
+		// and the branch may still exist on the remote if it was
+		// not deleted after merging. Ask gh when requested.
+		if reason == git.PruneNone && opts.CheckPRs && remoteBranches[t.Branch] {
+			if prs.has(t.Branch) {
+				reason = git.PruneMerged
+				label = PruneLabelPRMerged
+			}
+		}
+
+		if reason == git.PruneNone {
+			continue
+		}
+
+		if opts.MergedOnly && reason != git.PruneMerged {
+			continue
+		}
+
+		if opts.GoneOnly && reason != git.PruneRemoteGone {
+			continue
+		}
+
+		c := PruneCandidate{Branch: t.Branch, Path: t.Path, Label: label}
+
+		// When the branch is gone from the remote but not merged
+		// locally, verify via gh that the PR was actually merged.
+		// Otherwise the caller must confirm before removing it.
+		if reason == git.PruneRemoteGone {
+			if prs.has(t.Branch) {
+				c.Label = PruneLabelPRMerged
+			} else {
+				c.Label = PruneLabelGone
+				c.Confirm = true
+			}
+		}
+
+		candidates = append(candidates, c)
+	}
+
+	return candidates
+}
+
+// PruneEligible reports whether a worktree may be considered for
+// pruning at all. Bare and detached entries, the base branch, branches
+// matching prune_exclude or exclude, and the main working tree (which
+// git worktree remove cannot remove) are never pruned.
+func PruneEligible(t git.Worktree, rc config.ResolvedConfig, exclude []string) bool {
+	if t.Bare || t.Branch == "" || t.Branch == git.ResolveBase(rc.Repo, rc.Branch) {
+		return false
+	}
+
+	if pruneExcluded(t.Branch, rc.PruneExclude) || pruneExcluded(t.Branch, exclude) {
+		return false
+	}
+
+	return filepath.Clean(t.Path) != filepath.Clean(rc.Repo)
+}
+
+// pruneExcluded reports whether branch matches any of the glob
+// patterns. Malformed patterns never match.
+func pruneExcluded(branch string, patterns []string) bool {
+	for _, pattern := range patterns {
+		matched, err := path.Match(pattern, branch)
+		if err != nil {
+			slog.Debug("invalid prune exclude pattern", slog.String("pattern", pattern), slog.Any("err", err))
+			continue
+		}
+
+		if matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+// PruneTarget returns the branch to check merge status against: the
+// base recorded when the worktree was created, falling back to the
+// project's base branch.
+func PruneTarget(rc config.ResolvedConfig, branch string) string {
+	base, err := git.GetBranchBase(rc.Repo, branch)
+	if err != nil {
+		slog.Debug("could not read branch base", slog.String("branch", branch), slog.Any("err", err))
+	}
+
+	if base == "" {
+		return git.ResolveBase(rc.Repo, rc.Branch)
+	}
+
+	return base
+}
+
+// mergedPRs answers whether branches had their PR merged. It lists the
+// project's merged PRs with gh the first time it is asked, so that
+// checking many branches costs one gh call rather than one per branch.
+type mergedPRs struct {
+	nwo      string
+	byBranch map[string]github.PRSummary
+	complete bool
+	loaded   bool
+}
+
+// has reports whether gh finds a merged PR for the branch. Any gh
+// failure is treated as not merged.
+func (m *mergedPRs) has(branch string) bool {
+	if m.nwo == "" {
+		return false
+	}
+
+	if !m.loaded {
+		m.loaded = true
+
+		prs, err := github.ListPRs(m.nwo, "merged")
+		if err != nil {
+			slog.Debug("gh PR list failed", slog.String("repo", m.nwo), slog.Any("err", err))
+
+			m.nwo = ""
+
+			return false
+		}
+
+		m.byBranch = github.PRsByBranch(prs)
+		m.complete = github.PRListComplete(prs)
+	}
+
+	merged, err := github.IsPRMerged(m.nwo, branch, m.byBranch, m.complete)
+	if err != nil {
+		slog.Debug("gh PR check failed",
+			slog.String("branch", branch),
+			slog.Any("err", err),
+		)
+	}
+
+	return merged
+}
+
+// RemoteNWO returns the "owner/repo" string for the named remote, or
+// an empty string if it cannot be determined. An empty result causes
+// callers to skip gh lookups.
+func RemoteNWO(repoPath, remote string) string {
+	raw, err := git.RemoteURL(repoPath, remote)
+	if err != nil {
+		return ""
+	}
+
+	return git.NormalizeRemoteURL(raw)
+}
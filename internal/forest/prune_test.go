@@ -0,0 +1,108 @@
+package forest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mhamza15/forest/internal/config"
+	"github.com/mhamza15/forest/internal/git"
+)
+
+func TestPruneCandidates(t *testing.T) {
+	repo := initTestRepo(t)
+
+	rc := config.ResolvedConfig{
+		Name:           "demo",
+		Repo:           repo,
+		WorktreeDir:    t.TempDir(),
+		Branch:         "main",
+		UpstreamRemote: "origin",
+		PruneExclude:   []string{"keep/*"},
+	}
+
+	for _, branch := range []string{"done", "wip", "keep/this", "flagged"} {
+		_, err := AddTree(rc, branch)
+		require.NoError(t, err)
+	}
+
+	wip := git.FindByBranch(repo, "wip")
+	require.NotNil(t, wip)
+	require.NoError(t, os.WriteFile(filepath.Join(wip.Path, "wip.txt"), []byte("wip"), 0o644))
+	runGit(t, wip.Path, "add", "wip.txt")
+	runGit(t, wip.Path, "commit", "-m", "wip")
+
+	trees, err := git.List(repo)
+	require.NoError(t, err)
+
+	branches := func(candidates []PruneCandidate) []string {
+		var out []string
+		for _, c := range candidates {
+			out = append(out, c.Branch)
+		}
+
+		return out
+	}
+
+	candidates := PruneCandidates(rc, trees, PruneOptions{})
+	assert.Equal(t, []string{"done", "flagged"}, branches(candidates))
+	assert.Equal(t, PruneLabelMerged, candidates[0].Label)
+	assert.False(t, candidates[0].Confirm)
+
+	candidates = PruneCandidates(rc, trees, PruneOptions{Exclude: []string{"flag*", "["}})
+	assert.Equal(t, []string{"done"}, branches(candidates))
+
+	assert.Empty(t, PruneCandidates(rc, trees, PruneOptions{GoneOnly: true}))
+}
+
+func TestPruneCandidates_LooksUpPRsOnPruneRemote(t *testing.T) {
+	repo := initTestRepo(t)
+	upstream := initTestRepo(t)
+	runGit(t, repo, "remote", "add", "origin", "https://github.com/fork/demo.git")
+	runGit(t, repo, "remote", "add", "upstream", "git@github.com:acme/demo.git")
+
+	// The fake ssh serves the upstream repository for any host, so
+	// that the GitHub remote's branches can be listed offline.
+	bin := t.TempDir()
+	ssh := filepath.Join(bin, "ssh")
+	require.NoError(t, os.WriteFile(ssh, []byte("#!/bin/sh\nexec git upload-pack "+upstream+"\n"), 0o755))
+	t.Setenv("GIT_SSH_COMMAND", ssh)
+
+	rc := config.ResolvedConfig{
+		Name:           "demo",
+		Repo:           repo,
+		WorktreeDir:    t.TempDir(),
+		Branch:         "main",
+		UpstreamRemote: "upstream",
+	}
+
+	_, err := AddTree(rc, "wip")
+	require.NoError(t, err)
+
+	wip := git.FindByBranch(repo, "wip")
+	require.NotNil(t, wip)
+	require.NoError(t, os.WriteFile(filepath.Join(wip.Path, "wip.txt"), []byte("wip"), 0o644))
+	runGit(t, wip.Path, "add", "wip.txt")
+	runGit(t, wip.Path, "commit", "-m", "wip")
+
+	trees, err := git.List(repo)
+	require.NoError(t, err)
+
+	// The fake gh records its arguments and reports no merged PRs.
+	log := filepath.Join(bin, "gh.log")
+	script := "#!/bin/sh\necho \"$@\" >> " + log + "\necho '[]'\n"
+	require.NoError(t, os.WriteFile(filepath.Join(bin, "gh"), []byte(script), 0o755))
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	candidates := PruneCandidates(rc, trees, PruneOptions{})
+	require.Len(t, candidates, 1)
+	assert.Equal(t, PruneLabelGone, candidates[0].Label)
+
+	calls, err := os.ReadFile(log)
+	require.NoError(t, err)
+	assert.Contains(t, string(calls), "--repo acme/demo")
+	assert.NotContains(t, string(calls), "fork/demo")
+}
@@ -3,17 +3,75 @@ package git
 import (
 	"bytes"
 	"fmt"
+	"io"
 	"os/exec"
+	"strconv"
 	"strings"
+
+	"github.com/mhamza15/forest/internal/network"
 )
 
+// CloneOptions controls optional git clone behavior. The zero value
+// performs a full clone.
+type CloneOptions struct {
+	// Depth creates a shallow clone with history truncated to the
+	// given number of commits. Zero clones the full history.
+	Depth int
+
+	// SingleBranch clones only the history of the remote's default
+	// branch.
+	SingleBranch bool
+
+	// Partial creates a blobless partial clone. Commits and trees are
+	// cloned up front, and file contents are fetched on demand when a
+	// worktree checks them out.
+	Partial bool
+
+	// Progress receives git's progress output while cloning. When
+	// nil, the clone runs silently.
+	Progress io.Writer
+}
+
 // Clone clones a git repository from url into dest.
 func Clone(url, dest string) error {
-	cmd := exec.Command("git", "clone", url, dest)
+	return CloneWithOptions(url, dest, CloneOptions{})
+}
 
-	output, err := cmd.CombinedOutput()
+// Flags returns the git clone flags for the options, for tools such
+// as gh repo clone that pass extra flags through to git.
+func (o CloneOptions) Flags() []string {
+	var flags []string
+
+	if o.Depth > 0 {
+		flags = append(flags, "--depth", strconv.Itoa(o.Depth))
+	}
+
+	if o.SingleBranch {
+		flags = append(flags, "--single-branch")
+	}
+
+	if o.Partial {
+		flags = append(flags, "--filter=blob:none")
+	}
+
+	if o.Progress != nil {
+		flags = append(flags, "--progress")
+	}
+
+	return flags
+}
+
+// CloneWithOptions clones a git repository from url into dest with the
+// given options.
+func CloneWithOptions(url, dest string, opts CloneOptions) error {
+	args := append([]string{"clone"}, opts.Flags()...)
+	args = append(args, url, dest)
+
+	cmd := exec.Command("git", args...)
+
+	output, err := runWithProgress(cmd, opts.Progress)
 	if err != nil {
-		return fmt.Errorf("git clone: %s: %w", bytes.TrimSpace(output), err)
+		return network.CommandError("git clone", output, opts.Progress != nil, err)
 	}
 
 	return nil
@@ -31,3 +89,50 @@ func DefaultBranch(repoPath string) (string, error) {
 
 	return strings.TrimSpace(string(output)), nil
 }
+
+// DefaultBaseAlias is a base branch value that resolves to the
+// remote's default branch at the time a worktree is created.
+const DefaultBaseAlias = "@default"
+
+// RemoteDefaultBranch returns the default branch of the given remote
+// as recorded by refs/remotes/<remote>/HEAD, without the remote
+// prefix (e.g. "main").
+func RemoteDefaultBranch(repoPath, remote string) (string, error) {
+	cmd := exec.Command("git", "-C", repoPath, "symbolic-ref", "--short", "refs/remotes/"+remote+"/HEAD")
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("git symbolic-ref: %s: %w", bytes.TrimSpace(output), err)
+	}
+
+	return strings.TrimPrefix(strings.TrimSpace(string(output)), remote+"/"), nil
+}
+
+// ResolveBase resolves the "origin/HEAD" and "@default" base branch
+// shorthands to the remote's current default branch. The local branch
+// name is returned when it exists, otherwise the remote tracking ref
+// (e.g. "origin/main"). Any other value, or a shorthand that cannot be
+// resolved, is returned unchanged.
+func ResolveBase(repoPath, base string) string {
+	if base != "origin/HEAD" && base != DefaultBaseAlias {
+		return base
+	}
+
+	branch, err := RemoteDefaultBranch(repoPath, "origin")
+	if err != nil {
+		// Without a remote HEAD, fall back to the repository's own
+		// checked out branch, which for a clone is the default.
+		branch, err = DefaultBranch(repoPath)
+		if err != nil {
+			return base
+		}
+
+		return branch
+	}
+
+	if BranchExists(repoPath, branch) {
+		return branch
+	}
+
+	return "origin/" + branch
+}
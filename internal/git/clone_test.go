@@ -1,9 +1,12 @@
 package git
 
 import (
+	"bytes"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -31,6 +34,59 @@ func TestClone_InvalidURL(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestCloneWithOptions_Depth(t *testing.T) {
+	src := initTestRepo(t)
+
+	for _, msg := range []string{"second", "third"} {
+		cmd := exec.Command("git", "-C", src, "commit", "--allow-empty", "-m", msg)
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, "commit failed: %s", out)
+	}
+
+	dest := filepath.Join(t.TempDir(), "shallow")
+
+	// Local paths ignore --depth, so clone through the file transport.
+	require.NoError(t, CloneWithOptions("file://"+src, dest, CloneOptions{Depth: 1, SingleBranch: true}))
+
+	out, err := exec.Command("git", "-C", dest, "rev-parse", "--is-shallow-repository").Output()
+	require.NoError(t, err)
+	assert.Equal(t, "true", strings.TrimSpace(string(out)))
+
+	out, err = exec.Command("git", "-C", dest, "rev-list", "--count", "HEAD").Output()
+	require.NoError(t, err)
+	assert.Equal(t, "1", strings.TrimSpace(string(out)))
+}
+
+func TestCloneOptionsFlags(t *testing.T) {
+	assert.Empty(t, CloneOptions{}.Flags())
+	assert.Equal(t,
+		[]string{"--depth", "1", "--single-branch", "--filter=blob:none", "--progress"},
+		CloneOptions{Depth: 1, SingleBranch: true, Partial: true, Progress: io.Discard}.Flags(),
+	)
+}
+
+func TestCloneWithOptions_Partial(t *testing.T) {
+	src := initTestRepo(t)
+	dest := filepath.Join(t.TempDir(), "cloned")
+
+	require.NoError(t, CloneWithOptions("file://"+src, dest, CloneOptions{Partial: true}))
+
+	out, err := exec.Command("git", "-C", dest, "config", "remote.origin.partialclonefilter").Output()
+	require.NoError(t, err)
+	assert.Equal(t, "blob:none", strings.TrimSpace(string(out)))
+}
+
+func TestCloneWithOptions_Progress(t *testing.T) {
+	src := initTestRepo(t)
+	dest := filepath.Join(t.TempDir(), "cloned")
+
+	var progress bytes.Buffer
+
+	require.NoError(t, CloneWithOptions(src, dest, CloneOptions{Progress: &progress}))
+
+	assert.Contains(t, progress.String(), "Cloning into")
+}
+
 func TestDefaultBranch(t *testing.T) {
 	repo := initTestRepo(t)
 
@@ -63,3 +119,26 @@ func TestDefaultBranch_CustomBranch(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, "develop", branch)
 }
+
+func TestResolveBase(t *testing.T) {
+	local, _ := initTestRepoWithRemote(t, "feature")
+	noRemote := initTestRepo(t)
+
+	tests := []struct {
+		name string
+		repo string
+		base string
+		want string
+	}{
+		{name: "literal", repo: local, base: "develop", want: "develop"},
+		{name: "alias", repo: local, base: DefaultBaseAlias, want: "main"},
+		{name: "origin HEAD", repo: local, base: "origin/HEAD", want: "main"},
+		{name: "no remote falls back to HEAD", repo: noRemote, base: DefaultBaseAlias, want: "main"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ResolveBase(tt.repo, tt.base))
+		})
+	}
+}
@@ -11,18 +11,16 @@ import (
 
 // CopyFiles copies each file from repoPath to worktreePath, preserving
 // relative directory structure. Paths in files are relative to the repo
-// root. Files that do not exist in the source are skipped with a
-// warning returned in the warnings slice.
-func CopyFiles(repoPath, worktreePath string, files []string) []string {
-	var warnings []string
-
+// root. Files that do not exist in the source are skipped, with a
+// warning returned in missing; other failures are returned in warnings.
+func CopyFiles(repoPath, worktreePath string, files []string) (missing, warnings []string) {
 	for _, f := range files {
 		src := filepath.Join(repoPath, f)
 		dst := filepath.Join(worktreePath, f)
 
 		if err := copyFile(src, dst); err != nil {
 			if errors.Is(err, fs.ErrNotExist) {
-				warnings = append(warnings, fmt.Sprintf("copy: %s not found, skipping", f))
+				missing = append(missing, fmt.Sprintf("copy: %s not found, skipping", f))
 				continue
 			}
 
@@ -30,7 +28,7 @@ func CopyFiles(repoPath, worktreePath string, files []string) []string {
 		}
 	}
 
-	return warnings
+	return missing, warnings
 }
 
 // copyFile copies a single file from src to dst, creating parent
@@ -41,6 +39,12 @@ func copyFile(src, dst string) error {
 		return err
 	}
 
+	// Opening dst truncates it, which would empty src if they are the
+	// same file.
+	if dstInfo, err := os.Stat(dst); err == nil && os.SameFile(info, dstInfo) {
+		return fmt.Errorf("source and destination are the same file")
+	}
+
 	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
 		return fmt.Errorf("creating parent directory: %w", err)
 	}
@@ -18,7 +18,8 @@ func TestCopyFiles(t *testing.T) {
 	require.NoError(t, os.MkdirAll(filepath.Join(src, "config"), 0o755))
 	require.NoError(t, os.WriteFile(filepath.Join(src, "config", "local.yml"), []byte("db: local"), 0o644))
 
-	warnings := CopyFiles(src, dst, []string{".env", "config/local.yml"})
+	missing, warnings := CopyFiles(src, dst, []string{".env", "config/local.yml"})
+	assert.Empty(t, missing)
 	assert.Empty(t, warnings)
 
 	data, err := os.ReadFile(filepath.Join(dst, ".env"))
@@ -34,10 +35,27 @@ func TestCopyFiles_MissingSource(t *testing.T) {
 	src := t.TempDir()
 	dst := t.TempDir()
 
-	warnings := CopyFiles(src, dst, []string{"nonexistent.txt"})
+	missing, warnings := CopyFiles(src, dst, []string{"nonexistent.txt"})
 
+	assert.Empty(t, warnings)
+	require.Len(t, missing, 1)
+	assert.Contains(t, missing[0], "not found, skipping")
+}
+
+func TestCopyFiles_SameDirectory(t *testing.T) {
+	dir := t.TempDir()
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".env"), []byte("SECRET=abc"), 0o644))
+
+	missing, warnings := CopyFiles(dir, dir, []string{".env"})
+
+	assert.Empty(t, missing)
 	require.Len(t, warnings, 1)
-	assert.Contains(t, warnings[0], "not found, skipping")
+	assert.Contains(t, warnings[0], "same file")
+
+	data, err := os.ReadFile(filepath.Join(dir, ".env"))
+	require.NoError(t, err)
+	assert.Equal(t, "SECRET=abc", string(data))
 }
 
 func TestCopyFiles_PreservesPermissions(t *testing.T) {
@@ -46,7 +64,8 @@ func TestCopyFiles_PreservesPermissions(t *testing.T) {
 
 	require.NoError(t, os.WriteFile(filepath.Join(src, "script.sh"), []byte("#!/bin/sh"), 0o755))
 
-	warnings := CopyFiles(src, dst, []string{"script.sh"})
+	missing, warnings := CopyFiles(src, dst, []string{"script.sh"})
+	assert.Empty(t, missing)
 	assert.Empty(t, warnings)
 
 	info, err := os.Stat(filepath.Join(dst, "script.sh"))
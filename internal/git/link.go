@@ -12,17 +12,16 @@ import (
 // files. Each symlink points to the corresponding absolute path in
 // repoPath. Paths in files are relative to the repo root. Parent
 // directories in the worktree are created as needed. Files that do not
-// exist in the source are skipped with a warning.
-func SymlinkFiles(repoPath, worktreePath string, files []string) []string {
-	var warnings []string
-
+// exist in the source are skipped, with a warning returned in missing;
+// other failures are returned in warnings.
+func SymlinkFiles(repoPath, worktreePath string, files []string) (missing, warnings []string) {
 	for _, f := range files {
 		src := filepath.Join(repoPath, f)
 		dst := filepath.Join(worktreePath, f)
 
 		if err := symlinkFile(src, dst); err != nil {
 			if errors.Is(err, fs.ErrNotExist) {
-				warnings = append(warnings, fmt.Sprintf("symlink: %s not found, skipping", f))
+				missing = append(missing, fmt.Sprintf("symlink: %s not found, skipping", f))
 				continue
 			}
 
@@ -30,7 +29,7 @@ func SymlinkFiles(repoPath, worktreePath string, files []string) []string {
 		}
 	}
 
-	return warnings
+	return missing, warnings
 }
 
 // symlinkFile creates a symlink at dst pointing to the absolute path src.
@@ -18,7 +18,8 @@ func TestSymlinkFiles(t *testing.T) {
 	require.NoError(t, os.MkdirAll(filepath.Join(src, "config"), 0o755))
 	require.NoError(t, os.WriteFile(filepath.Join(src, "config", "local.yml"), []byte("db: local"), 0o644))
 
-	warnings := SymlinkFiles(src, dst, []string{".env", "config/local.yml"})
+	missing, warnings := SymlinkFiles(src, dst, []string{".env", "config/local.yml"})
+	assert.Empty(t, missing)
 	assert.Empty(t, warnings)
 
 	// Verify symlink targets.
@@ -40,10 +41,11 @@ func TestSymlinkFiles_MissingSource(t *testing.T) {
 	src := t.TempDir()
 	dst := t.TempDir()
 
-	warnings := SymlinkFiles(src, dst, []string{"nonexistent.txt"})
+	missing, warnings := SymlinkFiles(src, dst, []string{"nonexistent.txt"})
 
-	require.Len(t, warnings, 1)
-	assert.Contains(t, warnings[0], "not found, skipping")
+	assert.Empty(t, warnings)
+	require.Len(t, missing, 1)
+	assert.Contains(t, missing[0], "not found, skipping")
 }
 
 func TestSymlinkFiles_OverwritesExisting(t *testing.T) {
@@ -55,7 +57,8 @@ func TestSymlinkFiles_OverwritesExisting(t *testing.T) {
 	// Place a regular file at the destination first.
 	require.NoError(t, os.WriteFile(filepath.Join(dst, "file.txt"), []byte("old"), 0o644))
 
-	warnings := SymlinkFiles(src, dst, []string{"file.txt"})
+	missing, warnings := SymlinkFiles(src, dst, []string{"file.txt"})
+	assert.Empty(t, missing)
 	assert.Empty(t, warnings)
 
 	// Destination should now be a symlink, not the old regular file.
@@ -78,7 +81,8 @@ func TestSymlinkFiles_OverwritesExistingSymlink(t *testing.T) {
 	dstLink := filepath.Join(dst, "a.txt")
 	require.NoError(t, os.Symlink(fileB, dstLink))
 
-	warnings := SymlinkFiles(src, dst, []string{"a.txt"})
+	missing, warnings := SymlinkFiles(src, dst, []string{"a.txt"})
+	assert.Empty(t, missing)
 	assert.Empty(t, warnings)
 
 	// Symlink should now point to fileA, not fileB.
@@ -4,9 +4,13 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"io"
 	"os/exec"
 	"path/filepath"
+	"slices"
 	"strings"
+
+	"github.com/mhamza15/forest/internal/network"
 )
 
 // Remotes returns the names of all configured remotes for the
@@ -74,13 +78,124 @@ func NormalizeRemoteURL(raw string) string {
 // localBranch differs from remoteBranch, it creates a local tracking
 // branch. This is used to pull PR branches from forks.
 func Fetch(repoPath, remoteURL, remoteBranch, localBranch string) error {
+	return FetchWithProgress(repoPath, remoteURL, remoteBranch, localBranch, nil)
+}
+
+// FetchWithProgress is like Fetch but streams git's progress output to
+// progress when it is non-nil, so long fetches do not appear hung.
+func FetchWithProgress(repoPath, remoteURL, remoteBranch, localBranch string, progress io.Writer) error {
 	refspec := fmt.Sprintf("refs/heads/%s:refs/heads/%s", remoteBranch, localBranch)
 
-	cmd := exec.Command("git", "-C", repoPath, "fetch", remoteURL, refspec)
+	args := []string{"-C", repoPath, "fetch"}
+	if progress != nil {
+		args = append(args, "--progress")
+	}
+	args = append(args, remoteURL, refspec)
+
+	return network.Retry(func() error {
+		return runFetch(args, progress)
+	})
+}
+
+// FetchCommit fetches the commit sha from a remote URL without
+// updating any refs, so that it can be checked out detached. Progress
+// is streamed to progress when it is non-nil.
+func FetchCommit(repoPath, remoteURL, sha string, progress io.Writer) error {
+	args := []string{"-C", repoPath, "fetch", "--no-tags"}
+	if progress != nil {
+		args = append(args, "--progress")
+	}
+	args = append(args, remoteURL, sha)
+
+	return network.Retry(func() error {
+		return runFetch(args, progress)
+	})
+}
+
+// runFetch runs a single git fetch attempt with args. A missing remote
+// ref is marked permanent so that it is not retried. A fetch streaming
+// its progress is not subject to the network timeout.
+func runFetch(args []string, progress io.Writer) error {
+	ctx, cancel := network.StreamContext(progress != nil)
+	defer cancel()
+
+	cmd := network.Command(ctx, "git", args...)
+
+	output, err := runWithProgress(cmd, progress)
+	if err != nil {
+		err = network.CommandError("git fetch", output, progress != nil, network.Error(ctx, err))
+
+		if bytes.Contains(output, []byte("couldn't find remote ref")) {
+			return network.Permanent(err)
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// runWithProgress runs cmd and returns its combined output. When
+// progress is non-nil, stderr (where git reports progress) is also
+// streamed to it as the command runs.
+func runWithProgress(cmd *exec.Cmd, progress io.Writer) ([]byte, error) {
+	if progress == nil {
+		return cmd.CombinedOutput()
+	}
+
+	var buf bytes.Buffer
+
+	cmd.Stdout = &buf
+	cmd.Stderr = io.MultiWriter(&buf, progress)
+
+	err := cmd.Run()
+
+	return buf.Bytes(), err
+}
+
+// AddRemote adds a named remote. Unlike EnsureRemote, it is an error
+// if the remote already exists.
+func AddRemote(repoPath, name, url string) error {
+	cmd := exec.Command("git", "-C", repoPath, "remote", "add", name, url)
 
 	output, err := cmd.CombinedOutput()
 	if err != nil {
-		return fmt.Errorf("git fetch: %s: %w", bytes.TrimSpace(output), err)
+		return fmt.Errorf("git remote add: %s: %w", bytes.TrimSpace(output), err)
+	}
+
+	return nil
+}
+
+// SetRemote adds a named remote, or points it at url when it already
+// exists.
+func SetRemote(repoPath, name, url string) error {
+	remotes, err := Remotes(repoPath)
+	if err != nil {
+		return err
+	}
+
+	if !slices.Contains(remotes, name) {
+		return AddRemote(repoPath, name, url)
+	}
+
+	cmd := exec.Command("git", "-C", repoPath, "remote", "set-url", name, url)
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("git remote set-url: %s: %w", bytes.TrimSpace(output), err)
+	}
+
+	return nil
+}
+
+// RenameRemote renames a remote, updating its remote tracking refs and
+// the upstream configuration of branches that track it.
+func RenameRemote(repoPath, oldName, newName string) error {
+	cmd := exec.Command("git", "-C", repoPath, "remote", "rename", oldName, newName)
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("git remote rename: %s: %w", bytes.TrimSpace(output), err)
 	}
 
 	return nil
@@ -108,14 +223,55 @@ func EnsureRemote(repoPath, name, url string) error {
 // FetchBranch fetches a single branch from a named remote, updating
 // the corresponding remote tracking ref (e.g. refs/remotes/<remote>/<branch>).
 func FetchBranch(repoPath, remote, branch string) error {
-	cmd := exec.Command("git", "-C", repoPath, "fetch", remote, branch)
+	return FetchBranchWithProgress(repoPath, remote, branch, nil)
+}
 
-	output, err := cmd.CombinedOutput()
+// FetchBranchWithProgress is like FetchBranch but streams git's
+// progress output to progress when it is non-nil.
+func FetchBranchWithProgress(repoPath, remote, branch string, progress io.Writer) error {
+	args := []string{"-C", repoPath, "fetch"}
+	if progress != nil {
+		args = append(args, "--progress")
+	}
+	args = append(args, remote, branch)
+
+	return network.Retry(func() error {
+		return runFetch(args, progress)
+	})
+}
+
+// RemoteRefExists reports whether the remote tracking ref
+// refs/remotes/<remote>/<branch> exists locally, meaning the branch
+// was fetched before and can be used without another fetch.
+func RemoteRefExists(repoPath, remote, branch string) bool {
+	cmd := exec.Command("git", "-C", repoPath, "rev-parse", "--verify", "--quiet", "refs/remotes/"+remote+"/"+branch)
+	return cmd.Run() == nil
+}
+
+// RemoteForURL returns the name of the first remote whose URL refers to
+// the same repository as url, comparing normalized "owner/repo" forms
+// so that HTTPS and SSH URLs match. It returns an empty string if no
+// remote matches.
+func RemoteForURL(repoPath, url string) string {
+	remotes, err := Remotes(repoPath)
 	if err != nil {
-		return fmt.Errorf("git fetch: %s: %w", bytes.TrimSpace(output), err)
+		return ""
 	}
 
-	return nil
+	want := NormalizeRemoteURL(url)
+
+	for _, remote := range remotes {
+		remoteURL, err := RemoteURL(repoPath, remote)
+		if err != nil {
+			continue
+		}
+
+		if NormalizeRemoteURL(remoteURL) == want {
+			return remote
+		}
+	}
+
+	return ""
 }
 
 // CreateTrackingBranch creates a local branch from the given remote
@@ -147,6 +303,78 @@ func CreateTrackingBranch(repoPath, branch, remote, remoteBranch string) error {
 	return nil
 }
 
+// FetchUpstream fetches the upstream branch of a local branch,
+// updating its remote tracking ref. It does nothing when the branch
+// has no upstream on a remote.
+func FetchUpstream(repoPath, branch string) error {
+	remote, _, err := branchConfigValue(repoPath, branch, "remote")
+	if err != nil {
+		return err
+	}
+
+	merge, _, err := branchConfigValue(repoPath, branch, "merge")
+	if err != nil {
+		return err
+	}
+
+	if remote == "" || remote == "." || merge == "" {
+		return nil
+	}
+
+	remoteBranch := strings.TrimPrefix(merge, "refs/heads/")
+
+	if err := fetchRemoteBranch(repoPath, remote, remoteBranch); err != nil {
+		return fmt.Errorf("fetching %q from %s: %w", remoteBranch, remote, err)
+	}
+
+	return nil
+}
+
+// FastForward fetches the upstream of a local branch and moves the
+// branch forward to it without checking it out. Branches without an
+// upstream are left alone. It returns ErrDiverged when the update is
+// not a fast-forward. A branch checked out in a worktree is only
+// updated when that worktree is clean, by merging there with
+// --ff-only so that its files follow.
+func FastForward(repoPath, branch string) error {
+	if err := FetchUpstream(repoPath, branch); err != nil {
+		return err
+	}
+
+	upstream := Upstream(repoPath, branch)
+	if upstream == "" {
+		return nil
+	}
+
+	if !IsMerged(repoPath, branch, upstream) {
+		return fmt.Errorf("fast-forwarding %s to %s: %w", branch, upstream, ErrDiverged)
+	}
+
+	var cmd *exec.Cmd
+
+	if wt := FindByBranch(repoPath, branch); wt != nil {
+		dirty, err := IsDirty(wt.Path)
+		if err != nil {
+			return err
+		}
+
+		if dirty {
+			return fmt.Errorf("fast-forwarding %s checked out at %s: %w", branch, wt.Path, ErrWorktreeDirty)
+		}
+
+		cmd = exec.Command("git", "-C", wt.Path, "merge", "--ff-only", "--quiet", upstream)
+	} else {
+		cmd = exec.Command("git", "-C", repoPath, "update-ref", "refs/heads/"+branch, upstream)
+	}
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("fast-forwarding %s: %s: %w", branch, bytes.TrimSpace(output), err)
+	}
+
+	return nil
+}
+
 // SetBranchUpstream configures the upstream branch for the given local
 // branch by writing the same remote and merge keys that Git uses for
 // tracked branches.
@@ -162,6 +390,21 @@ func SetBranchUpstream(repoPath, branch, remote, remoteBranch string) error {
 	return nil
 }
 
+// SetBranchBase records the base branch a branch was created from in
+// the branch's git config (branch.<name>.forestBase). Prune uses it to
+// check merge status against the branch's actual base rather than the
+// project default.
+func SetBranchBase(repoPath, branch, base string) error {
+	return setBranchConfig(repoPath, branch, "forestBase", base)
+}
+
+// GetBranchBase returns the base branch recorded by SetBranchBase, or
+// an empty string if none was recorded.
+func GetBranchBase(repoPath, branch string) (string, error) {
+	base, _, err := branchConfigValue(repoPath, branch, "forestBase")
+	return base, err
+}
+
 func setBranchConfig(repoPath, branch, key, value string) error {
 	name := fmt.Sprintf("branch.%s.%s", branch, key)
 
@@ -238,6 +481,40 @@ func EnableWorktreeConfig(repoPath string) error {
 	return nil
 }
 
+// CopyConfig copies the values of the given git config keys, as seen
+// from the repository, into the worktree-specific config of the
+// worktree at worktreePath. This lets each worktree diverge later, for
+// example with its own user.email. Keys without a value are skipped.
+func CopyConfig(repoPath, worktreePath string, keys []string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	if err := EnableWorktreeConfig(repoPath); err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		cmd := exec.Command("git", "-C", repoPath, "config", "--get", key)
+
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+				continue
+			}
+
+			return fmt.Errorf("git config --get %s: %s: %w", key, bytes.TrimSpace(output), err)
+		}
+
+		if err := setWorktreeConfig(worktreePath, key, strings.TrimSpace(string(output))); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func repoConfigEnabled(repoPath, key string) (bool, error) {
 	cmd := exec.Command("git", "-C", repoPath, "config", "--get", "--bool", key)
 
@@ -313,16 +590,39 @@ func unsetWorktreeConfig(worktreePath, key string) error {
 func FetchRemoteBranch(repoPath, branch string) (string, error) {
 	remotes, err := Remotes(repoPath)
 	if err != nil || len(remotes) == 0 {
-		return "", fmt.Errorf("no remotes configured")
+		return "", ErrNoRemotes
 	}
 
 	for _, remote := range remotes {
-		cmd := exec.Command("git", "-C", repoPath, "fetch", remote, branch)
-
-		if _, err := cmd.CombinedOutput(); err == nil {
+		if err := fetchRemoteBranch(repoPath, remote, branch); err == nil {
 			return remote, nil
+		} else if errors.Is(err, network.ErrTimeout) {
+			return "", fmt.Errorf("fetching %q from %s: %w", branch, remote, err)
 		}
 	}
 
-	return "", fmt.Errorf("branch %q not found on any remote", branch)
+	return "", fmt.Errorf("%w on any remote: %s", ErrBranchNotFound, branch)
+}
+
+// FetchRemoteBranchFrom is like FetchRemoteBranch but only tries the
+// named remote, for branches known to belong to one repository.
+func FetchRemoteBranchFrom(repoPath, remote, branch string) error {
+	err := fetchRemoteBranch(repoPath, remote, branch)
+
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, network.ErrTimeout):
+		return fmt.Errorf("fetching %q from %s: %w", branch, remote, err)
+	default:
+		return fmt.Errorf("%w on %s: %s", ErrBranchNotFound, remote, branch)
+	}
+}
+
+func fetchRemoteBranch(repoPath, remote, branch string) error {
+	args := []string{"-C", repoPath, "fetch", remote, branch}
+
+	return network.Retry(func() error {
+		return runFetch(args, nil)
+	})
 }
@@ -1,14 +1,19 @@
 package git
 
 import (
+	"bytes"
 	"errors"
+	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/mhamza15/forest/internal/network"
 )
 
 func TestNormalizeRemoteURL(t *testing.T) {
@@ -164,6 +169,145 @@ func TestConfigureWorktreePush_SameBranchName(t *testing.T) {
 	assert.False(t, enabled)
 }
 
+func TestBranchBase(t *testing.T) {
+	repo := initTestRepo(t)
+
+	cmd := exec.Command("git", "-C", repo, "branch", "feature/login", "main")
+	output, err := cmd.CombinedOutput()
+	require.NoError(t, err, "git branch: %s", output)
+
+	base, err := GetBranchBase(repo, "feature/login")
+	require.NoError(t, err)
+	assert.Empty(t, base)
+
+	require.NoError(t, SetBranchBase(repo, "feature/login", "develop"))
+
+	base, err = GetBranchBase(repo, "feature/login")
+	require.NoError(t, err)
+	assert.Equal(t, "develop", base)
+}
+
+func TestAddAndRenameRemote(t *testing.T) {
+	local, remote := initTestRepoWithRemote(t, "feature")
+
+	require.NoError(t, RenameRemote(local, "origin", "fork"))
+	require.NoError(t, AddRemote(local, "upstream", remote))
+
+	remotes, err := Remotes(local)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"fork", "upstream"}, remotes)
+
+	// The default branch follows the renamed remote.
+	assert.Equal(t, "fork", branchConfig(t, local, "main", "remote"))
+
+	// Adding an existing remote is an error, unlike EnsureRemote.
+	assert.Error(t, AddRemote(local, "upstream", remote))
+
+	// SetRemote repoints an existing remote and adds a missing one.
+	require.NoError(t, SetRemote(local, "upstream", "https://github.com/owner/repo.git"))
+	require.NoError(t, SetRemote(local, "other", remote))
+
+	url, err := RemoteURL(local, "upstream")
+	require.NoError(t, err)
+	assert.Equal(t, "https://github.com/owner/repo.git", url)
+
+	url, err = RemoteURL(local, "other")
+	require.NoError(t, err)
+	assert.Equal(t, remote, url)
+}
+
+func TestRemoteRefExistsAndRemoteForURL(t *testing.T) {
+	local, remote := initTestRepoWithRemote(t, "feature")
+
+	// A fresh clone has tracking refs for every remote branch.
+	assert.True(t, RemoteRefExists(local, "origin", "feature"))
+	assert.False(t, RemoteRefExists(local, "origin", "missing"))
+
+	assert.Equal(t, "origin", RemoteForURL(local, remote))
+	assert.Empty(t, RemoteForURL(local, "https://github.com/acme/other.git"))
+}
+
+func TestFetchBranchWithProgress_NotTimedOut(t *testing.T) {
+	local, _ := initTestRepoWithRemote(t, "feature")
+
+	network.SetTimeout(time.Nanosecond)
+	network.SetRetries(0)
+
+	t.Cleanup(func() {
+		network.SetTimeout(network.DefaultTimeout)
+		network.SetRetries(network.DefaultRetries)
+	})
+
+	// Without progress the fetch is cancelled by the timeout.
+	require.ErrorIs(t, FetchBranch(local, "origin", "feature"), network.ErrTimeout)
+
+	var progress bytes.Buffer
+	require.NoError(t, FetchBranchWithProgress(local, "origin", "feature", &progress))
+	assert.True(t, RemoteRefExists(local, "origin", "feature"))
+}
+
+func TestFetchBranchWithProgress_ErrorOmitsProgress(t *testing.T) {
+	local, _ := initTestRepoWithRemote(t, "feature")
+
+	var progress bytes.Buffer
+
+	err := FetchBranchWithProgress(local, "origin", "missing", &progress)
+	require.Error(t, err)
+	assert.Contains(t, progress.String(), "couldn't find remote ref")
+	assert.NotContains(t, err.Error(), "couldn't find remote ref")
+
+	// Without progress, the output is the only place to report it.
+	err = FetchBranch(local, "origin", "missing")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "couldn't find remote ref")
+}
+
+func TestCopyConfig(t *testing.T) {
+	repo := initTestRepo(t)
+	runGit(t, repo, "config", "user.email", "repo@example.com")
+
+	wtPath := filepath.Join(t.TempDir(), "feature")
+	require.NoError(t, Add(repo, wtPath, "feature", "main"))
+
+	require.NoError(t, CopyConfig(repo, wtPath, []string{"user.email", "missing.key"}))
+
+	source, value := worktreeConfigValue(t, wtPath, "user.email")
+	assert.Equal(t, "repo@example.com", value)
+	assert.Contains(t, source, "config.worktree")
+
+	_, _, ok := worktreeConfigLookup(t, wtPath, "missing.key")
+	assert.False(t, ok)
+}
+
+func TestFastForward(t *testing.T) {
+	local, remote := initTestRepoWithRemote(t, "feature")
+	runGit(t, local, "config", "user.email", "test@test.com")
+	runGit(t, local, "config", "user.name", "test")
+	runGit(t, local, "branch", "--track", "feature", "origin/feature")
+
+	runGit(t, remote, "commit", "--allow-empty", "-m", "main work")
+	runGit(t, remote, "checkout", "feature")
+	runGit(t, remote, "commit", "--allow-empty", "-m", "feature work")
+	runGit(t, remote, "checkout", "main")
+
+	// feature is not checked out, so its ref is updated directly.
+	require.NoError(t, FastForward(local, "feature"))
+	assert.Equal(t, runGit(t, remote, "rev-parse", "feature"), runGit(t, local, "rev-parse", "feature"))
+
+	// main is checked out in a dirty worktree and is left alone.
+	require.NoError(t, os.WriteFile(filepath.Join(local, "wip.txt"), []byte("wip"), 0o644))
+	require.ErrorIs(t, FastForward(local, "main"), ErrWorktreeDirty)
+
+	require.NoError(t, os.Remove(filepath.Join(local, "wip.txt")))
+	require.NoError(t, FastForward(local, "main"))
+	assert.Equal(t, runGit(t, remote, "rev-parse", "main"), runGit(t, local, "rev-parse", "HEAD"))
+
+	// A diverged branch would need a merge.
+	runGit(t, remote, "commit", "--allow-empty", "-m", "more main work")
+	runGit(t, local, "commit", "--allow-empty", "-m", "local work")
+	assert.ErrorIs(t, FastForward(local, "main"), ErrDiverged)
+}
+
 func branchConfig(t *testing.T, repoPath, branch, key string) string {
 	t.Helper()
 
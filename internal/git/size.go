@@ -0,0 +1,43 @@
+package git
+
+import (
+	"io/fs"
+	"path/filepath"
+)
+
+// WorktreeSize returns the total size in bytes of the regular files in
+// the worktree at path. The .git entry at the worktree root is skipped
+// because its object storage is shared with the main repository.
+// Symlinks are not followed, so linked files are not double-counted.
+func WorktreeSize(path string) (int64, error) {
+	var total int64
+
+	err := filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.Name() == ".git" && filepath.Dir(p) == filepath.Clean(path) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		total += info.Size()
+
+		return nil
+	})
+
+	return total, err
+}
@@ -0,0 +1,33 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorktreeSize(t *testing.T) {
+	dir := t.TempDir()
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.txt"), make([]byte, 100), 0o644))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "sub"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "sub", "b.txt"), make([]byte, 50), 0o644))
+
+	// Symlinks and .git metadata are not counted.
+	require.NoError(t, os.Symlink(filepath.Join(dir, "a.txt"), filepath.Join(dir, "link.txt")))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, ".git"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".git", "objects"), make([]byte, 1000), 0o644))
+
+	size, err := WorktreeSize(dir)
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(150), size)
+}
+
+func TestWorktreeSize_MissingPath(t *testing.T) {
+	_, err := WorktreeSize(filepath.Join(t.TempDir(), "missing"))
+	assert.Error(t, err)
+}
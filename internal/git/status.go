@@ -0,0 +1,109 @@
+package git
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CommitInfo describes a single commit.
+type CommitInfo struct {
+	// Hash is the full commit hash.
+	Hash string
+
+	// Subject is the first line of the commit message.
+	Subject string
+
+	// Author is the commit author's name.
+	Author string
+
+	// Date is the author date.
+	Date time.Time
+}
+
+// IsDirty reports whether the worktree at path has modified, staged,
+// or untracked files.
+func IsDirty(path string) (bool, error) {
+	cmd := exec.Command("git", "-C", path, "status", "--porcelain")
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return false, fmt.Errorf("git status: %s: %w", bytes.TrimSpace(output), err)
+	}
+
+	return len(bytes.TrimSpace(output)) > 0, nil
+}
+
+// AheadBehind returns how many commits ref has that other does not
+// (ahead), and how many commits other has that ref does not (behind).
+func AheadBehind(repoPath, ref, other string) (int, int, error) {
+	cmd := exec.Command("git", "-C", repoPath, "rev-list", "--left-right", "--count", ref+"..."+other)
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return 0, 0, fmt.Errorf("git rev-list: %s: %w", bytes.TrimSpace(output), err)
+	}
+
+	fields := strings.Fields(string(output))
+	if len(fields) != 2 {
+		return 0, 0, fmt.Errorf("git rev-list: unexpected output %q", bytes.TrimSpace(output))
+	}
+
+	ahead, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return 0, 0, fmt.Errorf("parsing ahead count: %w", err)
+	}
+
+	behind, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return 0, 0, fmt.Errorf("parsing behind count: %w", err)
+	}
+
+	return ahead, behind, nil
+}
+
+// Upstream returns the upstream ref configured for branch (e.g.
+// "origin/feature"), or an empty string if none is set.
+func Upstream(repoPath, branch string) string {
+	cmd := exec.Command("git", "-C", repoPath, "rev-parse", "--abbrev-ref", "--symbolic-full-name", branch+"@{upstream}")
+
+	output, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimSpace(string(output))
+}
+
+// LastCommit returns the commit checked out at HEAD in the given
+// worktree.
+func LastCommit(path string) (CommitInfo, error) {
+	// Separate fields with NUL so subjects and names can contain any
+	// printable character.
+	cmd := exec.Command("git", "-C", path, "log", "-1", "--format=%H%x00%s%x00%an%x00%aI")
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return CommitInfo{}, fmt.Errorf("git log: %s: %w", bytes.TrimSpace(output), err)
+	}
+
+	parts := strings.SplitN(strings.TrimSpace(string(output)), "\x00", 4)
+	if len(parts) != 4 {
+		return CommitInfo{}, fmt.Errorf("git log: unexpected output %q", bytes.TrimSpace(output))
+	}
+
+	date, err := time.Parse(time.RFC3339, parts[3])
+	if err != nil {
+		return CommitInfo{}, fmt.Errorf("parsing commit date: %w", err)
+	}
+
+	return CommitInfo{
+		Hash:    parts[0],
+		Subject: parts[1],
+		Author:  parts[2],
+		Date:    date,
+	}, nil
+}
@@ -0,0 +1,78 @@
+package git
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsDirty(t *testing.T) {
+	repo := initTestRepo(t)
+
+	dirty, err := IsDirty(repo)
+	require.NoError(t, err)
+	assert.False(t, dirty)
+
+	require.NoError(t, os.WriteFile(filepath.Join(repo, "new.txt"), []byte("hi"), 0o644))
+
+	dirty, err = IsDirty(repo)
+	require.NoError(t, err)
+	assert.True(t, dirty)
+}
+
+func TestAheadBehind(t *testing.T) {
+	repo := initTestRepo(t)
+
+	commands := [][]string{
+		{"git", "branch", "feature"},
+		{"git", "commit", "--allow-empty", "-m", "main only"},
+		{"git", "checkout", "feature"},
+		{"git", "commit", "--allow-empty", "-m", "feature one"},
+		{"git", "commit", "--allow-empty", "-m", "feature two"},
+	}
+
+	for _, args := range commands {
+		cmd := exec.Command(args[0], args[1:]...)
+		cmd.Dir = repo
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, "command %v failed: %s", args, out)
+	}
+
+	ahead, behind, err := AheadBehind(repo, "feature", "main")
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, ahead)
+	assert.Equal(t, 1, behind)
+}
+
+func TestUpstream(t *testing.T) {
+	local, _ := initTestRepoWithRemote(t, "feature")
+
+	assert.Equal(t, "origin/main", Upstream(local, "main"))
+
+	cmd := exec.Command("git", "-C", local, "branch", "--no-track", "local-only", "main")
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err, "git branch: %s", out)
+
+	assert.Empty(t, Upstream(local, "local-only"))
+}
+
+func TestLastCommit(t *testing.T) {
+	repo := initTestRepo(t)
+
+	cmd := exec.Command("git", "-C", repo, "commit", "--allow-empty", "-m", "add: login page")
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err, "git commit: %s", out)
+
+	commit, err := LastCommit(repo)
+	require.NoError(t, err)
+
+	assert.Len(t, commit.Hash, 40)
+	assert.Equal(t, "add: login page", commit.Subject)
+	assert.Equal(t, "test", commit.Author)
+	assert.False(t, commit.Date.IsZero())
+}
@@ -10,12 +10,26 @@ import (
 	"os/exec"
 	"path/filepath"
 	"strings"
+
+	"github.com/mhamza15/forest/internal/network"
 )
 
 // ErrWorktreeDirty is returned when a worktree has modified or untracked
 // files and cannot be removed without --force.
 var ErrWorktreeDirty = errors.New("worktree contains modified or untracked files")
 
+// ErrBranchNotFound is returned when a branch does not exist where it
+// was looked up, such as on any of the repository's remotes.
+var ErrBranchNotFound = errors.New("branch not found")
+
+// ErrNoRemotes is returned when an operation needs a remote but the
+// repository has none configured.
+var ErrNoRemotes = errors.New("no remotes configured")
+
+// ErrDiverged is returned when a branch has commits that its upstream
+// does not, so updating it would require a merge.
+var ErrDiverged = errors.New("branch has diverged from its upstream")
+
 // SafeBranchDir converts a branch name into a flat directory name by
 // replacing path separators with dashes. Without this, a branch like
 // feature/login would create nested directories.
@@ -36,21 +50,59 @@ type Worktree struct {
 	Bare bool
 }
 
-// Add creates a new worktree at worktreePath for the given branch.
-// It picks the appropriate strategy based on where the branch exists:
+// AddOptions controls optional git worktree add behavior. The zero
+// value matches plain "git worktree add".
+type AddOptions struct {
+	// NoCheckout creates the worktree without populating its files,
+	// which is useful for quickly creating a worktree skeleton.
+	NoCheckout bool
+
+	// Track is a remote tracking ref (e.g. "upstream/feature") to
+	// create the new branch from with upstream tracking configured.
+	// When empty, the tracking ref is detected automatically.
+	Track string
+
+	// NoTrack skips remote tracking ref detection, so a branch that
+	// does not exist locally is always created off the base branch.
+	NoTrack bool
+
+	// Force allows creating the worktree even if the branch is
+	// already checked out elsewhere or the path is registered to a
+	// missing worktree.
+	Force bool
+}
+
+// Add creates a new worktree at worktreePath for the given branch
+// using default options. See AddWithOptions.
+func Add(repoPath, worktreePath, branch, baseBranch string) error {
+	return AddWithOptions(repoPath, worktreePath, branch, baseBranch, AddOptions{})
+}
+
+// AddWithOptions creates a new worktree at worktreePath for the given
+// branch. It picks the appropriate strategy based on where the branch
+// exists:
 //
 //   - Local branch exists: checked out into the new worktree.
 //   - Remote tracking ref exists (e.g., after a fetch): a local
 //     branch is created with --track so that upstream is set.
 //   - Neither exists: a new branch is created off baseBranch.
-func Add(repoPath, worktreePath, branch, baseBranch string) error {
-	var args []string
+func AddWithOptions(repoPath, worktreePath, branch, baseBranch string, opts AddOptions) error {
+	args := []string{"-C", repoPath, "worktree", "add"}
+
+	if opts.Force {
+		args = append(args, "--force")
+	}
+
+	if opts.NoCheckout {
+		args = append(args, "--no-checkout")
+	}
+
 	if BranchExists(repoPath, branch) {
-		args = []string{"-C", repoPath, "worktree", "add", worktreePath, branch}
-	} else if ref := remoteTrackingRef(repoPath, branch); ref != "" {
-		args = []string{"-C", repoPath, "worktree", "add", "--track", "-b", branch, worktreePath, ref}
+		args = append(args, worktreePath, branch)
+	} else if ref := trackingRef(repoPath, branch, opts.Track); ref != "" && !opts.NoTrack {
+		args = append(args, "--track", "-b", branch, worktreePath, ref)
 	} else {
-		args = []string{"-C", repoPath, "worktree", "add", "-b", branch, worktreePath, baseBranch}
+		args = append(args, "-b", branch, worktreePath, baseBranch)
 	}
 
 	cmd := exec.Command("git", args...)
@@ -61,6 +113,20 @@ func Add(repoPath, worktreePath, branch, baseBranch string) error {
 	return nil
 }
 
+// AddDetached creates a new worktree at worktreePath with a detached
+// HEAD at ref, which may be any commit-ish such as a tag or commit
+// hash. No branch is created.
+func AddDetached(repoPath, worktreePath, ref string) error {
+	cmd := exec.Command("git", "-C", repoPath, "worktree", "add", "--detach", worktreePath, ref)
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("git worktree add: %s: %w", bytes.TrimSpace(output), err)
+	}
+
+	return nil
+}
+
 // BranchExists returns true if a local branch with the given name
 // exists in the repository.
 func BranchExists(repoPath, branch string) bool {
@@ -68,6 +134,47 @@ func BranchExists(repoPath, branch string) bool {
 	return cmd.Run() == nil
 }
 
+// TagExists returns true if a tag with the given name exists in the
+// repository. Both annotated and lightweight tags are accepted.
+func TagExists(repoPath, tag string) bool {
+	cmd := exec.Command("git", "-C", repoPath, "rev-parse", "--verify", "--quiet", "refs/tags/"+tag)
+	return cmd.Run() == nil
+}
+
+// CommitExists returns true if sha names a commit present in the
+// repository.
+func CommitExists(repoPath, sha string) bool {
+	cmd := exec.Command("git", "-C", repoPath, "rev-parse", "--verify", "--quiet", sha+"^{commit}")
+	return cmd.Run() == nil
+}
+
+// trackingRef returns the explicit track ref when set, otherwise the
+// detected remote tracking ref for branch.
+func trackingRef(repoPath, branch, track string) string {
+	if track != "" {
+		return track
+	}
+
+	return remoteTrackingRef(repoPath, branch)
+}
+
+// ValidateBranchName checks that name is a valid name for a new branch
+// in the repository at repoPath using git check-ref-format, so that
+// malformed names produce a friendly error instead of a raw git
+// failure during worktree creation.
+func ValidateBranchName(repoPath, name string) error {
+	if strings.TrimSpace(name) != name {
+		return fmt.Errorf("invalid branch name %q: leading or trailing whitespace", name)
+	}
+
+	cmd := exec.Command("git", "-C", repoPath, "check-ref-format", "--branch", name)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("invalid branch name %q", name)
+	}
+
+	return nil
+}
+
 // remoteTrackingRef returns the short remote tracking ref for the
 // given branch (e.g., "origin/feature"), or an empty string if no
 // remote tracks this branch. When multiple remotes track the same
@@ -95,6 +202,18 @@ func remoteTrackingRef(repoPath, branch string) string {
 	return ref
 }
 
+// ValidateRepo returns an error if path is not inside a git
+// repository.
+func ValidateRepo(path string) error {
+	cmd := exec.Command("git", "-C", path, "rev-parse", "--git-dir")
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s is not a git repository", path)
+	}
+
+	return nil
+}
+
 // CurrentBranch returns the branch name checked out in the given
 // directory, or an empty string if it cannot be determined (e.g.
 // detached HEAD or not a git directory).
@@ -140,11 +259,25 @@ func IsMerged(repoPath, branch, target string) bool {
 // given remote. It calls git ls-remote --heads once and parses all
 // results, making it efficient for checking many branches.
 func RemoteBranches(repoPath, remote string) (map[string]bool, error) {
-	cmd := exec.Command("git", "-C", repoPath, "ls-remote", "--heads", remote)
+	var output []byte
 
-	output, err := cmd.Output()
+	err := network.Retry(func() error {
+		ctx, cancel := network.Context()
+		defer cancel()
+
+		cmd := network.Command(ctx, "git", "-C", repoPath, "ls-remote", "--heads", remote)
+
+		var err error
+
+		output, err = cmd.Output()
+		if err != nil {
+			return fmt.Errorf("git ls-remote: %w", network.Error(ctx, err))
+		}
+
+		return nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("git ls-remote: %w", err)
+		return nil, err
 	}
 
 	branches := make(map[string]bool)
@@ -238,6 +371,20 @@ func removeWorktree(repoPath, worktreePath string, force bool) error {
 	return nil
 }
 
+// Repair runs git worktree repair in the repository at repoPath. After
+// the repository has been moved, this points its linked worktrees back
+// at it.
+func Repair(repoPath string) error {
+	cmd := exec.Command("git", "-C", repoPath, "worktree", "repair")
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("git worktree repair: %s: %w", bytes.TrimSpace(output), err)
+	}
+
+	return nil
+}
+
 // List returns all worktrees for the repository at repoPath by parsing
 // the porcelain output of git worktree list.
 func List(repoPath string) ([]Worktree, error) {
@@ -285,6 +432,38 @@ func FindByPath(repoPath, path string) *Worktree {
 	return nil
 }
 
+// ForeignWorktree reports whether path is the root of a git worktree
+// or repository that belongs to a repository other than repoPath. If
+// so, it also returns the other repository's common git directory.
+// Paths that merely sit inside some other repository are not foreign.
+func ForeignWorktree(repoPath, path string) (string, bool) {
+	root := WorktreeRoot(path)
+	if root == "" || !samePath(root, path) {
+		return "", false
+	}
+
+	other := commonDir(path)
+	if other == "" || samePath(other, commonDir(repoPath)) {
+		return "", false
+	}
+
+	return other, true
+}
+
+// commonDir returns the absolute path to the git directory shared by
+// all worktrees of the repository containing dir, or an empty string
+// if it cannot be determined.
+func commonDir(dir string) string {
+	cmd := exec.Command("git", "-C", dir, "rev-parse", "--path-format=absolute", "--git-common-dir")
+
+	output, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimSpace(string(output))
+}
+
 func samePath(left, right string) bool {
 	if filepath.Clean(left) == filepath.Clean(right) {
 		return true
@@ -88,6 +88,47 @@ func TestAdd_InvalidBase(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestAddWithOptions_NoCheckout(t *testing.T) {
+	repo := initTestRepo(t)
+
+	require.NoError(t, os.WriteFile(filepath.Join(repo, "README.md"), []byte("hello"), 0o644))
+
+	for _, args := range [][]string{{"add", "README.md"}, {"commit", "-m", "add readme"}} {
+		cmd := exec.Command("git", append([]string{"-C", repo}, args...)...)
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, "git %v: %s", args, out)
+	}
+
+	wtPath := filepath.Join(t.TempDir(), "skeleton")
+
+	require.NoError(t, AddWithOptions(repo, wtPath, "skeleton", "main", AddOptions{NoCheckout: true}))
+
+	// The worktree is registered and its .git link exists, but the
+	// tracked files are not materialized.
+	_, err := os.Stat(filepath.Join(wtPath, ".git"))
+	require.NoError(t, err)
+
+	_, err = os.Stat(filepath.Join(wtPath, "README.md"))
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	assert.NotNil(t, FindByBranch(repo, "skeleton"))
+}
+
+func TestAddWithOptions_ExplicitTrack(t *testing.T) {
+	local, _ := initTestRepoWithRemote(t, "feature")
+	wtPath := filepath.Join(t.TempDir(), "local-feature")
+
+	_, err := FetchRemoteBranch(local, "feature")
+	require.NoError(t, err)
+
+	require.NoError(t, AddWithOptions(local, wtPath, "local-feature", "main", AddOptions{Track: "origin/feature"}))
+
+	cmd := exec.Command("git", "-C", wtPath, "config", "branch.local-feature.merge")
+	out, err := cmd.Output()
+	require.NoError(t, err)
+	assert.Equal(t, "refs/heads/feature", strings.TrimSpace(string(out)))
+}
+
 func TestParsePorcelain(t *testing.T) {
 	input := `worktree /home/user/repo
 HEAD abc123
@@ -147,6 +188,47 @@ func TestSafeBranchDir(t *testing.T) {
 	}
 }
 
+func TestValidateBranchName(t *testing.T) {
+	repo := initTestRepo(t)
+
+	tests := []struct {
+		name    string
+		wantErr bool
+	}{
+		{name: "feature", wantErr: false},
+		{name: "feature/login", wantErr: false},
+		{name: "contributor/fix-bug", wantErr: false},
+		{name: "", wantErr: true},
+		{name: "feature//login", wantErr: true},
+		{name: "feature ", wantErr: true},
+		{name: "has space", wantErr: true},
+		{name: "double..dot", wantErr: true},
+		{name: "ends.lock", wantErr: true},
+		{name: "colon:name", wantErr: true},
+		{name: "-leading-dash", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateBranchName(repo, tt.name)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestValidateBranchName_UsesRepo(t *testing.T) {
+	repo := initTestRepo(t)
+	runGit(t, repo, "switch", "--quiet", "-c", "feature")
+	runGit(t, repo, "switch", "--quiet", "main")
+
+	// @{-1} only expands to a branch name inside a repository.
+	assert.NoError(t, ValidateBranchName(repo, "@{-1}"))
+}
+
 func TestPruneCheck(t *testing.T) {
 	repo := initTestRepo(t)
 
@@ -277,14 +359,14 @@ func TestFetchRemoteBranch_NotFound(t *testing.T) {
 	local, _ := initTestRepoWithRemote(t, "feature")
 
 	_, err := FetchRemoteBranch(local, "nonexistent")
-	assert.Error(t, err)
+	assert.ErrorIs(t, err, ErrBranchNotFound)
 }
 
 func TestFetchRemoteBranch_NoRemotes(t *testing.T) {
 	repo := initTestRepo(t)
 
 	_, err := FetchRemoteBranch(repo, "feature")
-	assert.Error(t, err)
+	assert.ErrorIs(t, err, ErrNoRemotes)
 }
 
 func TestAdd_RemoteBranch(t *testing.T) {
@@ -321,3 +403,38 @@ func TestRemoteTrackingRef_None(t *testing.T) {
 	// No remotes configured, so no tracking ref should exist.
 	assert.Empty(t, remoteTrackingRef(repo, "feature"))
 }
+
+func TestForeignWorktree(t *testing.T) {
+	repo := initTestRepo(t)
+	other := initTestRepo(t)
+
+	wtPath := filepath.Join(t.TempDir(), "feature")
+	require.NoError(t, Add(repo, wtPath, "feature", "main"))
+
+	otherWT := filepath.Join(t.TempDir(), "feature")
+	require.NoError(t, Add(other, otherWT, "feature", "main"))
+
+	_, foreign := ForeignWorktree(repo, wtPath)
+	assert.False(t, foreign, "own worktree")
+
+	_, foreign = ForeignWorktree(repo, otherWT)
+	assert.True(t, foreign, "other repo's worktree")
+
+	_, foreign = ForeignWorktree(repo, other)
+	assert.True(t, foreign, "other repo's main checkout")
+
+	// A plain directory inside another repository is not a worktree root.
+	sub := filepath.Join(other, "sub")
+	require.NoError(t, os.MkdirAll(sub, 0o755))
+
+	_, foreign = ForeignWorktree(repo, sub)
+	assert.False(t, foreign, "directory inside other repo")
+}
+
+func TestCommitExists(t *testing.T) {
+	repo := initTestRepo(t)
+	sha := strings.TrimSpace(runGit(t, repo, "rev-parse", "main"))
+
+	assert.True(t, CommitExists(repo, sha))
+	assert.False(t, CommitExists(repo, strings.Repeat("0", 40)))
+}
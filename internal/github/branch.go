@@ -0,0 +1,134 @@
+package github
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+// Default branch templates, matching the names forest has always used
+// for issue and pull request links.
+const (
+	DefaultIssueBranchTemplate = "issue-{{.Number}}"
+	DefaultPRBranchTemplate    = "{{.HeadBranch}}"
+)
+
+// BranchData is the data available to issue_branch_template and
+// pr_branch_template.
+type BranchData struct {
+	// Number is the issue or pull request number.
+	Number int
+
+	// Title is the issue or pull request title.
+	Title string
+
+	// HeadBranch is the pull request's head branch. It is empty for
+	// issues.
+	HeadBranch string
+
+	// Owner and Repo identify the repository the link points to.
+	Owner string
+	Repo  string
+}
+
+// branchFuncs are the helper functions available in branch templates.
+var branchFuncs = template.FuncMap{
+	"slug":  Slug,
+	"lower": strings.ToLower,
+}
+
+// BranchName renders a branch template with data. It fails if the
+// template is invalid or renders an empty name.
+func BranchName(tmpl string, data BranchData) (string, error) {
+	t, err := template.New("branch").Funcs(branchFuncs).Option("missingkey=error").Parse(tmpl)
+	if err != nil {
+		return "", fmt.Errorf("parsing branch template %q: %w", tmpl, err)
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("rendering branch template %q: %w", tmpl, err)
+	}
+
+	name := strings.TrimSpace(buf.String())
+	if name == "" {
+		return "", fmt.Errorf("branch template %q rendered an empty branch name", tmpl)
+	}
+
+	return name, nil
+}
+
+// Slug lowercases s and replaces each run of characters other than
+// letters and digits with a single hyphen, for use in branch names.
+// For example, "Fix: login fails!" becomes "fix-login-fails".
+func Slug(s string) string {
+	var b strings.Builder
+
+	hyphen := false
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if hyphen && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+
+			b.WriteRune(r)
+			hyphen = false
+
+			continue
+		}
+
+		hyphen = true
+	}
+
+	return b.String()
+}
+
+// IssueBranch renders tmpl for an issue link, or the default template
+// when tmpl is empty. The issue is only looked up with gh when the
+// template uses its title, so the default template works without gh.
+func IssueBranch(link Link, tmpl string) (string, error) {
+	if tmpl == "" {
+		tmpl = DefaultIssueBranchTemplate
+	}
+
+	data := BranchData{
+		Number: link.Number,
+		Owner:  link.Owner,
+		Repo:   link.Repo,
+	}
+
+	if usesTitle(tmpl) {
+		issue, err := FetchIssue(link.NWO(), link.Number)
+		if err != nil {
+			return "", fmt.Errorf("fetching issue metadata: %w", err)
+		}
+
+		data.Title = issue.Title
+	}
+
+	return BranchName(tmpl, data)
+}
+
+// PRBranch renders tmpl for a pull request link whose head has already
+// been fetched, or the default template when tmpl is empty.
+func PRBranch(link Link, head PRHead, tmpl string) (string, error) {
+	if tmpl == "" {
+		tmpl = DefaultPRBranchTemplate
+	}
+
+	return BranchName(tmpl, BranchData{
+		Number:     link.Number,
+		Title:      head.Title,
+		HeadBranch: head.Branch,
+		Owner:      link.Owner,
+		Repo:       link.Repo,
+	})
+}
+
+// usesTitle reports whether tmpl references the Title field, so that
+// callers can skip fetching metadata the template does not need.
+func usesTitle(tmpl string) bool {
+	return strings.Contains(tmpl, ".Title")
+}
@@ -0,0 +1,77 @@
+package github
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlug(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: "Fix: login fails!", want: "fix-login-fails"},
+		{in: "  leading and trailing  ", want: "leading-and-trailing"},
+		{in: "Add `config get` (v2)", want: "add-config-get-v2"},
+		{in: "Über café", want: "über-café"},
+		{in: "!!!", want: ""},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, Slug(tt.in), tt.in)
+	}
+}
+
+func TestBranchName(t *testing.T) {
+	data := BranchData{
+		Number:     42,
+		Title:      "Crash on Startup",
+		HeadBranch: "fix/startup",
+		Owner:      "acme",
+		Repo:       "app",
+	}
+
+	tests := []struct {
+		tmpl string
+		want string
+	}{
+		{tmpl: DefaultIssueBranchTemplate, want: "issue-42"},
+		{tmpl: DefaultPRBranchTemplate, want: "fix/startup"},
+		{tmpl: "issue-{{.Number}}-{{slug .Title}}", want: "issue-42-crash-on-startup"},
+		{tmpl: "{{lower .Owner}}/{{.Number}}", want: "acme/42"},
+	}
+
+	for _, tt := range tests {
+		got, err := BranchName(tt.tmpl, data)
+		require.NoError(t, err, tt.tmpl)
+		assert.Equal(t, tt.want, got, tt.tmpl)
+	}
+}
+
+func TestBranchName_Errors(t *testing.T) {
+	_, err := BranchName("issue-{{.Number", BranchData{})
+	assert.ErrorContains(t, err, "parsing branch template")
+
+	_, err = BranchName("{{.Missing}}", BranchData{})
+	assert.ErrorContains(t, err, "rendering branch template")
+
+	_, err = BranchName("{{.HeadBranch}}", BranchData{Number: 1})
+	assert.ErrorContains(t, err, "empty branch name")
+}
+
+func TestIssueBranch_DefaultSkipsLookup(t *testing.T) {
+	// Neither the default nor a title-free template needs gh.
+	t.Setenv("PATH", "")
+
+	link := Link{Kind: KindIssue, Owner: "acme", Repo: "app", Number: 7}
+
+	got, err := IssueBranch(link, "")
+	require.NoError(t, err)
+	assert.Equal(t, "issue-7", got)
+
+	got, err = IssueBranch(link, "{{.Repo}}-{{.Number}}")
+	require.NoError(t, err)
+	assert.Equal(t, "app-7", got)
+}
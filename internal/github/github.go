@@ -3,25 +3,35 @@
 package github
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/url"
 	"os/exec"
+	"regexp"
 	"strconv"
 	"strings"
+
+	"github.com/mhamza15/forest/internal/git"
+	"github.com/mhamza15/forest/internal/network"
 )
 
-// LinkKind distinguishes between an issue and a pull request.
+// LinkKind distinguishes between an issue, a pull request, and a
+// branch.
 type LinkKind int
 
 const (
 	KindIssue LinkKind = iota
 	KindPR
+	KindBranch
 )
 
-// Link holds the parsed components of a GitHub issue or PR URL.
+// Link holds the parsed components of a GitHub issue, PR, or branch
+// URL.
 type Link struct {
-	// Kind is KindIssue or KindPR.
+	// Kind is KindIssue, KindPR, or KindBranch.
 	Kind LinkKind
 
 	// Owner is the repository owner (user or organization).
@@ -30,8 +40,11 @@ type Link struct {
 	// Repo is the repository name.
 	Repo string
 
-	// Number is the issue or PR number.
+	// Number is the issue or PR number. It is zero for branches.
 	Number int
+
+	// Branch is the branch name of a KindBranch link.
+	Branch string
 }
 
 // NWO returns the owner/repo string (name with owner).
@@ -39,12 +52,17 @@ func (l Link) NWO() string {
 	return l.Owner + "/" + l.Repo
 }
 
-// ParseLink extracts owner, repo, number, and kind from a GitHub URL.
+// ParseLink extracts owner, repo, number or branch, and kind from a
+// GitHub URL.
 //
 // Accepted formats:
 //
 //	https://github.com/owner/repo/issues/42
 //	https://github.com/owner/repo/pull/99
+//	https://github.com/owner/repo/tree/feature/login
+//
+// Everything after /tree/ is taken as the branch, since branch names
+// may contain slashes.
 func ParseLink(raw string) (Link, error) {
 	u, err := url.Parse(raw)
 	if err != nil {
@@ -60,7 +78,7 @@ func ParseLink(raw string) (Link, error) {
 	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
 
 	if len(parts) < 4 {
-		return Link{}, fmt.Errorf("unexpected path %q: want /owner/repo/{issues,pull}/number", u.Path)
+		return Link{}, fmt.Errorf("unexpected path %q: want /owner/repo/{issues,pull}/number or /owner/repo/tree/branch", u.Path)
 	}
 
 	owner := parts[0]
@@ -75,8 +93,15 @@ func ParseLink(raw string) (Link, error) {
 		kind = KindIssue
 	case "pull":
 		kind = KindPR
+	case "tree":
+		return Link{
+			Kind:   KindBranch,
+			Owner:  owner,
+			Repo:   repo,
+			Branch: strings.Join(parts[3:], "/"),
+		}, nil
 	default:
-		return Link{}, fmt.Errorf("unexpected path segment %q: want \"issues\" or \"pull\"", kindStr)
+		return Link{}, fmt.Errorf("unexpected path segment %q: want \"issues\", \"pull\" or \"tree\"", kindStr)
 	}
 
 	num, err := strconv.Atoi(numStr)
@@ -92,11 +117,83 @@ func ParseLink(raw string) (Link, error) {
 	}, nil
 }
 
+// BranchCandidates returns the branches a KindBranch link may refer
+// to, longest first. GitHub puts any file path after the branch in the
+// same URL, so /tree/feature/login/docs may be branch "feature/login"
+// showing its docs directory, or a branch named "feature/login/docs".
+func (l Link) BranchCandidates() []string {
+	parts := strings.Split(l.Branch, "/")
+	candidates := make([]string, 0, len(parts))
+
+	for i := len(parts); i > 0; i-- {
+		candidates = append(candidates, strings.Join(parts[:i], "/"))
+	}
+
+	return candidates
+}
+
 // IsGitHubURL returns true if the string looks like a GitHub URL.
 func IsGitHubURL(s string) bool {
 	return strings.HasPrefix(s, "https://github.com/")
 }
 
+// referencePattern matches the owner/repo#number shorthand used for
+// issues and pull requests across GitHub.
+var referencePattern = regexp.MustCompile(`^([A-Za-z0-9][A-Za-z0-9-]*)/([A-Za-z0-9._-]+)#([0-9]+)$`)
+
+// IsReference returns true if the string is an owner/repo#number
+// shorthand reference.
+func IsReference(s string) bool {
+	return referencePattern.MatchString(s)
+}
+
+// ParseReference extracts owner, repo and number from an owner/repo#number
+// shorthand reference. The shorthand does not say whether it refers to
+// an issue or a pull request, so the returned link is an issue; use
+// LookupKind to find out.
+func ParseReference(s string) (Link, error) {
+	m := referencePattern.FindStringSubmatch(s)
+	if m == nil {
+		return Link{}, fmt.Errorf("unexpected reference %q: want owner/repo#number", s)
+	}
+
+	num, err := strconv.Atoi(m[3])
+	if err != nil {
+		return Link{}, fmt.Errorf("parsing number %q: %w", m[3], err)
+	}
+
+	return Link{
+		Kind:   KindIssue,
+		Owner:  m[1],
+		Repo:   m[2],
+		Number: num,
+	}, nil
+}
+
+// LookupKind asks GitHub whether number in the repository identified
+// by nwo is an issue or a pull request. GitHub's issues API returns
+// both, with a pull_request field set only for pull requests.
+func LookupKind(nwo string, number int) (LinkKind, error) {
+	output, err := runGH("api", fmt.Sprintf("repos/%s/issues/%d", nwo, number))
+	if err != nil {
+		return KindIssue, fmt.Errorf("gh api: %w", err)
+	}
+
+	var issue struct {
+		PullRequest *json.RawMessage `json:"pull_request"`
+	}
+
+	if err := json.Unmarshal(output, &issue); err != nil {
+		return KindIssue, fmt.Errorf("parsing gh output: %w", err)
+	}
+
+	if issue.PullRequest != nil {
+		return KindPR, nil
+	}
+
+	return KindIssue, nil
+}
+
 // RepoInfo holds the owner and repository name parsed from a GitHub URL.
 type RepoInfo struct {
 	// Owner is the repository owner (user or organization).
@@ -161,14 +258,39 @@ type PRHead struct {
 	// ForkOwner is the login of the fork's owner. It is set only
 	// when IsFork is true.
 	ForkOwner string
+
+	// State is the PR state as reported by GitHub: PROpen, PRClosed,
+	// or PRMerged.
+	State string
+
+	// HeadSHA is the commit the PR head currently points to.
+	HeadSHA string
+
+	// Title is the pull request title.
+	Title string
+
+	// BaseBranch is the branch the PR targets, such as "main" or
+	// "release/2.0".
+	BaseBranch string
 }
 
+// Pull request states reported by gh.
+const (
+	PROpen   = "OPEN"
+	PRClosed = "CLOSED"
+	PRMerged = "MERGED"
+)
+
 // ghPRJSON is the subset of gh pr view --json output that we need.
 type ghPRJSON struct {
 	HeadRefName       string      `json:"headRefName"`
 	HeadRepo          ghRepoJSON  `json:"headRepository"`
 	HeadRepoOwner     ghOwnerJSON `json:"headRepositoryOwner"`
 	IsCrossRepository bool        `json:"isCrossRepository"`
+	State             string      `json:"state"`
+	HeadRefOid        string      `json:"headRefOid"`
+	Title             string      `json:"title"`
+	BaseRefName       string      `json:"baseRefName"`
 }
 
 type ghRepoJSON struct {
@@ -186,13 +308,11 @@ func FetchPRHead(nwo string, number int) (PRHead, error) {
 	// gh pr view requires a repo flag when we are not inside the repo.
 	num := strconv.Itoa(number)
 
-	cmd := exec.Command(
-		"gh", "pr", "view", num,
+	output, err := runGH(
+		"pr", "view", num,
 		"--repo", nwo,
-		"--json", "headRefName,headRepository,headRepositoryOwner,isCrossRepository",
+		"--json", "headRefName,headRepository,headRepositoryOwner,isCrossRepository,state,headRefOid,title,baseRefName",
 	)
-
-	output, err := cmd.Output()
 	if err != nil {
 		return PRHead{}, fmt.Errorf("gh pr view: %w", err)
 	}
@@ -209,9 +329,13 @@ func FetchPRHead(nwo string, number int) (PRHead, error) {
 	)
 
 	head := PRHead{
-		Branch:   pr.HeadRefName,
-		CloneURL: cloneURL,
-		IsFork:   pr.IsCrossRepository,
+		Branch:     pr.HeadRefName,
+		CloneURL:   cloneURL,
+		IsFork:     pr.IsCrossRepository,
+		State:      pr.State,
+		HeadSHA:    pr.HeadRefOid,
+		Title:      pr.Title,
+		BaseBranch: pr.BaseRefName,
 	}
 
 	if pr.IsCrossRepository {
@@ -221,21 +345,144 @@ func FetchPRHead(nwo string, number int) (PRHead, error) {
 	return head, nil
 }
 
+// Issue holds the metadata of a GitHub issue.
+type Issue struct {
+	// Number is the issue number.
+	Number int `json:"number"`
+
+	// Title is the issue title.
+	Title string `json:"title"`
+
+	// State is the issue state as reported by GitHub, OPEN or CLOSED.
+	State string `json:"state"`
+}
+
+// FetchIssue retrieves an issue's metadata using the gh CLI. The nwo
+// argument is the "owner/repo" string for the repository.
+func FetchIssue(nwo string, number int) (Issue, error) {
+	output, err := runGH(
+		"issue", "view", strconv.Itoa(number),
+		"--repo", nwo,
+		"--json", "number,title,state",
+	)
+	if err != nil {
+		return Issue{}, fmt.Errorf("gh issue view: %w", err)
+	}
+
+	var issue Issue
+	if err := json.Unmarshal(output, &issue); err != nil {
+		return Issue{}, fmt.Errorf("parsing gh output: %w", err)
+	}
+
+	return issue, nil
+}
+
+// PRListLimit is the most PRs ListPRs returns. A list of this length
+// may be missing older PRs.
+const PRListLimit = 300
+
+// PRSummary is a pull request as listed by gh pr list.
+type PRSummary struct {
+	Number int    `json:"number"`
+	URL    string `json:"url"`
+	Branch string `json:"headRefName"`
+	State  string `json:"state"`
+}
+
+// ListPRs returns up to PRListLimit of the most recent PRs in the
+// repository identified by nwo ("owner/repo") with the given state
+// ("open", "merged", "closed" or "all"), using a single gh call.
+// Callers index the result by head branch with PRsByBranch instead of
+// asking gh about each branch.
+func ListPRs(nwo, state string) ([]PRSummary, error) {
+	output, err := runGH(
+		"pr", "list",
+		"--state", state,
+		"--repo", nwo,
+		"--json", "number,url,headRefName,state",
+		"--limit", strconv.Itoa(PRListLimit),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("gh pr list: %w", err)
+	}
+
+	return parsePRs(output)
+}
+
+// PRListComplete reports whether prs, as returned by ListPRs, holds
+// every matching PR rather than being cut off at PRListLimit. It must
+// be given the list itself, since PRsByBranch drops PRs that share a
+// branch.
+func PRListComplete(prs []PRSummary) bool {
+	return len(prs) < PRListLimit
+}
+
+// PRsByBranch indexes PRs by head branch. PRs from forks are keyed by
+// their branch name alone, so a fork branch can shadow a local branch
+// of the same name. When several PRs share a branch, the first (most
+// recent) one wins.
+func PRsByBranch(prs []PRSummary) map[string]PRSummary {
+	byBranch := make(map[string]PRSummary, len(prs))
+
+	for _, pr := range prs {
+		if _, ok := byBranch[pr.Branch]; !ok {
+			byBranch[pr.Branch] = pr
+		}
+	}
+
+	return byBranch
+}
+
+// OpenPRForBranch returns the number and URL of an open PR whose head
+// is branch in the repository identified by nwo. found is false when
+// there is none.
+func OpenPRForBranch(nwo, branch string) (number int, url string, found bool, err error) {
+	output, err := runGH(
+		"pr", "list",
+		"--head", branch,
+		"--state", "open",
+		"--repo", nwo,
+		"--json", "number,url,headRefName,state",
+		"--limit", "1",
+	)
+	if err != nil {
+		return 0, "", false, fmt.Errorf("gh pr list: %w", err)
+	}
+
+	prs, err := parsePRs(output)
+	if err != nil || len(prs) == 0 {
+		return 0, "", false, err
+	}
+
+	return prs[0].Number, prs[0].URL, true, nil
+}
+
 // IsPRMerged checks whether a merged pull request exists for the
 // given branch in the repository identified by nwo ("owner/repo").
-// It uses gh pr list to find merged PRs matching the head branch.
+// merged may hold the repository's merged PRs from ListPRs, indexed
+// by PRsByBranch, to answer without a gh call. complete reports
+// whether that list held every merged PR (see PRListComplete); gh pr
+// list is only run for the branch when merged is nil or incomplete.
 // Returns an error if the gh CLI is unavailable or the query fails.
-func IsPRMerged(nwo, branch string) (bool, error) {
-	cmd := exec.Command(
-		"gh", "pr", "list",
+func IsPRMerged(nwo, branch string, merged map[string]PRSummary, complete bool) (bool, error) {
+	if merged != nil {
+		if _, ok := merged[branch]; ok {
+			return true, nil
+		}
+
+		if complete {
+			return false, nil
+		}
+	}
+
+	output, err := runGH(
+		"pr", "list",
 		"--head", branch,
 		"--state", "merged",
 		"--repo", nwo,
 		"--json", "number",
 		"--limit", "1",
 	)
-
-	output, err := cmd.Output()
 	if err != nil {
 		return false, fmt.Errorf("gh pr list: %w", err)
 	}
@@ -243,3 +490,158 @@ func IsPRMerged(nwo, branch string) (bool, error) {
 	// gh pr list --json returns "[]\n" when no PRs match.
 	return len(output) > 0 && strings.TrimSpace(string(output)) != "[]", nil
 }
+
+// parsePRs parses gh pr list --json output.
+func parsePRs(data []byte) ([]PRSummary, error) {
+	var prs []PRSummary
+	if err := json.Unmarshal(data, &prs); err != nil {
+		return nil, fmt.Errorf("parsing gh output: %w", err)
+	}
+
+	return prs, nil
+}
+
+// runGH runs gh with args and returns its stdout, retrying transient
+// failures. A missing gh binary, missing authentication, or an unknown
+// repository or pull request cannot succeed on retry and is returned
+// immediately.
+func runGH(args ...string) ([]byte, error) {
+	var output []byte
+
+	err := network.Retry(func() error {
+		ctx, cancel := network.Context()
+		defer cancel()
+
+		var err error
+
+		output, err = network.Command(ctx, "gh", args...).Output()
+		if err == nil {
+			return nil
+		}
+
+		if permanentGHError(err) {
+			return network.Permanent(err)
+		}
+
+		return network.Error(ctx, err)
+	})
+
+	return output, err
+}
+
+// runGHCommand runs gh with args in dir, for commands that work on a
+// local repository, such as clones, rather than only query GitHub.
+// Failures are retried like runGH's. The returned output combines
+// stdout and stderr for error messages; stderr is also streamed to
+// progress when it is not nil, in which case the command is not
+// subject to the network timeout.
+func runGHCommand(dir string, progress io.Writer, args ...string) ([]byte, error) {
+	var output bytes.Buffer
+
+	err := network.Retry(func() error {
+		ctx, cancel := network.StreamContext(progress != nil)
+		defer cancel()
+
+		output.Reset()
+
+		cmd := network.Command(ctx, "gh", args...)
+		cmd.Dir = dir
+		cmd.Stdout = &output
+		cmd.Stderr = &output
+
+		if progress != nil {
+			cmd.Stderr = io.MultiWriter(&output, progress)
+		}
+
+		err := cmd.Run()
+		if err == nil {
+			return nil
+		}
+
+		if errors.Is(err, exec.ErrNotFound) || permanentGHOutput(output.Bytes()) {
+			return network.Permanent(err)
+		}
+
+		return network.Error(ctx, err)
+	})
+
+	return output.Bytes(), err
+}
+
+// permanentGHError reports whether a gh failure is deterministic.
+func permanentGHError(err error) bool {
+	if errors.Is(err, exec.ErrNotFound) {
+		return true
+	}
+
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return false
+	}
+
+	return permanentGHOutput(exitErr.Stderr)
+}
+
+// permanentGHOutput reports whether gh's error output describes a
+// failure that retrying cannot fix.
+func permanentGHOutput(output []byte) bool {
+	for _, msg := range []string{"Could not resolve to", "gh auth login", "no pull requests found", "HTTP 404", "already exists and is not an empty directory"} {
+		if bytes.Contains(output, []byte(msg)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Authenticated reports whether the gh CLI is installed and logged in
+// to github.com.
+func Authenticated() bool {
+	if _, err := exec.LookPath("gh"); err != nil {
+		return false
+	}
+
+	ctx, cancel := network.Context()
+	defer cancel()
+
+	cmd := network.Command(ctx, "gh", "auth", "status", "--hostname", "github.com")
+
+	return cmd.Run() == nil
+}
+
+// CloneRepo clones the repository identified by nwo ("owner/repo")
+// into dest using gh repo clone, which authenticates with gh's stored
+// credentials and so works for private repositories. The options'
+// flags are passed through to git clone. Like other gh calls, it is
+// retried after transient failures, and it is subject to the network
+// timeout unless it streams progress.
+func CloneRepo(nwo, dest string, opts git.CloneOptions) error {
+	args := []string{"repo", "clone", nwo, dest}
+
+	if flags := opts.Flags(); len(flags) > 0 {
+		args = append(args, "--")
+		args = append(args, flags...)
+	}
+
+	output, err := runGHCommand("", opts.Progress, args...)
+	if err != nil {
+		return network.CommandError("gh repo clone", output, opts.Progress != nil, err)
+	}
+
+	return nil
+}
+
+// CheckoutPR checks out pull request number of nwo as a local branch
+// named branch using gh pr checkout, which fetches the head with gh's
+// credentials and sets up the fork remote and upstream. It runs in
+// dir, which must be a worktree of the repository; the worktree's
+// HEAD is switched to the new branch. Like other gh calls, it is
+// subject to the network timeout and retried after transient failures.
+func CheckoutPR(nwo string, number int, dir, branch string) error {
+	output, err := runGHCommand(dir, nil, "pr", "checkout", strconv.Itoa(number), "--repo", nwo, "--branch", branch)
+	if err != nil {
+		return fmt.Errorf("gh pr checkout: %s: %w", bytes.TrimSpace(output), err)
+	}
+
+	return nil
+}
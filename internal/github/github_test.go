@@ -1,10 +1,16 @@
 package github
 
 import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/mhamza15/forest/internal/git"
 )
 
 func TestParseLink_Issue(t *testing.T) {
@@ -28,6 +34,39 @@ func TestParseLink_PR(t *testing.T) {
 	assert.Equal(t, 99, link.Number)
 }
 
+func TestParseLink_Branch(t *testing.T) {
+	tests := []struct {
+		name string
+		url  string
+		want string
+	}{
+		{name: "single segment", url: "https://github.com/acme/widgets/tree/main", want: "main"},
+		{name: "multiple segments", url: "https://github.com/acme/widgets/tree/feature/login", want: "feature/login"},
+		{name: "deeply nested", url: "https://github.com/acme/widgets/tree/user/jo/fix/login", want: "user/jo/fix/login"},
+		{name: "trailing slash", url: "https://github.com/acme/widgets/tree/feature/login/", want: "feature/login"},
+		{name: "escaped characters", url: "https://github.com/acme/widgets/tree/fix%23123?tab=readme", want: "fix#123"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			link, err := ParseLink(tt.url)
+			require.NoError(t, err)
+
+			assert.Equal(t, KindBranch, link.Kind)
+			assert.Equal(t, "acme/widgets", link.NWO())
+			assert.Equal(t, tt.want, link.Branch)
+			assert.Zero(t, link.Number)
+		})
+	}
+}
+
+func TestLink_BranchCandidates(t *testing.T) {
+	link := Link{Kind: KindBranch, Branch: "feature/login/docs"}
+
+	assert.Equal(t, []string{"feature/login/docs", "feature/login", "feature"}, link.BranchCandidates())
+	assert.Equal(t, []string{"main"}, Link{Kind: KindBranch, Branch: "main"}.BranchCandidates())
+}
+
 func TestParseLink_TrailingSlash(t *testing.T) {
 	link, err := ParseLink("https://github.com/acme/widgets/issues/7/")
 	require.NoError(t, err)
@@ -46,6 +85,7 @@ func TestParseLink_Errors(t *testing.T) {
 		{name: "bad kind", url: "https://github.com/acme/widgets/commits/123"},
 		{name: "not a number", url: "https://github.com/acme/widgets/issues/abc"},
 		{name: "too few segments", url: "https://github.com/acme/widgets"},
+		{name: "missing branch", url: "https://github.com/acme/widgets/tree/"},
 	}
 
 	for _, tt := range tests {
@@ -139,3 +179,156 @@ func TestParseRepoURL_Errors(t *testing.T) {
 		})
 	}
 }
+
+func TestParseReference(t *testing.T) {
+	link, err := ParseReference("acme/widgets#42")
+	require.NoError(t, err)
+
+	assert.Equal(t, KindIssue, link.Kind)
+	assert.Equal(t, "acme", link.Owner)
+	assert.Equal(t, "widgets", link.Repo)
+	assert.Equal(t, 42, link.Number)
+
+	link, err = ParseReference("my-org/my.repo_v2#7")
+	require.NoError(t, err)
+	assert.Equal(t, "my-org/my.repo_v2", link.NWO())
+	assert.Equal(t, 7, link.Number)
+}
+
+func TestIsReference(t *testing.T) {
+	tests := []struct {
+		in   string
+		want bool
+	}{
+		{in: "acme/widgets#42", want: true},
+		{in: "acme/widgets#", want: false},
+		{in: "acme/widgets#abc", want: false},
+		{in: "widgets#42", want: false},
+		{in: "feature/login", want: false},
+		{in: "acme/widgets/extra#42", want: false},
+		{in: "https://github.com/acme/widgets/issues/42", want: false},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, IsReference(tt.in), tt.in)
+
+		_, err := ParseReference(tt.in)
+		assert.Equal(t, tt.want, err == nil, tt.in)
+	}
+}
+
+func TestParsePRs(t *testing.T) {
+	prs, err := parsePRs([]byte(`[{"number":12,"url":"https://github.com/o/r/pull/12","headRefName":"feature","state":"OPEN"}]`))
+	require.NoError(t, err)
+	assert.Equal(t, []PRSummary{{Number: 12, URL: "https://github.com/o/r/pull/12", Branch: "feature", State: "OPEN"}}, prs)
+
+	prs, err = parsePRs([]byte("[]\n"))
+	require.NoError(t, err)
+	assert.Empty(t, prs)
+
+	_, err = parsePRs([]byte("not json"))
+	assert.Error(t, err)
+}
+
+func TestPRsByBranch(t *testing.T) {
+	byBranch := PRsByBranch([]PRSummary{
+		{Number: 3, Branch: "feature"},
+		{Number: 2, Branch: "fix"},
+		{Number: 1, Branch: "feature"},
+	})
+
+	assert.Len(t, byBranch, 2)
+	assert.Equal(t, 3, byBranch["feature"].Number)
+	assert.Equal(t, 2, byBranch["fix"].Number)
+}
+
+func TestIsPRMerged_Prefetched(t *testing.T) {
+	prs := []PRSummary{{Number: 7, Branch: "done"}}
+	merged := PRsByBranch(prs)
+
+	// A list shorter than the limit is complete, so it answers both
+	// ways without calling gh.
+	ok, err := IsPRMerged("o/r", "done", merged, PRListComplete(prs))
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = IsPRMerged("o/r", "open", merged, PRListComplete(prs))
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestIsPRMerged_FullListWithDuplicateBranches(t *testing.T) {
+	// A full list is cut off at the limit even when repeated branch
+	// names leave fewer keys, so older PRs are asked about with gh.
+	prs := make([]PRSummary, PRListLimit)
+	for i := range prs {
+		prs[i] = PRSummary{Number: PRListLimit - i, Branch: fmt.Sprintf("branch-%d", i%10)}
+	}
+
+	merged := PRsByBranch(prs)
+	require.Len(t, merged, 10)
+	assert.False(t, PRListComplete(prs))
+
+	bin := t.TempDir()
+	script := "#!/bin/sh\necho '[{\"number\":1}]'\n"
+	require.NoError(t, os.WriteFile(filepath.Join(bin, "gh"), []byte(script), 0o755))
+	t.Setenv("PATH", bin)
+
+	ok, err := IsPRMerged("o/r", "old-branch", merged, PRListComplete(prs))
+	require.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestCloneRepo_RetriesTransientFailures(t *testing.T) {
+	// The fake gh fails once with a network error, then succeeds,
+	// recording each call's arguments.
+	bin := t.TempDir()
+	calls := filepath.Join(bin, "calls")
+	script := "#!/bin/sh\n" +
+		"echo \"$@\" >> " + calls + "\n" +
+		"if [ ! -e " + calls + ".failed ]; then : > " + calls + ".failed; echo 'connection reset by peer' >&2; exit 1; fi\n"
+	require.NoError(t, os.WriteFile(filepath.Join(bin, "gh"), []byte(script), 0o755))
+	t.Setenv("PATH", bin)
+
+	require.NoError(t, CloneRepo("acme/widgets", "/tmp/widgets", git.CloneOptions{Depth: 1}))
+
+	data, err := os.ReadFile(calls)
+	require.NoError(t, err)
+	assert.Equal(t, strings.Repeat("repo clone acme/widgets /tmp/widgets -- --depth 1\n", 2), string(data))
+}
+
+func TestCloneRepo_PermanentFailure(t *testing.T) {
+	bin := t.TempDir()
+	calls := filepath.Join(bin, "calls")
+	script := "#!/bin/sh\n" +
+		"echo \"$@\" >> " + calls + "\n" +
+		"echo 'GraphQL: Could not resolve to a Repository' >&2\nexit 1\n"
+	require.NoError(t, os.WriteFile(filepath.Join(bin, "gh"), []byte(script), 0o755))
+	t.Setenv("PATH", bin)
+
+	err := CloneRepo("acme/missing", "/tmp/missing", git.CloneOptions{})
+	require.ErrorContains(t, err, "Could not resolve to a Repository")
+
+	data, err := os.ReadFile(calls)
+	require.NoError(t, err)
+	assert.Equal(t, "repo clone acme/missing /tmp/missing\n", string(data))
+}
+
+func TestCheckoutPR_RunsInDirWithRetries(t *testing.T) {
+	// The fake gh fails once with a network error, then succeeds,
+	// recording the directory and arguments of each call.
+	bin := t.TempDir()
+	calls := filepath.Join(bin, "calls")
+	script := "#!/bin/sh\n" +
+		"echo \"$PWD $*\" >> " + calls + "\n" +
+		"if [ ! -e " + calls + ".failed ]; then : > " + calls + ".failed; echo 'connection reset by peer' >&2; exit 1; fi\n"
+	require.NoError(t, os.WriteFile(filepath.Join(bin, "gh"), []byte(script), 0o755))
+	t.Setenv("PATH", bin)
+
+	dir := t.TempDir()
+	require.NoError(t, CheckoutPR("acme/widgets", 7, dir, "pr-7"))
+
+	data, err := os.ReadFile(calls)
+	require.NoError(t, err)
+	assert.Equal(t, strings.Repeat(dir+" pr checkout 7 --repo acme/widgets --branch pr-7\n", 2), string(data))
+}
@@ -0,0 +1,92 @@
+// Package network holds shared settings for network-bound git and gh
+// subprocesses, such as the timeout after which they are cancelled.
+package network
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"time"
+)
+
+// DefaultTimeout is the timeout applied to network commands unless
+// overridden with SetTimeout.
+const DefaultTimeout = 30 * time.Second
+
+// ErrTimeout is returned (wrapped) when a network command is cancelled
+// because it exceeded the configured timeout.
+var ErrTimeout = errors.New("timed out")
+
+// waitDelay bounds how long a cancelled command may hold its output
+// pipes open (e.g. via a lingering ssh child) before Wait returns.
+const waitDelay = time.Second
+
+var timeout = DefaultTimeout
+
+// SetTimeout sets the timeout for subsequent network commands. A zero
+// or negative duration disables the timeout.
+func SetTimeout(d time.Duration) {
+	timeout = d
+}
+
+// Timeout returns the currently configured timeout.
+func Timeout() time.Duration {
+	return timeout
+}
+
+// Context returns a context that expires after the configured timeout.
+// When the timeout is disabled, the context never expires. The caller
+// must call the returned cancel function.
+func Context() (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(context.Background())
+	}
+
+	return context.WithTimeout(context.Background(), timeout)
+}
+
+// StreamContext is like Context, but for a command whose progress is
+// streamed to the user when streaming is true. Large clones and
+// fetches can legitimately run far longer than the timeout, and the
+// user can see that they are alive and interrupt them, so such a
+// context never expires.
+func StreamContext(streaming bool) (context.Context, context.CancelFunc) {
+	if streaming {
+		return context.WithCancel(context.Background())
+	}
+
+	return Context()
+}
+
+// Command returns an exec.Cmd that is killed when ctx is done.
+func Command(ctx context.Context, name string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.WaitDelay = waitDelay
+
+	return cmd
+}
+
+// Error converts err into a clear timeout error if ctx expired, so a
+// killed process is not reported as a generic exec failure. Otherwise
+// err is returned unchanged.
+func Error(ctx context.Context, err error) error {
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%w after %s", ErrTimeout, timeout)
+	}
+
+	return err
+}
+
+// CommandError describes the failure of the command name. Its output
+// is quoted in the message unless it was streamed to the user as
+// progress, in which case repeating it would only duplicate what was
+// already shown.
+func CommandError(name string, output []byte, streamed bool, err error) error {
+	if streamed {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+
+	return fmt.Errorf("%s: %s: %w", name, bytes.TrimSpace(output), err)
+}
@@ -0,0 +1,64 @@
+package network
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommand_TimesOut(t *testing.T) {
+	SetTimeout(50 * time.Millisecond)
+	t.Cleanup(func() { SetTimeout(DefaultTimeout) })
+
+	ctx, cancel := Context()
+	defer cancel()
+
+	err := Error(ctx, Command(ctx, "sleep", "5").Run())
+	require.ErrorIs(t, err, ErrTimeout)
+	assert.Contains(t, err.Error(), "timed out after 50ms")
+}
+
+func TestCommand_CompletesWithinTimeout(t *testing.T) {
+	ctx, cancel := Context()
+	defer cancel()
+
+	assert.NoError(t, Error(ctx, Command(ctx, "true").Run()))
+}
+
+func TestContext_Disabled(t *testing.T) {
+	SetTimeout(0)
+	t.Cleanup(func() { SetTimeout(DefaultTimeout) })
+
+	ctx, cancel := Context()
+	defer cancel()
+
+	_, ok := ctx.Deadline()
+	assert.False(t, ok)
+}
+
+func TestStreamContext(t *testing.T) {
+	ctx, cancel := StreamContext(true)
+	defer cancel()
+
+	_, ok := ctx.Deadline()
+	assert.False(t, ok, "streaming commands are not timed out")
+
+	ctx, cancel = StreamContext(false)
+	defer cancel()
+
+	_, ok = ctx.Deadline()
+	assert.True(t, ok)
+}
+
+func TestCommandError(t *testing.T) {
+	err := errors.New("exit status 128")
+	output := []byte("Receiving objects: 100%\nfatal: remote error\n")
+
+	assert.EqualError(t, CommandError("git fetch", output, false, err),
+		"git fetch: Receiving objects: 100%\nfatal: remote error: exit status 128")
+	assert.EqualError(t, CommandError("git fetch", output, true, err), "git fetch: exit status 128")
+	assert.ErrorIs(t, CommandError("git fetch", output, true, err), err)
+}
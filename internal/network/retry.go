@@ -0,0 +1,84 @@
+package network
+
+import (
+	"errors"
+	"log/slog"
+	"time"
+)
+
+// DefaultRetries is the number of times a failed network command is
+// retried unless overridden with SetRetries.
+const DefaultRetries = 2
+
+// initialBackoff is the delay before the first retry. It doubles after
+// each further attempt.
+const initialBackoff = time.Second
+
+var retries = DefaultRetries
+
+// SetRetries sets how many times subsequent network commands are
+// retried after a transient failure. Zero or a negative count disables
+// retries.
+func SetRetries(n int) {
+	retries = max(n, 0)
+}
+
+// Retries returns the currently configured retry count.
+func Retries() int {
+	return retries
+}
+
+// permanentError marks an error as deterministic, so retrying the
+// command that produced it cannot succeed.
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err as not worth retrying, such as a branch or pull
+// request that does not exist. Its message is unchanged. Permanent
+// returns nil if err is nil.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &permanentError{err: err}
+}
+
+// Retry calls fn until it succeeds, retrying transient failures up to
+// the configured number of times with exponential backoff. Errors
+// marked with Permanent and timeouts are returned immediately, since
+// the command already waited the full timeout.
+func Retry(fn func() error) error {
+	return retry(retries+1, initialBackoff, fn)
+}
+
+func retry(attempts int, backoff time.Duration, fn func() error) error {
+	var err error
+
+	for attempt := 1; ; attempt++ {
+		err = fn()
+
+		var permanent *permanentError
+
+		switch {
+		case err == nil:
+			return nil
+		case errors.As(err, &permanent), errors.Is(err, ErrTimeout), attempt >= attempts:
+			return err
+		}
+
+		slog.Debug("retrying network command",
+			slog.Int("attempt", attempt),
+			slog.Duration("backoff", backoff),
+			slog.Any("err", err),
+		)
+
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
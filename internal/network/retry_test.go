@@ -0,0 +1,59 @@
+package network
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetry_SucceedsAfterTransientFailures(t *testing.T) {
+	calls := 0
+
+	err := retry(3, 0, func() error {
+		calls++
+		if calls < 3 {
+			return errors.New("connection reset")
+		}
+
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, calls)
+}
+
+func TestRetry_GivesUpAfterAttempts(t *testing.T) {
+	calls := 0
+
+	err := retry(2, 0, func() error {
+		calls++
+		return errors.New("connection reset")
+	})
+
+	assert.EqualError(t, err, "connection reset")
+	assert.Equal(t, 2, calls)
+}
+
+func TestRetry_StopsOnPermanentAndTimeout(t *testing.T) {
+	notFound := errors.New("not found")
+
+	for _, failure := range []error{
+		fmt.Errorf("fetching: %w", Permanent(notFound)),
+		fmt.Errorf("fetching: %w", ErrTimeout),
+	} {
+		calls := 0
+
+		err := retry(3, 0, func() error {
+			calls++
+			return failure
+		})
+
+		assert.Equal(t, failure, err)
+		assert.Equal(t, 1, calls)
+	}
+
+	assert.ErrorIs(t, Permanent(notFound), notFound)
+	assert.NoError(t, Permanent(nil))
+}
@@ -0,0 +1,106 @@
+// Package prompt implements interactive yes/no confirmation prompts
+// that can be answered automatically for scripted use.
+package prompt
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/x/term"
+)
+
+// ErrNoTTY is returned when a prompt is needed but stdin is not a
+// terminal, so no answer could be read without hanging.
+var ErrNoTTY = errors.New("refusing to prompt without a TTY; pass --yes")
+
+// ErrInvalidChoice is returned by Choose when the answer does not name
+// one of the options.
+var ErrInvalidChoice = errors.New("invalid choice")
+
+var assumeYes bool
+
+// SetAssumeYes makes subsequent calls to Confirm answer yes without
+// reading from stdin.
+func SetAssumeYes(yes bool) {
+	assumeYes = yes
+}
+
+// AssumeYes reports whether prompts are answered automatically.
+func AssumeYes() bool {
+	return assumeYes
+}
+
+// Confirm prints prompt and reads a yes/no answer from stdin. Only "y"
+// and "yes" (case-insensitive) count as yes. When SetAssumeYes is
+// enabled, it returns true without prompting. When stdin is not a
+// terminal, it returns ErrNoTTY instead of blocking on input that may
+// never come.
+func Confirm(prompt string) (bool, error) {
+	if assumeYes {
+		return true, nil
+	}
+
+	if !IsInteractive() {
+		return false, ErrNoTTY
+	}
+
+	return confirm(os.Stdin, os.Stdout, prompt), nil
+}
+
+// Choose prints question followed by a numbered list of options and
+// reads the number of the chosen option from stdin, returning its
+// index. It returns ErrInvalidChoice for an answer that is not one of
+// the numbers, and ErrNoTTY when stdin is not a terminal.
+func Choose(question string, options []string) (int, error) {
+	if !IsInteractive() {
+		return -1, ErrNoTTY
+	}
+
+	i, ok := choose(os.Stdin, os.Stdout, question, options)
+	if !ok {
+		return -1, ErrInvalidChoice
+	}
+
+	return i, nil
+}
+
+// IsInteractive reports whether stdin is a terminal that a user can
+// answer prompts on.
+func IsInteractive() bool {
+	return term.IsTerminal(os.Stdin.Fd())
+}
+
+func confirm(in io.Reader, out io.Writer, prompt string) bool {
+	_, _ = fmt.Fprint(out, prompt)
+
+	reader := bufio.NewReader(in)
+	answer, _ := reader.ReadString('\n')
+	answer = strings.TrimSpace(strings.ToLower(answer))
+
+	return answer == "y" || answer == "yes"
+}
+
+func choose(in io.Reader, out io.Writer, question string, options []string) (int, bool) {
+	_, _ = fmt.Fprintln(out, question)
+
+	for i, option := range options {
+		_, _ = fmt.Fprintf(out, "  %d) %s\n", i+1, option)
+	}
+
+	_, _ = fmt.Fprintf(out, "Choice [1-%d]: ", len(options))
+
+	reader := bufio.NewReader(in)
+	answer, _ := reader.ReadString('\n')
+
+	n, err := strconv.Atoi(strings.TrimSpace(answer))
+	if err != nil || n < 1 || n > len(options) {
+		return -1, false
+	}
+
+	return n - 1, true
+}
@@ -0,0 +1,82 @@
+package prompt
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfirm(t *testing.T) {
+	tests := []struct {
+		input string
+		want  bool
+	}{
+		{input: "y\n", want: true},
+		{input: "YES\n", want: true},
+		{input: " yes \n", want: true},
+		{input: "n\n", want: false},
+		{input: "\n", want: false},
+		{input: "", want: false},
+		{input: "yep\n", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			var out bytes.Buffer
+
+			assert.Equal(t, tt.want, confirm(strings.NewReader(tt.input), &out, "Proceed? "))
+			assert.Equal(t, "Proceed? ", out.String())
+		})
+	}
+}
+
+func TestConfirm_AssumeYes(t *testing.T) {
+	SetAssumeYes(true)
+	t.Cleanup(func() { SetAssumeYes(false) })
+
+	ok, err := Confirm("Proceed? ")
+	require.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestConfirm_NoTTY(t *testing.T) {
+	// Test binaries run with stdin redirected, never a terminal.
+	if IsInteractive() {
+		t.Skip("stdin is a terminal")
+	}
+
+	ok, err := Confirm("Proceed? ")
+	require.ErrorIs(t, err, ErrNoTTY)
+	assert.False(t, ok)
+}
+
+func TestChoose(t *testing.T) {
+	options := []string{"alpha", "beta"}
+
+	tests := []struct {
+		input string
+		want  int
+		ok    bool
+	}{
+		{input: "1\n", want: 0, ok: true},
+		{input: " 2 \n", want: 1, ok: true},
+		{input: "0\n", want: -1, ok: false},
+		{input: "3\n", want: -1, ok: false},
+		{input: "beta\n", want: -1, ok: false},
+		{input: "", want: -1, ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			var out bytes.Buffer
+
+			got, ok := choose(strings.NewReader(tt.input), &out, "Pick one:", options)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, "Pick one:\n  1) alpha\n  2) beta\nChoice [1-2]: ", out.String())
+		})
+	}
+}
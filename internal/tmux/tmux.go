@@ -2,11 +2,21 @@
 package tmux
 
 import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"log/slog"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
+	"unicode/utf8"
 )
 
 // ErrNotRunning is returned when a tmux operation requires an active
@@ -32,15 +42,144 @@ func SessionExists(name string) bool {
 	return cmd.Run() == nil
 }
 
+// SessionInfo describes a running tmux session.
+type SessionInfo struct {
+	// Name is the session name.
+	Name string
+
+	// Attached is true if at least one client is attached.
+	Attached bool
+
+	// Windows is the number of windows in the session.
+	Windows int
+
+	// Created is when the session was created.
+	Created time.Time
+
+	// Activity is when the session was last active.
+	Activity time.Time
+
+	// Forest is true if the session was tagged as created by forest.
+	Forest bool
+
+	// Project and Branch are the forest project and branch the
+	// session was created for. Both are empty for untagged sessions.
+	Project string
+	Branch  string
+
+	// Path is the worktree the session was created for. It is empty
+	// for untagged sessions and ones tagged before it was recorded.
+	Path string
+}
+
+// User options set on sessions created by forest, so they can be told
+// apart from other tmux sessions even after their worktree is gone.
+const (
+	OptionForest  = "@forest"
+	OptionProject = "@forest_project"
+	OptionBranch  = "@forest_branch"
+	OptionPath    = "@forest_path"
+)
+
+// sessionFormat is the list-sessions format parsed by parseSessions.
+// Fields are tab-separated so session names may contain spaces.
+const sessionFormat = "#{session_name}\t#{session_attached}\t#{session_windows}\t" +
+	"#{session_created}\t#{session_activity}\t" +
+	"#{" + OptionForest + "}\t#{" + OptionProject + "}\t#{" + OptionBranch + "}\t" +
+	"#{" + OptionPath + "}"
+
+// ListSessions returns all running tmux sessions with a single tmux
+// call. It returns no sessions, and no error, when the tmux server is
+// not running.
+func ListSessions() ([]SessionInfo, error) {
+	cmd := exec.Command("tmux", "list-sessions", "-F", sessionFormat)
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		msg := strings.TrimSpace(string(output))
+
+		if strings.Contains(msg, "no server running") || strings.Contains(msg, "error connecting") {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("tmux list-sessions: %s: %w", msg, err)
+	}
+
+	return parseSessions(output), nil
+}
+
+// parseSessions parses list-sessions output in sessionFormat.
+// Malformed lines are skipped.
+func parseSessions(data []byte) []SessionInfo {
+	var sessions []SessionInfo
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+
+	for scanner.Scan() {
+		parts := strings.Split(scanner.Text(), "\t")
+		if len(parts) != 9 {
+			continue
+		}
+
+		attached, err := strconv.Atoi(parts[1])
+		if err != nil {
+			continue
+		}
+
+		windows, err := strconv.Atoi(parts[2])
+		if err != nil {
+			continue
+		}
+
+		created, err := strconv.ParseInt(parts[3], 10, 64)
+		if err != nil {
+			continue
+		}
+
+		activity, err := strconv.ParseInt(parts[4], 10, 64)
+		if err != nil {
+			continue
+		}
+
+		sessions = append(sessions, SessionInfo{
+			Name:     parts[0],
+			Attached: attached > 0,
+			Windows:  windows,
+			Created:  time.Unix(created, 0),
+			Activity: time.Unix(activity, 0),
+			Forest:   parts[5] == "1",
+			Project:  parts[6],
+			Branch:   parts[7],
+			Path:     parts[8],
+		})
+	}
+
+	return sessions
+}
+
 // NewSession creates a new detached tmux session with the given name
 // and working directory. It does not switch to the session.
 func NewSession(name, workdir string) error {
-	cmd := exec.Command(
-		"tmux", "new-session",
-		"-d",
-		"-s", name,
-		"-c", workdir,
-	)
+	return newSession(name, workdir, "")
+}
+
+// NewSessionCmd is like NewSession but runs command in the session's
+// first window instead of a shell. Unlike sending the command as
+// keystrokes, it cannot arrive before the shell is ready. The window
+// closes when the command exits, which is why ApplyLayout still sends
+// layout commands to an interactive shell.
+func NewSessionCmd(name, workdir, command string) error {
+	return newSession(name, workdir, command)
+}
+
+func newSession(name, workdir, command string) error {
+	args := []string{"new-session", "-d", "-s", name, "-c", workdir}
+
+	if command != "" {
+		args = append(args, command)
+	}
+
+	cmd := exec.Command("tmux", args...)
 
 	output, err := cmd.CombinedOutput()
 	if err != nil {
@@ -50,6 +189,67 @@ func NewSession(name, workdir string) error {
 	return nil
 }
 
+// SetOption sets a session option, such as a user option starting
+// with "@", on the named session.
+func SetOption(session, key, value string) error {
+	cmd := exec.Command("tmux", "set-option", "-t", session, key, value)
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("tmux set-option: %s: %w", strings.TrimSpace(string(output)), err)
+	}
+
+	return nil
+}
+
+// ShowOption returns the value of a session option on the named
+// session, or an empty string if the option is not set.
+func ShowOption(session, key string) (string, error) {
+	cmd := exec.Command("tmux", "show-options", "-t", session, "-v", "-q", key)
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("tmux show-options: %s: %w", strings.TrimSpace(string(output)), err)
+	}
+
+	return strings.TrimSpace(string(output)), nil
+}
+
+// TagSession marks the named session as created by forest for the
+// given project, branch and worktree path.
+func TagSession(session, project, branch, path string) error {
+	options := [][2]string{
+		{OptionForest, "1"},
+		{OptionProject, project},
+		{OptionBranch, branch},
+		{OptionPath, path},
+	}
+
+	for _, o := range options {
+		if err := SetOption(session, o[0], o[1]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RunCommand runs a tmux command line, such as
+// "set-option -t mysession status-style bg=red", using tmux's own
+// command parser so quoting and ";" separators behave as in a tmux
+// config file.
+func RunCommand(line string) error {
+	cmd := exec.Command("tmux", "source-file", "-")
+	cmd.Stdin = strings.NewReader(line + "\n")
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("tmux %s: %s: %w", line, strings.TrimSpace(string(output)), err)
+	}
+
+	return nil
+}
+
 // SwitchTo moves the user to the named tmux session. Inside tmux it
 // switches the current client; outside tmux it attaches interactively.
 func SwitchTo(name string) error {
@@ -125,18 +325,28 @@ type LayoutWindow struct {
 
 	// Command is the shell command to run. Empty opens a plain shell.
 	Command string
+
+	// Shell is the program the window runs instead of tmux's default
+	// shell. Command is typed into it.
+	Shell string
 }
 
 // NewWindow creates a new window in the named session with its working
 // directory set to workdir. If name is non-empty, the window is given
-// that title. If command is non-empty, it is sent as keystrokes.
-func NewWindow(session, workdir string, w LayoutWindow) error {
+// that title. If shell is non-empty, the window runs it instead of the
+// default shell. If command is non-empty, it is sent as keystrokes once
+// the window's shell is ready, waiting at most readyTimeout.
+func NewWindow(session, workdir string, w LayoutWindow, readyTimeout time.Duration) error {
 	args := []string{"new-window", "-t", session, "-c", workdir}
 
 	if w.Name != "" {
 		args = append(args, "-n", w.Name)
 	}
 
+	if w.Shell != "" {
+		args = append(args, w.Shell)
+	}
+
 	cmd := exec.Command("tmux", args...)
 
 	output, err := cmd.CombinedOutput()
@@ -145,6 +355,7 @@ func NewWindow(session, workdir string, w LayoutWindow) error {
 	}
 
 	if w.Command != "" {
+		WaitForShell(session, readyTimeout)
 		return SendKeys(session, w.Command)
 	}
 
@@ -163,6 +374,19 @@ func RenameWindow(session, name string) error {
 	return nil
 }
 
+// SelectWindow makes the window with exactly the given name current
+// in the named session. It fails if no such window exists.
+func SelectWindow(session, name string) error {
+	cmd := exec.Command("tmux", "select-window", "-t", session+":="+name)
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("tmux select-window: %s: %w", strings.TrimSpace(string(output)), err)
+	}
+
+	return nil
+}
+
 // SendKeys sends a command string to the current window of the named
 // session, followed by Enter.
 func SendKeys(session, command string) error {
@@ -176,12 +400,87 @@ func SendKeys(session, command string) error {
 	return nil
 }
 
+// readyPollInterval is how often WaitForShell checks the pane.
+const readyPollInterval = 50 * time.Millisecond
+
+// shells are the pane commands WaitForShell recognizes as a shell, in
+// addition to the basename of $SHELL.
+var shells = []string{"bash", "zsh", "fish", "sh", "dash", "ksh", "mksh", "tcsh", "csh", "nu", "elvish", "xonsh"}
+
+// WaitForShell waits until the current pane of target is running a
+// shell that has printed its prompt, so that keys sent to it are not
+// discarded while the shell starts up. The prompt is taken to be
+// printed once the cursor has left the top-left corner and stopped
+// moving between polls. It gives up after timeout, reporting whether
+// the shell became ready; a zero timeout does not wait at all.
+func WaitForShell(target string, timeout time.Duration) bool {
+	if timeout <= 0 {
+		return false
+	}
+
+	deadline := time.Now().Add(timeout)
+
+	var last paneState
+
+	for {
+		state, ok := readPaneState(target)
+		if ok && isShell(state.command) && state.cursor != "0,0" && state == last {
+			return true
+		}
+
+		last = state
+
+		if time.Now().After(deadline) {
+			slog.Debug("timed out waiting for shell", slog.String("target", target), slog.Duration("timeout", timeout))
+			return false
+		}
+
+		time.Sleep(readyPollInterval)
+	}
+}
+
+// paneState is what WaitForShell watches in a pane.
+type paneState struct {
+	command string
+	cursor  string
+}
+
+// readPaneState returns the command running in the current pane of
+// target and its cursor position as "x,y".
+func readPaneState(target string) (paneState, bool) {
+	cmd := exec.Command("tmux", "display-message", "-p", "-t", target,
+		"#{pane_current_command}\t#{cursor_x},#{cursor_y}")
+
+	output, err := cmd.Output()
+	if err != nil {
+		return paneState{}, false
+	}
+
+	command, cursor, ok := strings.Cut(strings.TrimSpace(string(output)), "\t")
+
+	return paneState{command: command, cursor: cursor}, ok
+}
+
+// isShell reports whether a pane command name is a known shell.
+func isShell(name string) bool {
+	name = strings.TrimPrefix(name, "-")
+
+	if userShell := os.Getenv("SHELL"); userShell != "" && name == filepath.Base(userShell) {
+		return true
+	}
+
+	return slices.Contains(shells, name)
+}
+
 // ApplyLayout creates tmux windows for the given layout in the named
 // session. The first entry's command is sent to the session's initial
-// window (and it is renamed if a name is given). Subsequent entries
+// window (and it is renamed if a name is given); its shell is not
+// applied here, so the session must be created with NewSessionCmd for
+// it. Subsequent entries
 // each create a new window. workdir is the working directory for all
-// windows.
-func ApplyLayout(session, workdir string, windows []LayoutWindow) error {
+// windows. Each command waits up to readyTimeout for its window's
+// shell to start before it is sent.
+func ApplyLayout(session, workdir string, windows []LayoutWindow, readyTimeout time.Duration) error {
 	for i, w := range windows {
 		if i == 0 {
 			if w.Name != "" {
@@ -191,6 +490,8 @@ func ApplyLayout(session, workdir string, windows []LayoutWindow) error {
 			}
 
 			if w.Command != "" {
+				WaitForShell(session, readyTimeout)
+
 				if err := SendKeys(session, w.Command); err != nil {
 					return err
 				}
@@ -199,7 +500,7 @@ func ApplyLayout(session, workdir string, windows []LayoutWindow) error {
 			continue
 		}
 
-		if err := NewWindow(session, workdir, w); err != nil {
+		if err := NewWindow(session, workdir, w, readyTimeout); err != nil {
 			return err
 		}
 	}
@@ -213,20 +514,42 @@ func ApplyLayout(session, workdir string, windows []LayoutWindow) error {
 	return nil
 }
 
+// sessionNameReplacer rewrites characters that tmux rejects or
+// misinterprets in session names. Tmux does not allow dots, uses colons
+// as the session:window separator in target syntax, and whitespace
+// would need quoting in every tmux command line. Slashes are replaced
+// for consistency with git.SafeBranchDir.
+var sessionNameReplacer = strings.NewReplacer(
+	".", "_",
+	":", "-",
+	"/", "-",
+	" ", "-",
+	"\t", "-",
+	"\n", "-",
+)
+
+// MaxSessionNameLen is the longest session name SessionName returns.
+// Longer names are unwieldy in tmux and are shortened with a hash.
+const MaxSessionNameLen = 100
+
 // SessionName builds the conventional forest session name from a
-// project name and branch.
+// project name and branch. Names longer than MaxSessionNameLen are cut
+// short and end in a hash of the full project and branch, so distinct
+// long branches still get distinct sessions.
 func SessionName(project, branch string) string {
-	name := project + "-" + branch
-
-	// Tmux does not allow dots in session names.
-	name = strings.ReplaceAll(name, ".", "_")
+	name := sessionNameReplacer.Replace(project + "-" + branch)
+	if len(name) <= MaxSessionNameLen {
+		return name
+	}
 
-	// Replace slashes for consistency with SafeBranchDir.
-	name = strings.ReplaceAll(name, "/", "-")
+	sum := sha256.Sum256([]byte(project + "\x00" + branch))
+	suffix := "-" + hex.EncodeToString(sum[:4])
 
-	// Tmux uses colons as separator in target syntax (session:window).
-	// A colon in the session name would break target resolution.
-	name = strings.ReplaceAll(name, ":", "-")
+	// Cut on a rune boundary so the name stays valid UTF-8.
+	cut := MaxSessionNameLen - len(suffix)
+	for cut > 0 && !utf8.RuneStart(name[cut]) {
+		cut--
+	}
 
-	return name
+	return name[:cut] + suffix
 }
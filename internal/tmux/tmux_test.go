@@ -1,7 +1,12 @@
 package tmux
 
 import (
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
+	"unicode/utf8"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -33,6 +38,21 @@ func TestSessionName(t *testing.T) {
 			branch:  "fix:login:bug",
 			want:    "myapp-fix-login-bug",
 		},
+		{
+			project: "my app",
+			branch:  "feature/login page",
+			want:    "my-app-feature-login-page",
+		},
+		{
+			project: "myapp",
+			branch:  "fix/v1.2:hotfix",
+			want:    "myapp-fix-v1_2-hotfix",
+		},
+		{
+			project: "myapp",
+			branch:  "fonctionnalité/ログイン",
+			want:    "myapp-fonctionnalité-ログイン",
+		},
 	}
 
 	for _, tt := range tests {
@@ -42,6 +62,26 @@ func TestSessionName(t *testing.T) {
 	}
 }
 
+func TestSessionName_Long(t *testing.T) {
+	branch := "feature/" + strings.Repeat("deeply/nested/", 10)
+
+	a := SessionName("myapp", branch+"one")
+	b := SessionName("myapp", branch+"two")
+
+	assert.Len(t, a, MaxSessionNameLen)
+	assert.Len(t, b, MaxSessionNameLen)
+	assert.NotEqual(t, a, b)
+	assert.True(t, strings.HasPrefix(a, "myapp-feature-deeply-nested-"))
+
+	// The name is stable for the same project and branch.
+	assert.Equal(t, a, SessionName("myapp", branch+"one"))
+
+	// Multi-byte characters are not split.
+	wide := SessionName("myapp", strings.Repeat("é", 60))
+	assert.True(t, utf8.ValidString(wide))
+	assert.LessOrEqual(t, len(wide), MaxSessionNameLen)
+}
+
 func TestIsRunning(t *testing.T) {
 	t.Setenv("TMUX", "")
 	assert.False(t, IsRunning())
@@ -62,3 +102,178 @@ func TestSessionExists_NoSuchSession(t *testing.T) {
 	// This session name is unlikely to exist.
 	assert.False(t, SessionExists("forest-test-nonexistent-session-xyz"))
 }
+
+func TestNewSessionCmd(t *testing.T) {
+	tests := []struct {
+		name    string
+		command string
+		want    []string
+	}{
+		{
+			name: "no command",
+			want: []string{"new-session", "-d", "-s", "demo-feature", "-c", "/trees/demo/feature"},
+		},
+		{
+			name:    "command",
+			command: "nvim .",
+			want:    []string{"new-session", "-d", "-s", "demo-feature", "-c", "/trees/demo/feature", "nvim ."},
+		},
+		{
+			name:    "command with flags",
+			command: "fish -l -C 'set -x EDITOR nvim'",
+			want:    []string{"new-session", "-d", "-s", "demo-feature", "-c", "/trees/demo/feature", "fish -l -C 'set -x EDITOR nvim'"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := fakeTmux(t, "")
+
+			require.NoError(t, NewSessionCmd("demo-feature", "/trees/demo/feature", tt.command))
+			assert.Equal(t, [][]string{tt.want}, calls())
+		})
+	}
+}
+
+func TestNewSession_NoCommand(t *testing.T) {
+	calls := fakeTmux(t, "")
+
+	require.NoError(t, NewSession("demo-main", "/src/demo"))
+	assert.Equal(t, [][]string{{"new-session", "-d", "-s", "demo-main", "-c", "/src/demo"}}, calls())
+}
+
+func TestNewSessionCmd_Failure(t *testing.T) {
+	fakeTmux(t, "echo 'duplicate session: demo-feature' >&2; exit 1")
+
+	err := NewSessionCmd("demo-feature", "/trees/demo/feature", "nvim")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "tmux new-session: duplicate session: demo-feature")
+}
+
+func TestNewWindow_Shell(t *testing.T) {
+	tests := []struct {
+		name   string
+		window LayoutWindow
+		want   [][]string
+	}{
+		{
+			name:   "default shell",
+			window: LayoutWindow{Name: "logs"},
+			want:   [][]string{{"new-window", "-t", "demo", "-c", "/wt", "-n", "logs"}},
+		},
+		{
+			name:   "shell",
+			window: LayoutWindow{Name: "logs", Shell: "fish"},
+			want:   [][]string{{"new-window", "-t", "demo", "-c", "/wt", "-n", "logs", "fish"}},
+		},
+		{
+			name:   "shell with arguments and no name",
+			window: LayoutWindow{Shell: "bash --norc"},
+			want:   [][]string{{"new-window", "-t", "demo", "-c", "/wt", "bash --norc"}},
+		},
+		{
+			name:   "command typed into the shell",
+			window: LayoutWindow{Name: "logs", Shell: "fish", Command: "tail -f log"},
+			want: [][]string{
+				{"new-window", "-t", "demo", "-c", "/wt", "-n", "logs", "fish"},
+				{"send-keys", "-t", "demo", "tail -f log", "Enter"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := fakeTmux(t, "")
+
+			// A zero timeout sends the command without waiting.
+			require.NoError(t, NewWindow("demo", "/wt", tt.window, 0))
+			assert.Equal(t, tt.want, calls())
+		})
+	}
+}
+
+func TestApplyLayout_FirstWindowShellNotApplied(t *testing.T) {
+	calls := fakeTmux(t, "")
+
+	windows := []LayoutWindow{
+		{Name: "editor", Shell: "fish", Command: "nvim"},
+		{Name: "logs", Shell: "zsh"},
+	}
+
+	require.NoError(t, ApplyLayout("demo", "/wt", windows, 0))
+	assert.Equal(t, [][]string{
+		{"rename-window", "-t", "demo", "editor"},
+		{"send-keys", "-t", "demo", "nvim", "Enter"},
+		{"new-window", "-t", "demo", "-c", "/wt", "-n", "logs", "zsh"},
+		{"select-window", "-t", "demo:^"},
+	}, calls())
+}
+
+func TestParseSessions(t *testing.T) {
+	data := []byte("myapp-feature\t1\t3\t1700000000\t1700003600\t1\tmyapp\tfeature\t/trees/myapp/feature\n" +
+		"my app-main\t0\t1\t1700000100\t1700000100\t\t\t\t\n" +
+		"garbage line\n")
+
+	sessions := parseSessions(data)
+	require.Len(t, sessions, 2)
+
+	assert.Equal(t, SessionInfo{
+		Name:     "myapp-feature",
+		Attached: true,
+		Windows:  3,
+		Created:  time.Unix(1700000000, 0),
+		Activity: time.Unix(1700003600, 0),
+		Forest:   true,
+		Project:  "myapp",
+		Branch:   "feature",
+		Path:     "/trees/myapp/feature",
+	}, sessions[0])
+
+	assert.Equal(t, "my app-main", sessions[1].Name)
+	assert.False(t, sessions[1].Attached)
+	assert.Equal(t, 1, sessions[1].Windows)
+	assert.False(t, sessions[1].Forest)
+	assert.Empty(t, sessions[1].Project)
+}
+
+func TestIsShell(t *testing.T) {
+	t.Setenv("SHELL", "/opt/bin/oil")
+
+	assert.True(t, isShell("zsh"))
+	assert.True(t, isShell("-bash"))
+	assert.True(t, isShell("oil"))
+	assert.False(t, isShell("nvim"))
+}
+
+// fakeTmux puts a tmux on PATH that records its arguments and then
+// runs script, and returns a function listing the recorded calls, one
+// slice of arguments per call.
+func fakeTmux(t *testing.T, script string) func() [][]string {
+	t.Helper()
+
+	bin := t.TempDir()
+	log := filepath.Join(bin, "tmux.log")
+
+	// Each argument goes on its own line and each call ends with an
+	// empty line, so arguments with spaces stay intact.
+	body := "#!/bin/sh\nfor arg; do printf '%s\\n' \"$arg\"; done >> " + log + "\necho >> " + log + "\n" + script + "\n"
+	require.NoError(t, os.WriteFile(filepath.Join(bin, "tmux"), []byte(body), 0o755))
+	t.Setenv("PATH", bin)
+
+	return func() [][]string {
+		data, err := os.ReadFile(log)
+		if os.IsNotExist(err) {
+			return nil
+		}
+
+		require.NoError(t, err)
+
+		var calls [][]string
+
+		for _, call := range strings.Split(strings.TrimSuffix(string(data), "\n\n"), "\n\n") {
+			calls = append(calls, strings.Split(call, "\n"))
+		}
+
+		return calls
+	}
+}
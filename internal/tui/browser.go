@@ -12,6 +12,7 @@ import (
 	tea "charm.land/bubbletea/v2"
 	"charm.land/lipgloss/v2"
 
+	"github.com/mhamza15/forest/internal/clipboard"
 	"github.com/mhamza15/forest/internal/config"
 	"github.com/mhamza15/forest/internal/forest"
 	"github.com/mhamza15/forest/internal/git"
@@ -28,6 +29,9 @@ const (
 	modeConfirmForce
 	modeNewSelectProject
 	modeNewInputBranch
+	modeNewProjectPath
+	modePruneScanning
+	modeConfirmPrune
 )
 
 // projectNode is a collapsible project with its worktrees.
@@ -59,7 +63,8 @@ type Model struct {
 	// For the "new tree" flow: which project was selected.
 	newProject string
 
-	// Text input for branch name in new-tree mode.
+	// Text input for the branch name in new-tree mode and the repo
+	// path in add-project mode.
 	input textinput.Model
 
 	// Status messages shown after actions.
@@ -69,6 +74,24 @@ type Model struct {
 	// The chosen action to execute after the TUI exits. Nil means
 	// the user quit without selecting anything.
 	action func() error
+
+	// Sort orders for projects and the trees within them, and the git
+	// state used by the tree sorts, keyed by worktree path.
+	treeSort      treeSort
+	projectSort   projectSort
+	statusCache   map[string]treeStatus
+	statusLoading bool
+
+	// Prune candidates awaiting selection, the selected ones still to
+	// be removed, and how many have been removed so far.
+	pruneCandidates []pruneCandidate
+	pruneCursor     int
+	pruneQueue      []pruneCandidate
+	pruned          int
+
+	// Paths the user tried to copy while no clipboard was available,
+	// printed after the TUI exits instead.
+	yanked []string
 }
 
 // NewModel loads projects and their worktrees, returning a model
@@ -83,27 +106,18 @@ func NewModel(project string) (Model, error) {
 		names = []string{project}
 	}
 
-	var projects []projectNode
-
-	for _, name := range names {
-		proj, loadErr := config.LoadProject(name)
-		if loadErr != nil {
-			continue
-		}
-
-		trees, listErr := git.List(proj.Repo)
-		if listErr != nil {
-			trees = nil
-		}
+	global, err := config.LoadGlobal()
+	if err != nil {
+		return Model{}, err
+	}
 
-		projects = append(projects, projectNode{
-			name:     name,
-			repo:     proj.Repo,
-			trees:    trees,
-			expanded: project != "",
-		})
+	keys, err := newKeyMap(global.TUIKeys)
+	if err != nil {
+		return Model{}, err
 	}
 
+	projects := loadProjects(names, project != "")
+
 	ti := textinput.New()
 	ti.Placeholder = "branch name"
 	ti.CharLimit = 128
@@ -116,13 +130,40 @@ func NewModel(project string) (Model, error) {
 
 	return Model{
 		projects: projects,
-		keys:     defaultKeyMap(),
+		keys:     keys,
 		help:     help.New(),
 		spinner:  s,
 		input:    ti,
 	}, nil
 }
 
+// loadProjects loads the named projects and their worktrees, skipping
+// any whose config cannot be read.
+func loadProjects(names []string, expanded bool) []projectNode {
+	var projects []projectNode
+
+	for _, name := range names {
+		proj, loadErr := config.LoadProject(name)
+		if loadErr != nil {
+			continue
+		}
+
+		trees, listErr := git.List(proj.RepoPath())
+		if listErr != nil {
+			trees = nil
+		}
+
+		projects = append(projects, projectNode{
+			name:     name,
+			repo:     proj.RepoPath(),
+			trees:    trees,
+			expanded: expanded,
+		})
+	}
+
+	return projects
+}
+
 // Init satisfies tea.Model.
 func (m Model) Init() tea.Cmd {
 	return nil
@@ -138,22 +179,31 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case deleteResultMsg:
 		return m.handleDeleteResult(msg)
 
+	case pruneScanMsg:
+		return m.handlePruneScan(msg)
+
+	case pruneResultMsg:
+		return m.handlePruneResult(msg)
+
+	case statusMsg:
+		return m.handleStatus(msg)
+
 	case spinner.TickMsg:
-		if m.mode == modeDeleting {
+		if m.mode == modeDeleting || m.mode == modePruneScanning {
 			var cmd tea.Cmd
 			m.spinner, cmd = m.spinner.Update(msg)
 			return m, cmd
 		}
 
 	case tea.KeyPressMsg:
-		if m.mode == modeDeleting {
+		if m.mode == modeDeleting || m.mode == modePruneScanning {
 			return m, nil
 		}
 		return m.handleKey(msg)
 	}
 
 	// Forward to text input when active.
-	if m.mode == modeNewInputBranch {
+	if m.mode == modeNewInputBranch || m.mode == modeNewProjectPath {
 		var cmd tea.Cmd
 		m.input, cmd = m.input.Update(msg)
 		return m, cmd
@@ -168,6 +218,11 @@ func (m Model) handleKey(msg tea.KeyPressMsg) (tea.Model, tea.Cmd) {
 		return m.handleNewBranchInput(msg)
 	}
 
+	// In add-project mode, the input holds the repo path.
+	if m.mode == modeNewProjectPath {
+		return m.handleNewProjectInput(msg)
+	}
+
 	// In delete confirmation mode.
 	if m.mode == modeConfirmDelete {
 		return m.handleConfirmDelete(msg)
@@ -183,6 +238,11 @@ func (m Model) handleKey(msg tea.KeyPressMsg) (tea.Model, tea.Cmd) {
 		return m.handleNewSelectProject(msg)
 	}
 
+	// In the prune candidate list.
+	if m.mode == modeConfirmPrune {
+		return m.handleConfirmPrune(msg)
+	}
+
 	switch {
 	case key.Matches(msg, m.keys.Quit):
 		return m, tea.Quit
@@ -205,6 +265,27 @@ func (m Model) handleKey(msg tea.KeyPressMsg) (tea.Model, tea.Cmd) {
 	case key.Matches(msg, m.keys.New):
 		return m.startNew()
 
+	case key.Matches(msg, m.keys.Project):
+		return m.startNewProject()
+
+	case key.Matches(msg, m.keys.Yank):
+		return m.yankPath()
+
+	case key.Matches(msg, m.keys.Prune):
+		return m.startPrune()
+
+	case key.Matches(msg, m.keys.Sort):
+		m.keepCursor(func() { m.treeSort = (m.treeSort + 1) % (treeSortAhead + 1) })
+		m.status = "sorting trees by " + m.treeSort.String()
+
+		return m, m.loadStatuses()
+
+	case key.Matches(msg, m.keys.SortAll):
+		m.keepCursor(func() { m.projectSort = (m.projectSort + 1) % (projectSortActivity + 1) })
+		m.status = "sorting projects by " + m.projectSort.String()
+
+		return m, m.loadStatuses()
+
 	case key.Matches(msg, m.keys.Help):
 		m.help.ShowAll = !m.help.ShowAll
 	}
@@ -214,67 +295,79 @@ func (m Model) handleKey(msg tea.KeyPressMsg) (tea.Model, tea.Cmd) {
 
 // View renders the inline tree browser.
 func (m Model) View() tea.View {
-	if len(m.projects) == 0 {
-		return tea.NewView(styleDim.Render("No projects registered. Use 'forest project add' to add one.") + "\n")
+	if len(m.projects) == 0 && m.mode == modeBrowse {
+		msg := fmt.Sprintf("No projects registered. Press %s to add one, or %s to quit.",
+			m.keys.Project.Help().Key, m.keys.Quit.Help().Key)
+
+		return tea.NewView(styleDim.Render(msg) + "\n")
 	}
 
 	var b strings.Builder
 
 	b.WriteString(styleHeader.Render("Projects") + "\n")
 
-	row := 0
+	for i, r := range m.rows() {
+		p := m.projects[r.project]
 
-	for _, p := range m.projects {
-		prefix := "  "
-		if row == m.cursor {
-			prefix = styleCursor.Render("> ")
-		}
+		if r.tree == -1 {
+			prefix := "  "
+			if i == m.cursor {
+				prefix = styleCursor.Render("> ")
+			}
 
-		arrow := ">"
-		if p.expanded {
-			arrow = "v"
-		}
+			arrow := ">"
+			if p.expanded {
+				arrow = "v"
+			}
 
-		line := fmt.Sprintf("%s%s %s", prefix, styleDim.Render(arrow), styleProject.Render(p.name))
-		b.WriteString(line + "\n")
-		row++
+			line := fmt.Sprintf("%s%s %s", prefix, styleDim.Render(arrow), styleProject.Render(p.name))
+			b.WriteString(line + "\n")
 
-		if !p.expanded {
 			continue
 		}
 
-		for _, t := range p.trees {
-			treePrefix := "    "
-			if row == m.cursor {
-				treePrefix = styleCursor.Render("  > ")
-			}
-
-			branch := t.Branch
-			if branch == "" {
-				branch = "(detached)"
-			}
+		treePrefix := "    "
+		if i == m.cursor {
+			treePrefix = styleCursor.Render("  > ")
+		}
 
-			b.WriteString(treePrefix + styleTree.Render(branch) + "\n")
-			row++
+		branch := p.trees[r.tree].Branch
+		if branch == "" {
+			branch = "(detached)"
 		}
+
+		b.WriteString(treePrefix + styleTree.Render(branch) + "\n")
 	}
 
 	// Mode-specific footer.
 	switch m.mode {
 	case modeConfirmDelete:
-		b.WriteString("\n" + styleError.Render("Delete this tree? (y/N)") + "\n")
+		b.WriteString("\n" + styleError.Render(fmt.Sprintf("Delete this tree? (%s/N)", m.keys.Confirm.Help().Key)) + "\n")
 
 	case modeConfirmForce:
-		b.WriteString("\n" + styleError.Render("Worktree has modified or untracked files. Force remove? (y/N)") + "\n")
+		b.WriteString("\n" + styleError.Render(fmt.Sprintf("Worktree has modified or untracked files. Force remove? (%s/N)", m.keys.Confirm.Help().Key)) + "\n")
 
 	case modeDeleting:
-		b.WriteString("\n" + m.spinner.View() + " Deleting...\n")
+		if len(m.pruneQueue) > 0 {
+			b.WriteString("\n" + m.spinner.View() + " Pruning " + m.pruneQueue[0].branch + "...\n")
+		} else {
+			b.WriteString("\n" + m.spinner.View() + " Deleting...\n")
+		}
+
+	case modePruneScanning:
+		b.WriteString("\n" + m.spinner.View() + " Checking for prunable trees...\n")
+
+	case modeConfirmPrune:
+		b.WriteString(m.pruneView())
 
 	case modeNewSelectProject:
 		b.WriteString("\n" + styleDim.Render("Select a project for the new tree, then press enter") + "\n")
 
 	case modeNewInputBranch:
 		b.WriteString("\n" + styleDim.Render("Branch name: ") + m.input.View() + "\n")
+
+	case modeNewProjectPath:
+		b.WriteString("\n" + styleDim.Render("Repository path: ") + m.input.View() + "\n")
 	}
 
 	if m.status != "" {
@@ -286,7 +379,13 @@ func (m Model) View() tea.View {
 	}
 
 	if m.mode == modeBrowse {
-		b.WriteString("\n" + m.help.View(m.keys) + "\n")
+		if path := m.selectedPath(); path != "" {
+			b.WriteString("\n" + styleDim.Render(path) + "\n")
+		}
+	}
+
+	if m.mode != modeDeleting && m.mode != modePruneScanning {
+		b.WriteString("\n" + m.help.View(modeKeyMap{keys: m.keys, mode: m.mode}) + "\n")
 	}
 
 	return tea.NewView(b.String())
@@ -327,24 +426,9 @@ func (m *Model) moveCursor(delta int) {
 // cursor is pointing at. treeIdx is -1 if the cursor is on a project
 // row.
 func (m Model) cursorTarget() (projectIdx int, treeIdx int) {
-	row := 0
-
-	for pi, p := range m.projects {
-		if row == m.cursor {
-			return pi, -1
-		}
-		row++
-
-		if !p.expanded {
-			continue
-		}
-
-		for ti := range p.trees {
-			if row == m.cursor {
-				return pi, ti
-			}
-			row++
-		}
+	rows := m.rows()
+	if m.cursor < len(rows) {
+		return rows[m.cursor].project, rows[m.cursor].tree
 	}
 
 	return 0, -1
@@ -382,7 +466,7 @@ func (m Model) openSelected() (tea.Model, tea.Cmd) {
 			return err
 		}
 
-		if err := forest.OpenSession(rc, branch, wtPath); err != nil {
+		if _, err := forest.OpenSession(rc, branch, wtPath); err != nil {
 			return err
 		}
 
@@ -392,6 +476,42 @@ func (m Model) openSelected() (tea.Model, tea.Cmd) {
 	return m, tea.Quit
 }
 
+// selectedPath returns the filesystem path of the row under the
+// cursor: the worktree path for a tree, or the repo for a project.
+func (m Model) selectedPath() string {
+	if len(m.projects) == 0 {
+		return ""
+	}
+
+	pi, ti := m.cursorTarget()
+	if ti == -1 {
+		return m.projects[pi].repo
+	}
+
+	return m.projects[pi].trees[ti].Path
+}
+
+// yankPath copies the selected row's path to the clipboard. Without a
+// clipboard, the path is remembered and printed when the TUI exits.
+func (m Model) yankPath() (tea.Model, tea.Cmd) {
+	path := m.selectedPath()
+	if path == "" {
+		return m, nil
+	}
+
+	m.err = nil
+
+	if err := clipboard.Write(path); err != nil {
+		m.yanked = append(m.yanked, path)
+		m.status = "no clipboard available, path will be printed on exit"
+		return m, nil
+	}
+
+	m.status = "copied " + path
+
+	return m, nil
+}
+
 func (m Model) startDelete() (tea.Model, tea.Cmd) {
 	_, ti := m.cursorTarget()
 
@@ -552,6 +672,7 @@ func (m Model) handleNewSelectProject(msg tea.KeyPressMsg) (tea.Model, tea.Cmd)
 		if pi < len(m.projects) {
 			m.newProject = m.projects[pi].name
 			m.mode = modeNewInputBranch
+			m.input.Placeholder = "branch name"
 			m.input.Reset()
 			m.input.Focus()
 			return m, textinput.Blink
@@ -585,8 +706,6 @@ func (m Model) executeNewTree() (tea.Model, tea.Cmd) {
 		return m, nil
 	}
 
-	m.input.Blur()
-
 	rc, err := config.Resolve(m.newProject)
 	if err != nil {
 		m.err = err
@@ -594,6 +713,17 @@ func (m Model) executeNewTree() (tea.Model, tea.Cmd) {
 		return m, nil
 	}
 
+	// Reject a malformed name for a new branch while the input is
+	// still open to correct it.
+	if !git.BranchExists(rc.Repo, branch) {
+		if err := git.ValidateBranchName(rc.Repo, branch); err != nil {
+			m.err = err
+			return m, nil
+		}
+	}
+
+	m.input.Blur()
+
 	result, err := forest.AddTree(rc, branch)
 	if err != nil {
 		m.err = err
@@ -623,6 +753,83 @@ func (m Model) executeNewTree() (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
+func (m Model) startNewProject() (tea.Model, tea.Cmd) {
+	m.mode = modeNewProjectPath
+	m.status = ""
+	m.err = nil
+	m.input.Placeholder = "path to git repository"
+	m.input.Reset()
+	m.input.Focus()
+
+	return m, textinput.Blink
+}
+
+func (m Model) handleNewProjectInput(msg tea.KeyPressMsg) (tea.Model, tea.Cmd) {
+	switch msg.Code {
+	case tea.KeyEscape:
+		m.mode = modeBrowse
+		m.err = nil
+		m.input.Blur()
+		return m, nil
+
+	case tea.KeyEnter:
+		return m.executeNewProject()
+	}
+
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+
+	return m, cmd
+}
+
+// executeNewProject registers the repository at the entered path and
+// reloads it into the list. Invalid paths keep the input open with an
+// inline error so the user can correct them.
+func (m Model) executeNewProject() (tea.Model, tea.Cmd) {
+	path := strings.TrimSpace(m.input.Value())
+	if path == "" {
+		m.err = fmt.Errorf("repository path cannot be empty")
+		return m, nil
+	}
+
+	name, _, err := forest.RegisterProject(path, "")
+	if err != nil {
+		m.err = err
+		return m, nil
+	}
+
+	m.input.Blur()
+	m.err = nil
+
+	// Drop any stale entry with the same name before reloading it.
+	projects := m.projects[:0]
+	for _, p := range m.projects {
+		if p.name != name {
+			projects = append(projects, p)
+		}
+	}
+
+	m.projects = append(projects, loadProjects([]string{name}, true)...)
+
+	// Put the cursor on the new project's row.
+	for i, r := range m.rows() {
+		if r.tree == -1 && m.projects[r.project].name == name {
+			m.cursor = i
+			break
+		}
+	}
+	m.status = fmt.Sprintf("registered project %s", name)
+	m.mode = modeBrowse
+
+	return m, nil
+}
+
+// Yanked returns the paths the user copied while no clipboard was
+// available. The caller should print them after the TUI exits.
+func (m Model) Yanked() []string {
+	return m.yanked
+}
+
 // Action returns the post-exit action, if any. The caller should
 // execute this after the TUI program finishes.
 func (m Model) Action() func() error {
@@ -1,7 +1,13 @@
 // Package tui implements the inline tree browser for forest.
 package tui
 
-import "charm.land/bubbles/v2/key"
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"charm.land/bubbles/v2/key"
+)
 
 // keyMap defines all keybindings for the tree browser.
 type keyMap struct {
@@ -11,6 +17,12 @@ type keyMap struct {
 	Open    key.Binding
 	Delete  key.Binding
 	New     key.Binding
+	Project key.Binding
+	Yank    key.Binding
+	Prune   key.Binding
+	Select  key.Binding
+	Sort    key.Binding
+	SortAll key.Binding
 	Confirm key.Binding
 	Cancel  key.Binding
 	Help    key.Binding
@@ -22,13 +34,66 @@ func (k keyMap) ShortHelp() []key.Binding {
 	return []key.Binding{k.Up, k.Down, k.Open, k.Toggle, k.Help, k.Quit}
 }
 
-// FullHelp returns keybindings for the expanded help view.
+// FullHelp returns keybindings for the expanded help view. Prompt keys
+// such as Confirm are left to modeKeyMap, which shows them only while
+// a prompt is open.
 func (k keyMap) FullHelp() [][]key.Binding {
 	return [][]key.Binding{
 		{k.Up, k.Down, k.Open, k.Toggle},
-		{k.Delete, k.New, k.Confirm, k.Cancel},
-		{k.Help, k.Quit},
+		{k.Delete, k.New, k.Project, k.Yank, k.Prune},
+		{k.Sort, k.SortAll, k.Help, k.Quit},
+	}
+}
+
+// Text inputs read enter and esc directly, since other keys are typed
+// into the input. These bindings only describe them in the help view.
+var (
+	inputSubmit = key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "submit"))
+	inputCancel = key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc", "cancel"))
+)
+
+// modeKeyMap shows the bindings that apply in the browser's current
+// mode, so that the help view is accurate outside of browsing too.
+type modeKeyMap struct {
+	keys keyMap
+	mode mode
+}
+
+// ShortHelp returns the bindings of the current mode.
+func (k modeKeyMap) ShortHelp() []key.Binding {
+	switch k.mode {
+	case modeConfirmDelete, modeConfirmForce:
+		return []key.Binding{k.keys.Confirm, k.keys.Cancel}
+
+	case modeNewSelectProject:
+		return []key.Binding{k.keys.Up, k.keys.Down, withDesc(k.keys.Open, "select project"), k.keys.Cancel}
+
+	case modeNewInputBranch, modeNewProjectPath:
+		return []key.Binding{inputSubmit, inputCancel}
+
+	case modeConfirmPrune:
+		return []key.Binding{k.keys.Up, k.keys.Down, k.keys.Select, withDesc(k.keys.Open, "remove selected"), k.keys.Cancel}
+
+	default:
+		return k.keys.ShortHelp()
+	}
+}
+
+// FullHelp returns the expanded bindings of the current mode. Modes
+// other than browsing have few enough bindings to show them all in
+// the short view.
+func (k modeKeyMap) FullHelp() [][]key.Binding {
+	if k.mode == modeBrowse {
+		return k.keys.FullHelp()
 	}
+
+	return [][]key.Binding{k.ShortHelp()}
+}
+
+// withDesc returns a copy of b described as desc in the help view.
+func withDesc(b key.Binding, desc string) key.Binding {
+	b.SetHelp(b.Help().Key, desc)
+	return b
 }
 
 func defaultKeyMap() keyMap {
@@ -63,6 +128,36 @@ func defaultKeyMap() keyMap {
 			key.WithHelp("n", "new tree"),
 		),
 
+		Project: key.NewBinding(
+			key.WithKeys("p"),
+			key.WithHelp("p", "add project"),
+		),
+
+		Yank: key.NewBinding(
+			key.WithKeys("y"),
+			key.WithHelp("y", "copy path"),
+		),
+
+		Prune: key.NewBinding(
+			key.WithKeys("P"),
+			key.WithHelp("P", "prune"),
+		),
+
+		Select: key.NewBinding(
+			key.WithKeys("space", "x"),
+			key.WithHelp("space", "select"),
+		),
+
+		Sort: key.NewBinding(
+			key.WithKeys("s"),
+			key.WithHelp("s", "sort trees"),
+		),
+
+		SortAll: key.NewBinding(
+			key.WithKeys("S"),
+			key.WithHelp("S", "sort projects"),
+		),
+
 		Confirm: key.NewBinding(
 			key.WithKeys("y"),
 			key.WithHelp("y", "yes"),
@@ -84,3 +179,78 @@ func defaultKeyMap() keyMap {
 		),
 	}
 }
+
+// bindings returns pointers to the bindings that can be overridden,
+// keyed by the action names used in the tui_keys config setting.
+func (k *keyMap) bindings() map[string]*key.Binding {
+	return map[string]*key.Binding{
+		"up":       &k.Up,
+		"down":     &k.Down,
+		"toggle":   &k.Toggle,
+		"open":     &k.Open,
+		"delete":   &k.Delete,
+		"new":      &k.New,
+		"project":  &k.Project,
+		"yank":     &k.Yank,
+		"prune":    &k.Prune,
+		"select":   &k.Select,
+		"sort":     &k.Sort,
+		"sort_all": &k.SortAll,
+		"confirm":  &k.Confirm,
+		"cancel":   &k.Cancel,
+		"help":     &k.Help,
+		"quit":     &k.Quit,
+	}
+}
+
+// keyGroups lists the actions that are active at the same time. A key
+// may be reused across groups, such as y for both copying a path and
+// confirming a delete, but not within one.
+var keyGroups = [][]string{
+	{"up", "down", "toggle", "open", "delete", "new", "project", "yank", "prune", "sort", "sort_all", "help", "quit"},
+	{"up", "down", "open", "select", "cancel", "quit"},
+	{"confirm", "cancel"},
+}
+
+// newKeyMap returns the default keymap with the keys in overrides
+// replacing those of the named actions. Each override replaces all of
+// an action's default keys. Unknown actions and keys bound to two
+// actions that are active at the same time are errors.
+func newKeyMap(overrides map[string]string) (keyMap, error) {
+	k := defaultKeyMap()
+	bindings := k.bindings()
+
+	for action, keys := range overrides {
+		b, ok := bindings[action]
+		if !ok {
+			return k, fmt.Errorf("tui_keys: unknown action %q", action)
+		}
+
+		keys = strings.TrimSpace(keys)
+		if keys == "" {
+			return k, fmt.Errorf("tui_keys: no key given for %s", action)
+		}
+
+		b.SetKeys(keys)
+		b.SetHelp(keys, b.Help().Desc)
+	}
+
+	for _, group := range keyGroups {
+		owner := make(map[string]string)
+
+		for _, action := range group {
+			for _, bound := range bindings[action].Keys() {
+				if other, ok := owner[bound]; ok {
+					pair := []string{other, action}
+					slices.Sort(pair)
+
+					return k, fmt.Errorf("tui_keys: %s and %s are both bound to %q", pair[0], pair[1], bound)
+				}
+
+				owner[bound] = action
+			}
+		}
+	}
+
+	return k, nil
+}
@@ -0,0 +1,84 @@
+package tui
+
+import (
+	"testing"
+
+	"charm.land/bubbles/v2/help"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewKeyMap_Override(t *testing.T) {
+	k, err := newKeyMap(map[string]string{"delete": "x", "new": "c"})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"x"}, k.Delete.Keys())
+	assert.Equal(t, "x", k.Delete.Help().Key)
+	assert.Equal(t, "delete", k.Delete.Help().Desc)
+	assert.Equal(t, []string{"c"}, k.New.Keys())
+	assert.Equal(t, []string{"d"}, defaultKeyMap().Delete.Keys())
+}
+
+func TestNewKeyMap_Conflict(t *testing.T) {
+	_, err := newKeyMap(map[string]string{"delete": "n"})
+	assert.EqualError(t, err, `tui_keys: delete and new are both bound to "n"`)
+}
+
+func TestNewKeyMap_ReuseAcrossModes(t *testing.T) {
+	// y confirms deletes and copies paths, which are never active at
+	// the same time.
+	_, err := newKeyMap(map[string]string{"confirm": "y", "yank": "y"})
+	require.NoError(t, err)
+}
+
+func TestNewKeyMap_UnknownAction(t *testing.T) {
+	_, err := newKeyMap(map[string]string{"explode": "e"})
+	assert.EqualError(t, err, `tui_keys: unknown action "explode"`)
+}
+
+func TestNewKeyMap_Defaults(t *testing.T) {
+	_, err := newKeyMap(nil)
+	require.NoError(t, err)
+}
+
+func TestModeKeyMap(t *testing.T) {
+	keys := defaultKeyMap()
+
+	helpKeys := func(m mode) []string {
+		var out []string
+		for _, b := range (modeKeyMap{keys: keys, mode: m}).ShortHelp() {
+			out = append(out, b.Help().Key+" "+b.Help().Desc)
+		}
+
+		return out
+	}
+
+	assert.Equal(t, []string{"y yes", "N/esc cancel"}, helpKeys(modeConfirmDelete))
+	assert.Equal(t, []string{"enter submit", "esc cancel"}, helpKeys(modeNewInputBranch))
+	assert.Contains(t, helpKeys(modeConfirmPrune), "enter remove selected")
+	assert.Equal(t, "open", keys.Open.Help().Desc)
+	assert.Len(t, (modeKeyMap{keys: keys, mode: modeBrowse}).FullHelp(), len(keys.FullHelp()))
+}
+
+func TestHelpView(t *testing.T) {
+	// Unstyled, so the output can be matched as plain text.
+	h := help.New()
+	h.Styles = help.Styles{}
+	h.SetWidth(500)
+
+	keys := modeKeyMap{keys: defaultKeyMap(), mode: modeBrowse}
+
+	short := h.View(keys)
+	assert.Contains(t, short, "enter open")
+	assert.Contains(t, short, "? toggle help")
+	assert.NotContains(t, short, "d delete")
+
+	h.ShowAll = true
+
+	full := h.View(keys)
+	for _, want := range []string{"delete", "new tree", "add project", "copy path", "prune", "sort trees", "sort projects", "quit"} {
+		assert.Contains(t, full, want)
+	}
+	assert.NotContains(t, full, "yes")
+}
@@ -0,0 +1,279 @@
+package tui
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"charm.land/bubbles/v2/key"
+	tea "charm.land/bubbletea/v2"
+
+	"github.com/mhamza15/forest/internal/config"
+	"github.com/mhamza15/forest/internal/forest"
+	"github.com/mhamza15/forest/internal/git"
+)
+
+// pruneCandidate is a worktree that can be pruned, shown in the prune
+// list with the reason it qualifies.
+type pruneCandidate struct {
+	project  string
+	branch   string
+	path     string
+	label    forest.PruneLabel
+	dirty    bool
+	selected bool
+}
+
+// pruneScanMsg carries the candidates found by an async prune scan and
+// the projects that could not be checked.
+type pruneScanMsg struct {
+	candidates []pruneCandidate
+	skipped    []string
+}
+
+// pruneResultMsg carries the outcome of removing one prune candidate.
+type pruneResultMsg struct {
+	candidate pruneCandidate
+	err       error
+}
+
+// startPrune scans the loaded projects for prunable trees in the
+// background, showing the spinner until the list is ready.
+func (m Model) startPrune() (tea.Model, tea.Cmd) {
+	if len(m.projects) == 0 {
+		return m, nil
+	}
+
+	names := make([]string, len(m.projects))
+	for i, p := range m.projects {
+		names[i] = p.name
+	}
+
+	m.mode = modePruneScanning
+	m.status = ""
+	m.err = nil
+
+	scanCmd := func() tea.Msg {
+		var msg pruneScanMsg
+
+		// A broken project is skipped so that it does not block
+		// cleaning up the others, as in tree prune.
+		for _, name := range names {
+			found, err := scanPrune(name)
+			if err != nil {
+				slog.Debug("skipping project in prune scan", slog.String("project", name), slog.Any("err", err))
+				msg.skipped = append(msg.skipped, name)
+
+				continue
+			}
+
+			msg.candidates = append(msg.candidates, found...)
+		}
+
+		return msg
+	}
+
+	return m, tea.Batch(m.spinner.Tick, scanCmd)
+}
+
+// scanPrune returns the prunable trees of one project, chosen the same
+// way as tree prune. Branches that tree prune would remove without
+// asking start selected. Branches that are only gone from the remote
+// may not have been merged, and dirty worktrees would lose their
+// changes, so those must be selected explicitly.
+func scanPrune(name string) ([]pruneCandidate, error) {
+	rc, trees, err := forest.LoadPruneProject(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []pruneCandidate
+
+	for _, c := range forest.PruneCandidates(rc, trees, forest.PruneOptions{}) {
+		dirty, err := git.IsDirty(c.Path)
+		if err != nil {
+			slog.Debug("could not check worktree status", slog.String("path", c.Path), slog.Any("err", err))
+		}
+
+		candidates = append(candidates, pruneCandidate{
+			project:  name,
+			branch:   c.Branch,
+			path:     c.Path,
+			label:    c.Label,
+			dirty:    dirty,
+			selected: !c.Confirm && !dirty,
+		})
+	}
+
+	return candidates, nil
+}
+
+// handlePruneScan shows the prune list once the scan finishes.
+func (m Model) handlePruneScan(msg pruneScanMsg) (tea.Model, tea.Cmd) {
+	m.mode = modeBrowse
+
+	if len(msg.skipped) > 0 {
+		m.err = fmt.Errorf("could not check %s", strings.Join(msg.skipped, ", "))
+	}
+
+	if len(msg.candidates) == 0 {
+		m.status = "nothing to prune"
+		return m, nil
+	}
+
+	m.pruneCandidates = msg.candidates
+	m.pruneCursor = 0
+	m.mode = modeConfirmPrune
+
+	return m, nil
+}
+
+func (m Model) handleConfirmPrune(msg tea.KeyPressMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, m.keys.Quit), key.Matches(msg, m.keys.Cancel):
+		m.mode = modeBrowse
+		m.pruneCandidates = nil
+		m.status = "prune cancelled"
+
+	case key.Matches(msg, m.keys.Up):
+		if m.pruneCursor > 0 {
+			m.pruneCursor--
+		}
+
+	case key.Matches(msg, m.keys.Down):
+		if m.pruneCursor < len(m.pruneCandidates)-1 {
+			m.pruneCursor++
+		}
+
+	case key.Matches(msg, m.keys.Select):
+		c := &m.pruneCandidates[m.pruneCursor]
+		c.selected = !c.selected
+
+	case key.Matches(msg, m.keys.Open):
+		return m.executePrune()
+	}
+
+	return m, nil
+}
+
+// executePrune removes the selected candidates one at a time, reusing
+// the delete spinner until the last removal finishes.
+func (m Model) executePrune() (tea.Model, tea.Cmd) {
+	var queue []pruneCandidate
+
+	for _, c := range m.pruneCandidates {
+		if c.selected {
+			queue = append(queue, c)
+		}
+	}
+
+	m.pruneCandidates = nil
+
+	if len(queue) == 0 {
+		m.mode = modeBrowse
+		m.status = "nothing selected to prune"
+		return m, nil
+	}
+
+	m.pruneQueue = queue
+	m.pruned = 0
+	m.mode = modeDeleting
+
+	return m, tea.Batch(m.spinner.Tick, pruneNext(queue[0]))
+}
+
+// pruneNext returns a command that removes one candidate. Like tree
+// prune, dirty worktrees are removed anyway.
+func pruneNext(c pruneCandidate) tea.Cmd {
+	return func() tea.Msg {
+		rc, err := config.Resolve(c.project)
+		if err != nil {
+			return pruneResultMsg{candidate: c, err: err}
+		}
+
+		return pruneResultMsg{candidate: c, err: forest.RemoveTree(rc, c.branch, true)}
+	}
+}
+
+// handlePruneResult drops a removed tree from the list and starts the
+// next removal. A failure stops the remaining removals.
+func (m Model) handlePruneResult(msg pruneResultMsg) (tea.Model, tea.Cmd) {
+	c := msg.candidate
+
+	if msg.err != nil {
+		m.mode = modeBrowse
+		m.pruneQueue = nil
+		m.err = fmt.Errorf("pruning %s/%s: %w", c.project, c.branch, msg.err)
+		m.status = fmt.Sprintf("pruned %d tree(s)", m.pruned)
+		return m, nil
+	}
+
+	m.removeTree(c.project, c.path)
+	m.pruned++
+	m.pruneQueue = m.pruneQueue[1:]
+
+	if len(m.pruneQueue) > 0 {
+		return m, pruneNext(m.pruneQueue[0])
+	}
+
+	m.mode = modeBrowse
+	m.status = fmt.Sprintf("pruned %d tree(s)", m.pruned)
+
+	return m, nil
+}
+
+// removeTree drops the tree at wtPath from the named project and keeps
+// the cursor on a visible row.
+func (m *Model) removeTree(project, wtPath string) {
+	for pi, p := range m.projects {
+		if p.name != project {
+			continue
+		}
+
+		for ti, t := range p.trees {
+			if t.Path == wtPath {
+				m.projects[pi].trees = append(p.trees[:ti], p.trees[ti+1:]...)
+				break
+			}
+		}
+	}
+
+	total := m.visibleRows()
+	if m.cursor >= total && total > 0 {
+		m.cursor = total - 1
+	}
+}
+
+// pruneView renders the prune list with a checkbox and reason per
+// candidate. Dirty worktrees are flagged, since pruning removes them
+// with their changes. The keys are listed by the help view.
+func (m Model) pruneView() string {
+	var b strings.Builder
+
+	b.WriteString("\n" + styleHeader.Render("Prune") + "\n")
+
+	for i, c := range m.pruneCandidates {
+		prefix := "  "
+		if i == m.pruneCursor {
+			prefix = styleCursor.Render("> ")
+		}
+
+		check := "[ ]"
+		if c.selected {
+			check = "[x]"
+		}
+
+		reason := styleMerged.Render(string(c.label))
+		if c.label == forest.PruneLabelGone {
+			reason = styleGone.Render(string(c.label))
+		}
+
+		if c.dirty {
+			reason += " " + styleError.Render("dirty")
+		}
+
+		fmt.Fprintf(&b, "%s%s %s/%s %s\n", prefix, check, styleProject.Render(c.project), styleTree.Render(c.branch), reason)
+	}
+
+	return b.String()
+}
@@ -0,0 +1,93 @@
+package tui
+
+import (
+	"errors"
+	"testing"
+
+	tea "charm.land/bubbletea/v2"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mhamza15/forest/internal/forest"
+	"github.com/mhamza15/forest/internal/git"
+)
+
+// press sends a key press to the model and returns the updated model.
+func press(t *testing.T, m Model, msg tea.KeyPressMsg) (Model, tea.Cmd) {
+	t.Helper()
+
+	next, cmd := m.Update(msg)
+
+	return next.(Model), cmd
+}
+
+func TestPrune_SelectExecuteAndResults(t *testing.T) {
+	m := Model{
+		keys: defaultKeyMap(),
+		projects: []projectNode{{
+			name:     "demo",
+			expanded: true,
+			trees: []git.Worktree{
+				{Path: "/trees/demo/main", Branch: "main"},
+				{Path: "/trees/demo/merged", Branch: "merged"},
+				{Path: "/trees/demo/gone", Branch: "gone"},
+				{Path: "/trees/demo/dirty", Branch: "dirty"},
+			},
+		}},
+	}
+
+	next, _ := m.Update(pruneScanMsg{
+		candidates: []pruneCandidate{
+			{project: "demo", branch: "merged", path: "/trees/demo/merged", label: forest.PruneLabelMerged, selected: true},
+			{project: "demo", branch: "gone", path: "/trees/demo/gone", label: forest.PruneLabelGone},
+			{project: "demo", branch: "dirty", path: "/trees/demo/dirty", label: forest.PruneLabelMerged, dirty: true, selected: true},
+		},
+		skipped: []string{"broken"},
+	})
+	m = next.(Model)
+
+	require.Equal(t, modeConfirmPrune, m.mode)
+	require.ErrorContains(t, m.err, "broken")
+	assert.Contains(t, m.pruneView(), "dirty")
+
+	// Select the gone branch and deselect the dirty one.
+	m, _ = press(t, m, tea.KeyPressMsg{Code: tea.KeyDown})
+	m, _ = press(t, m, tea.KeyPressMsg{Code: 'x', Text: "x"})
+	m, _ = press(t, m, tea.KeyPressMsg{Code: tea.KeyDown})
+	m, _ = press(t, m, tea.KeyPressMsg{Code: 'x', Text: "x"})
+
+	m, cmd := press(t, m, tea.KeyPressMsg{Code: tea.KeyEnter})
+	require.NotNil(t, cmd)
+	assert.Equal(t, modeDeleting, m.mode)
+	require.Len(t, m.pruneQueue, 2)
+	assert.Equal(t, "merged", m.pruneQueue[0].branch)
+	assert.Equal(t, "gone", m.pruneQueue[1].branch)
+
+	next, cmd = m.Update(pruneResultMsg{candidate: m.pruneQueue[0]})
+	m = next.(Model)
+	require.NotNil(t, cmd, "the next removal should start")
+	assert.Equal(t, modeDeleting, m.mode)
+	assert.Len(t, m.projects[0].trees, 3)
+
+	next, _ = m.Update(pruneResultMsg{candidate: m.pruneQueue[0], err: errors.New("locked")})
+	m = next.(Model)
+	assert.Equal(t, modeBrowse, m.mode)
+	assert.Empty(t, m.pruneQueue)
+	assert.ErrorContains(t, m.err, "demo/gone")
+	assert.Equal(t, "pruned 1 tree(s)", m.status)
+	assert.Len(t, m.projects[0].trees, 3)
+}
+
+func TestPrune_NothingSelected(t *testing.T) {
+	m := Model{
+		keys:            defaultKeyMap(),
+		mode:            modeConfirmPrune,
+		pruneCandidates: []pruneCandidate{{project: "demo", branch: "gone", label: forest.PruneLabelGone}},
+	}
+
+	m, cmd := press(t, m, tea.KeyPressMsg{Code: tea.KeyEnter})
+	assert.Nil(t, cmd)
+	assert.Equal(t, modeBrowse, m.mode)
+	assert.Equal(t, "nothing selected to prune", m.status)
+}
@@ -0,0 +1,281 @@
+package tui
+
+import (
+	"cmp"
+	"maps"
+	"slices"
+	"time"
+
+	tea "charm.land/bubbletea/v2"
+
+	"github.com/mhamza15/forest/internal/git"
+)
+
+// treeSort orders the trees within each project.
+type treeSort int
+
+const (
+	// treeSortGit keeps the order reported by git worktree list.
+	treeSortGit treeSort = iota
+	treeSortBranch
+	treeSortDirty
+	treeSortAhead
+)
+
+func (s treeSort) String() string {
+	switch s {
+	case treeSortBranch:
+		return "branch name"
+	case treeSortDirty:
+		return "dirty first"
+	case treeSortAhead:
+		return "commits ahead"
+	default:
+		return "git order"
+	}
+}
+
+// projectSort orders the projects.
+type projectSort int
+
+const (
+	// projectSortConfig keeps the order projects were loaded in.
+	projectSortConfig projectSort = iota
+	projectSortName
+	projectSortActivity
+)
+
+func (s projectSort) String() string {
+	switch s {
+	case projectSortName:
+		return "name"
+	case projectSortActivity:
+		return "recent activity"
+	default:
+		return "config order"
+	}
+}
+
+// row is one visible line of the browser: a project, or one of its
+// trees when tree is not -1. Indexes refer to Model.projects and
+// projectNode.trees, so they stay valid regardless of sort order.
+type row struct {
+	project int
+	tree    int
+}
+
+// treeStatus caches the git state used for sorting a tree, since it
+// costs several git invocations per tree to compute.
+type treeStatus struct {
+	dirty      bool
+	ahead      int
+	lastCommit time.Time
+}
+
+// rows returns the visible rows in display order, applying the current
+// project and tree sort modes.
+func (m Model) rows() []row {
+	var rows []row
+
+	for _, pi := range m.projectOrder() {
+		rows = append(rows, row{project: pi, tree: -1})
+
+		if !m.projects[pi].expanded {
+			continue
+		}
+
+		for _, ti := range m.treeOrder(pi) {
+			rows = append(rows, row{project: pi, tree: ti})
+		}
+	}
+
+	return rows
+}
+
+func (m Model) projectOrder() []int {
+	order := indexes(len(m.projects))
+
+	switch m.projectSort {
+	case projectSortName:
+		slices.SortStableFunc(order, func(a, b int) int {
+			return cmp.Compare(m.projects[a].name, m.projects[b].name)
+		})
+
+	case projectSortActivity:
+		latest := make([]time.Time, len(m.projects))
+		for pi, p := range m.projects {
+			for _, t := range p.trees {
+				if last := m.statusOf(t).lastCommit; last.After(latest[pi]) {
+					latest[pi] = last
+				}
+			}
+		}
+
+		// Most recently active first.
+		slices.SortStableFunc(order, func(a, b int) int {
+			return latest[b].Compare(latest[a])
+		})
+	}
+
+	return order
+}
+
+func (m Model) treeOrder(pi int) []int {
+	p := m.projects[pi]
+	order := indexes(len(p.trees))
+
+	switch m.treeSort {
+	case treeSortBranch:
+		slices.SortStableFunc(order, func(a, b int) int {
+			return cmp.Compare(p.trees[a].Branch, p.trees[b].Branch)
+		})
+
+	case treeSortDirty:
+		slices.SortStableFunc(order, func(a, b int) int {
+			// true sorts before false.
+			return -cmpBool(m.statusOf(p.trees[a]).dirty, m.statusOf(p.trees[b]).dirty)
+		})
+
+	case treeSortAhead:
+		slices.SortStableFunc(order, func(a, b int) int {
+			return cmp.Compare(m.statusOf(p.trees[b]).ahead, m.statusOf(p.trees[a]).ahead)
+		})
+	}
+
+	return order
+}
+
+// statusOf returns the cached git state of a tree. Trees whose state
+// has not been loaded yet, or failed to load, get the zero value and
+// sort last.
+func (m Model) statusOf(t git.Worktree) treeStatus {
+	return m.statusCache[t.Path]
+}
+
+// statusMsg carries tree statuses computed in the background, keyed by
+// worktree path.
+type statusMsg struct {
+	statuses map[string]treeStatus
+}
+
+// needsStatus reports whether the current sort modes use git state.
+func (m Model) needsStatus() bool {
+	return m.treeSort == treeSortDirty || m.treeSort == treeSortAhead || m.projectSort == projectSortActivity
+}
+
+// loadStatuses returns a command that computes the git state of every
+// tree not yet cached, so that large repositories do not block
+// rendering. It returns nil when the sorts do not need it, nothing is
+// missing, or a load is already running.
+func (m *Model) loadStatuses() tea.Cmd {
+	if !m.needsStatus() || m.statusLoading {
+		return nil
+	}
+
+	type pending struct {
+		repo string
+		tree git.Worktree
+	}
+
+	var todo []pending
+
+	for _, p := range m.projects {
+		for _, t := range p.trees {
+			if _, ok := m.statusCache[t.Path]; !ok {
+				todo = append(todo, pending{repo: p.repo, tree: t})
+			}
+		}
+	}
+
+	if len(todo) == 0 {
+		return nil
+	}
+
+	m.statusLoading = true
+
+	return func() tea.Msg {
+		statuses := make(map[string]treeStatus, len(todo))
+		for _, p := range todo {
+			statuses[p.tree.Path] = loadStatus(p.repo, p.tree)
+		}
+
+		return statusMsg{statuses: statuses}
+	}
+}
+
+// handleStatus stores loaded statuses and re-sorts, keeping the cursor
+// on the same row. The cache is copied rather than written in place,
+// since earlier model copies share it.
+func (m Model) handleStatus(msg statusMsg) (tea.Model, tea.Cmd) {
+	m.statusLoading = false
+
+	m.keepCursor(func() {
+		cache := make(map[string]treeStatus, len(m.statusCache)+len(msg.statuses))
+		maps.Copy(cache, m.statusCache)
+		maps.Copy(cache, msg.statuses)
+		m.statusCache = cache
+	})
+
+	// Trees added while loading still need their state.
+	return m, m.loadStatuses()
+}
+
+// keepCursor applies change, which may reorder the rows, and moves the
+// cursor to wherever the row it was on ended up.
+func (m *Model) keepCursor(change func()) {
+	pi, ti := m.cursorTarget()
+
+	change()
+
+	for i, r := range m.rows() {
+		if r.project == pi && r.tree == ti {
+			m.cursor = i
+			return
+		}
+	}
+}
+
+// loadStatus computes the git state of a tree. Failures leave the zero
+// value so the tree sorts last.
+func loadStatus(repo string, t git.Worktree) treeStatus {
+	var st treeStatus
+
+	st.dirty, _ = git.IsDirty(t.Path)
+
+	if commit, err := git.LastCommit(t.Path); err == nil {
+		st.lastCommit = commit.Date
+	}
+
+	if t.Branch != "" {
+		base, _ := git.GetBranchBase(repo, t.Branch)
+		if base == "" {
+			base = git.Upstream(repo, t.Branch)
+		}
+
+		if base != "" {
+			st.ahead, _, _ = git.AheadBehind(repo, t.Branch, base)
+		}
+	}
+
+	return st
+}
+
+func indexes(n int) []int {
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+
+	return order
+}
+
+func cmpBool(a, b bool) int {
+	switch {
+	case a == b:
+		return 0
+	case a:
+		return 1
+	default:
+		return -1
+	}
+}
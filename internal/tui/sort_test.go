@@ -0,0 +1,92 @@
+package tui
+
+import (
+	"testing"
+
+	tea "charm.land/bubbletea/v2"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mhamza15/forest/internal/git"
+)
+
+// sortModel returns a model with one expanded project whose trees are
+// listed out of name order.
+func sortModel() Model {
+	return Model{
+		keys: defaultKeyMap(),
+		projects: []projectNode{{
+			name:     "demo",
+			expanded: true,
+			trees: []git.Worktree{
+				{Path: "/trees/c", Branch: "c"},
+				{Path: "/trees/a", Branch: "a"},
+				{Path: "/trees/b", Branch: "b"},
+			},
+		}},
+	}
+}
+
+// branchOrder returns the branches of the visible tree rows in order.
+func branchOrder(m Model) []string {
+	var out []string
+
+	for _, r := range m.rows() {
+		if r.tree >= 0 {
+			out = append(out, m.projects[r.project].trees[r.tree].Branch)
+		}
+	}
+
+	return out
+}
+
+// selectedBranch returns the branch of the tree under the cursor.
+func selectedBranch(m Model) string {
+	pi, ti := m.cursorTarget()
+	if ti < 0 {
+		return ""
+	}
+
+	return m.projects[pi].trees[ti].Branch
+}
+
+func TestSort_KeepsCursorOnTree(t *testing.T) {
+	m := sortModel()
+	m.cursor = 1 // tree c
+
+	m, cmd := press(t, m, tea.KeyPressMsg{Code: 's', Text: "s"})
+	assert.Nil(t, cmd, "sorting by name needs no git state")
+	assert.Equal(t, treeSortBranch, m.treeSort)
+	assert.Equal(t, []string{"a", "b", "c"}, branchOrder(m))
+	assert.Equal(t, "c", selectedBranch(m))
+}
+
+func TestSort_LoadsStatusInBackground(t *testing.T) {
+	m := sortModel()
+	m.treeSort = treeSortBranch
+	m.cursor = 1 // tree a
+
+	m, cmd := press(t, m, tea.KeyPressMsg{Code: 's', Text: "s"})
+	require.NotNil(t, cmd, "sorting dirty first loads tree state")
+	assert.Equal(t, treeSortDirty, m.treeSort)
+	assert.True(t, m.statusLoading)
+
+	// A second load is not started while one is running.
+	assert.Nil(t, m.loadStatuses())
+
+	cache := m.statusCache
+
+	next, cmd := m.Update(statusMsg{statuses: map[string]treeStatus{
+		"/trees/a": {},
+		"/trees/b": {dirty: true},
+		"/trees/c": {},
+	}})
+	m = next.(Model)
+
+	assert.Nil(t, cmd, "every tree is loaded")
+	assert.False(t, m.statusLoading)
+	assert.Equal(t, []string{"b", "c", "a"}, branchOrder(m))
+	assert.Equal(t, "a", selectedBranch(m))
+	assert.Empty(t, cache, "earlier model copies keep their cache")
+}
@@ -20,6 +20,12 @@ var (
 	styleDim = lipgloss.NewStyle().
 			Foreground(lipgloss.Color("#6C7086"))
 
+	styleMerged = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#94E2D5"))
+
+	styleGone = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#F9E2AF"))
+
 	styleError = lipgloss.NewStyle().
 			Foreground(lipgloss.Color("#F38BA8"))
 )